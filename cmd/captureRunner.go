@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kubeshark/kubeshark/config"
+	"github.com/kubeshark/kubeshark/internal/connect"
+	"github.com/kubeshark/kubeshark/kubernetes"
+	"github.com/kubeshark/kubeshark/misc"
+	"github.com/kubeshark/kubeshark/utils"
+	"github.com/rs/zerolog/log"
+)
+
+const captureConfirmationTimeout = 30 * time.Second
+const captureConfirmationInterval = 2 * time.Second
+
+var errCaptureStopDisabled = errors.New("stopping the traffic capture is disabled by `tap.stopTrafficCapturingDisabled`")
+var errPauseOverridden = errors.New("the capture was started or stopped meanwhile by someone else")
+
+// captureWorker is the capture state of a Worker pod, as it's confirmed by the Hub.
+type captureWorker struct {
+	Pod       string
+	Node      string
+	State     string
+	Confirmed bool
+}
+
+// setCapture stops or starts the capture of a running Kubeshark through its config map. A pause is a stop that is
+// marked with the time that the capture is resumed at, while a start or a stop clears that mark.
+func setCapture(kubernetesProvider *kubernetes.Provider, stopped bool, pausedUntil string) error {
+	return kubernetes.UpdateConfig(kubernetesProvider, func(data map[string]string) error {
+		if stopped && data[kubernetes.CONFIG_STOP_TRAFFIC_CAPTURING_DISABLED] == "true" {
+			return errCaptureStopDisabled
+		}
+
+		data[kubernetes.CONFIG_STOPPED] = strconv.FormatBool(stopped)
+		if pausedUntil == "" {
+			delete(data, kubernetes.CONFIG_PAUSED_UNTIL)
+		} else {
+			data[kubernetes.CONFIG_PAUSED_UNTIL] = pausedUntil
+		}
+
+		return nil
+	})
+}
+
+// resumeCapture starts the capture that was paused until pausedUntil, unless the capture was started, stopped or
+// paused again meanwhile, which is reported as errPauseOverridden.
+func resumeCapture(kubernetesProvider *kubernetes.Provider, pausedUntil string) error {
+	return kubernetes.UpdateConfig(kubernetesProvider, func(data map[string]string) error {
+		if data[kubernetes.CONFIG_PAUSED_UNTIL] != pausedUntil {
+			return errPauseOverridden
+		}
+
+		data[kubernetes.CONFIG_STOPPED] = strconv.FormatBool(false)
+		delete(data, kubernetes.CONFIG_PAUSED_UNTIL)
+		return nil
+	})
+}
+
+// expiredPause returns the time that the capture was paused until, if that's passed and the capture is still paused,
+// i.e. the `pause` command that paused it didn't run until the end to resume it.
+func expiredPause(kubernetesProvider *kubernetes.Provider) (pausedUntil string, expired bool) {
+	configMap, err := kubernetesProvider.GetConfigMap(context.Background(), config.Config.Tap.Release.Namespace, kubernetes.SELF_RESOURCES_PREFIX+kubernetes.SUFFIX_CONFIG_MAP)
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed getting the config map!")
+		return
+	}
+
+	pausedUntil = configMap.Data[kubernetes.CONFIG_PAUSED_UNTIL]
+	if pausedUntil == "" {
+		return
+	}
+
+	until, err := time.Parse(time.RFC3339, pausedUntil)
+	if err != nil {
+		log.Warn().Err(err).Str("until", pausedUntil).Msg("Invalid pause in the config map!")
+		return
+	}
+
+	return pausedUntil, time.Now().After(until)
+}
+
+// resumeExpiredPause resumes the capture if its pause is expired. It returns whether the capture is resumed.
+func resumeExpiredPause(kubernetesProvider *kubernetes.Provider) bool {
+	pausedUntil, expired := expiredPause(kubernetesProvider)
+	if !expired {
+		return false
+	}
+
+	log.Warn().Str("until", pausedUntil).Msg("The capture is still paused after its pause expired, resuming it...")
+	err := resumeCapture(kubernetesProvider, pausedUntil)
+	if errors.Is(err, errPauseOverridden) {
+		log.Warn().Err(err).Msg("The capture is left as it is.")
+		return false
+	} else if err != nil {
+		log.Error().Err(err).Msg(fmt.Sprintf("Failed resuming the capture! Start it by `%s start`.", misc.Program))
+		return false
+	}
+
+	log.Info().Msg("Resumed the capture.")
+	return true
+}
+
+// runSetCapture applies the capture state and waits for the Workers to confirm it. It exits if it fails.
+func runSetCapture(kubernetesProvider *kubernetes.Provider, stopped bool, pausedUntil string) {
+	if err := setCapture(kubernetesProvider, stopped, pausedUntil); errors.Is(err, errCaptureStopDisabled) {
+		log.Error().Err(err).Send()
+		os.Exit(1)
+	} else if err != nil {
+		log.Error().Err(err).Str("namespace", config.Config.Tap.Release.Namespace).Msg(fmt.Sprintf("Failed updating the config map, is %s running?", misc.Software))
+		os.Exit(1)
+	}
+
+	if !confirmCapture(kubernetesProvider, !stopped) {
+		os.Exit(1)
+	}
+}
+
+// confirmCapture waits until all the Workers report the capture state through the Hub, then prints the state of
+// each of them. It returns false if any of the Workers didn't confirm the state within the timeout.
+func confirmCapture(kubernetesProvider *kubernetes.Provider, capturing bool) bool {
+	establishProxyIfNeeded()
+	connector = connect.NewConnector(kubernetes.GetHubUrl(), connect.DefaultRetries, connect.DefaultTimeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), captureConfirmationTimeout)
+	defer cancel()
+
+	log.Info().Msg("Waiting for the Workers to confirm...")
+	workers := waitForCaptureWorkers(ctx, kubernetesProvider, capturing)
+
+	if len(workers) == 0 {
+		log.Error().Msg("No Workers are found!")
+		return false
+	}
+
+	printCaptureWorkers(workers)
+
+	if !allCaptureWorkersConfirmed(workers) {
+		log.Error().Dur("timeout", captureConfirmationTimeout).Msg("Some of the Workers didn't confirm the capture state in time!")
+		return false
+	}
+
+	if capturing {
+		log.Info().Int("workers", len(workers)).Msg("The capture is started.")
+	} else {
+		log.Info().Int("workers", len(workers)).Msg("The capture is stopped.")
+	}
+
+	return true
+}
+
+// waitForCaptureWorkers polls the Workers until all of them confirm the capture state, or until the context is done.
+func waitForCaptureWorkers(ctx context.Context, kubernetesProvider *kubernetes.Provider, capturing bool) (workers []*captureWorker) {
+	for {
+		if listedWorkers, err := listCaptureWorkers(ctx, kubernetesProvider, capturing); err != nil {
+			log.Debug().Err(err).Msg("Failed listing the Workers, retrying...")
+		} else {
+			workers = listedWorkers
+			if len(workers) > 0 && allCaptureWorkersConfirmed(workers) {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(captureConfirmationInterval):
+		}
+	}
+}
+
+// listCaptureWorkers lists the Worker pods, along with their capture states that the Hub reports.
+func listCaptureWorkers(ctx context.Context, kubernetesProvider *kubernetes.Provider, capturing bool) ([]*captureWorker, error) {
+	pods, err := kubernetesProvider.ListPodsByAppLabel(ctx, config.Config.Tap.Release.Namespace, map[string]string{kubernetes.AppLabelKey: "worker"})
+	if err != nil {
+		return nil, err
+	}
+
+	hubWorkers, err := connector.ListWorkers()
+	if err != nil {
+		return nil, err
+	}
+
+	hubWorkersByPod := make(map[string]*connect.WorkerStatus)
+	for _, hubWorker := range hubWorkers {
+		hubWorkersByPod[hubWorker.Pod] = hubWorker
+	}
+
+	var workers []*captureWorker
+	for _, pod := range pods {
+		worker := &captureWorker{
+			Pod:   pod.Name,
+			Node:  pod.Spec.NodeName,
+			State: "not connected to the Hub",
+		}
+
+		if hubWorker, ok := hubWorkersByPod[pod.Name]; ok {
+			worker.Confirmed = hubWorker.Capturing == capturing
+			if hubWorker.Capturing {
+				worker.State = "capturing"
+			} else {
+				worker.State = "stopped"
+			}
+		}
+
+		workers = append(workers, worker)
+	}
+
+	sort.Slice(workers, func(i, j int) bool {
+		return workers[i].Node < workers[j].Node
+	})
+
+	return workers, nil
+}
+
+func allCaptureWorkersConfirmed(workers []*captureWorker) bool {
+	for _, worker := range workers {
+		if !worker.Confirmed {
+			return false
+		}
+	}
+
+	return true
+}
+
+func printCaptureWorkers(workers []*captureWorker) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "NODE\tWORKER\tSTATE\tCONFIRMED")
+	for _, worker := range workers {
+		confirmed := fmt.Sprintf(utils.Green, "yes")
+		if !worker.Confirmed {
+			confirmed = fmt.Sprintf(utils.Red, "no")
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", worker.Node, worker.Pod, worker.State, confirmed)
+	}
+	writer.Flush()
+}
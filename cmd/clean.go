@@ -1,13 +1,18 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/creasty/defaults"
 	"github.com/kubeshark/kubeshark/config"
 	"github.com/kubeshark/kubeshark/config/configStructs"
+	"github.com/kubeshark/kubeshark/kubernetes"
 	"github.com/kubeshark/kubeshark/kubernetes/helm"
 	"github.com/kubeshark/kubeshark/misc"
+	"github.com/kubeshark/kubeshark/utils"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
@@ -16,15 +21,13 @@ var cleanCmd = &cobra.Command{
 	Use:   "clean",
 	Short: fmt.Sprintf("Removes all %s resources", misc.Software),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		resp, err := helm.NewHelm(
-			config.Config.Tap.Release.Repo,
-			config.Config.Tap.Release.Name,
-			config.Config.Tap.Release.Namespace,
-		).Uninstall()
-		if err != nil {
+		if len(config.Config.Tap.Contexts) > 0 {
+			cleanContexts(config.Config.Tap.Contexts)
+			return nil
+		}
+
+		if err := uninstallRelease(); err != nil {
 			log.Error().Err(err).Send()
-		} else {
-			log.Info().Msgf("Uninstalled the Helm release: %s", resp.Release.Name)
 		}
 		return nil
 	},
@@ -39,4 +42,56 @@ func init() {
 	}
 
 	cleanCmd.Flags().StringP(configStructs.ReleaseNamespaceLabel, "s", defaultTapConfig.Release.Namespace, "Release namespace of Kubeshark")
+	cleanCmd.Flags().StringSlice(configStructs.ContextsLabel, defaultTapConfig.Contexts, "Remove the releases from several clusters by their kubeconfig contexts")
+}
+
+// uninstallRelease uninstalls the Helm release of the config.
+func uninstallRelease() error {
+	resp, err := helm.NewHelm(
+		config.Config.Tap.Release.Repo,
+		config.Config.Tap.Release.Name,
+		config.Config.Tap.Release.Namespace,
+	).Uninstall()
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msgf("Uninstalled the Helm release: %s", resp.Release.Name)
+	return nil
+}
+
+// removeRelease uninstalls the release that the tap has installed, within the cleanup timeout. It waits until the
+// Workers are terminated, which unloads the PF_RING kernel module if `tap.kernelModule.unloadOnDestroy` is set, and
+// then deletes the PersistentVolumeClaims that are left behind. The termination signals are held meanwhile, so that
+// a repeated Ctrl+C doesn't leave the resources half-deleted.
+func removeRelease(kubernetesProvider *kubernetes.Provider) error {
+	release := utils.HoldTermination(fmt.Sprintf("Removing %s, please wait...", misc.Software))
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	log.Info().Msg(fmt.Sprintf("Removing %s...", misc.Software))
+	if config.Config.Tap.KernelModule.Enabled && config.Config.Tap.KernelModule.UnloadOnDestroy {
+		log.Info().Msg("Waiting for the Workers to unload the PF_RING kernel module...")
+	}
+
+	deadline, _ := ctx.Deadline()
+	resp, err := helm.NewHelm(
+		config.Config.Tap.Release.Repo,
+		config.Config.Tap.Release.Name,
+		config.Config.Tap.Release.Namespace,
+	).UninstallAndWait(time.Until(deadline))
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msgf("Uninstalled the Helm release: %s", resp.Release.Name)
+
+	claims, err := kubernetesProvider.DeletePersistentVolumeClaims(ctx, config.Config.Tap.Release.Namespace, fmt.Sprintf("app.kubernetes.io/instance=%s", config.Config.Tap.Release.Name))
+	if len(claims) > 0 {
+		log.Info().Str("claims", strings.Join(claims, ", ")).Msg("Deleted the leftover PersistentVolumeClaims:")
+	}
+
+	return err
 }
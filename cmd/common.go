@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"os"
 	"path"
 	"regexp"
 	"time"
@@ -14,6 +16,7 @@ import (
 	"github.com/kubeshark/kubeshark/kubernetes"
 	"github.com/kubeshark/kubeshark/misc"
 	"github.com/kubeshark/kubeshark/misc/fsUtils"
+	"github.com/kubeshark/kubeshark/utils"
 	"github.com/rs/zerolog/log"
 )
 
@@ -57,6 +60,15 @@ func startProxyReportErrorIfAny(kubernetesProvider *kubernetes.Provider, ctx con
 	}
 }
 
+func establishProxyIfNeeded() {
+	hubUrl := kubernetes.GetHubUrl()
+	response, err := http.Get(fmt.Sprintf("%s/echo", hubUrl))
+	if err != nil || response.StatusCode != 200 {
+		log.Info().Msg(fmt.Sprintf(utils.Yellow, "Couldn't connect to Hub. Establishing proxy..."))
+		runProxy(false, true)
+	}
+}
+
 func getKubernetesProviderForCli(silent bool, dontCheckVersion bool) (*kubernetes.Provider, error) {
 	kubeConfigPath := config.Config.KubeConfigPath()
 	kubernetesProvider, err := kubernetes.NewProvider(kubeConfigPath, config.Config.Kube.Context)
@@ -90,6 +102,25 @@ func getKubernetesProviderForCli(silent bool, dontCheckVersion bool) (*kubernete
 	return kubernetesProvider, nil
 }
 
+// resolveConfigSecrets replaces the secret references of the config with their values. The Kubernetes provider
+// may be nil, then it's created only if a Kubernetes secret is referenced.
+func resolveConfigSecrets(kubernetesProvider *kubernetes.Provider) {
+	err := config.ResolveSecrets(&config.Config, func(namespace string, name string, key string) (string, error) {
+		if kubernetesProvider == nil {
+			var err error
+			if kubernetesProvider, err = getKubernetesProviderForCli(true, true); err != nil {
+				return "", err
+			}
+		}
+
+		return kubernetesProvider.GetSecretValue(context.Background(), namespace, name, key)
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed resolving the secret references of the config!")
+		os.Exit(1)
+	}
+}
+
 func handleKubernetesProviderError(err error) {
 	var clusterBehindProxyErr *kubernetes.ClusterBehindProxyError
 	if ok := errors.As(err, &clusterBehindProxyErr); ok {
@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"path"
+	"text/tabwriter"
 
 	"github.com/creasty/defaults"
 	"github.com/kubeshark/kubeshark/config"
@@ -17,7 +20,30 @@ var configCmd = &cobra.Command{
 	Use:   "config",
 	Short: fmt.Sprintf("Generate %s config with default values", misc.Software),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if config.Config.Config.Regenerate {
+		if config.Config.Config.Explain {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "PATH\tVALUE\tSOURCE")
+			for _, explained := range config.ExplainConfig() {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", explained.Path, explained.Value, explained.Source)
+			}
+			if err := w.Flush(); err != nil {
+				log.Error().Err(err).Send()
+			}
+		} else if config.Config.Config.Schema {
+			schema, err := config.Schema()
+			if err != nil {
+				log.Error().Err(err).Msg("Failed generating the JSON Schema of the config.")
+				return nil
+			}
+
+			data, err := json.MarshalIndent(schema, "", "  ")
+			if err != nil {
+				log.Error().Err(err).Msg("Failed converting the JSON Schema of the config to JSON.")
+				return nil
+			}
+
+			fmt.Println(string(data))
+		} else if config.Config.Config.Regenerate {
 			defaultConfig := config.CreateDefaultConfig()
 			if err := defaults.Set(&defaultConfig); err != nil {
 				log.Error().Err(err).Send()
@@ -30,7 +56,7 @@ var configCmd = &cobra.Command{
 
 			log.Info().Str("config-path", config.ConfigFilePath).Msg("Template file written to config path.")
 		} else {
-			template, err := utils.PrettyYaml(config.Config)
+			template, err := utils.PrettyYaml(config.RedactSecrets(config.OmitReadonly(config.Config)))
 			if err != nil {
 				log.Error().Err(err).Msg("Failed converting config with defaults to YAML.")
 				return nil
@@ -52,5 +78,7 @@ func init() {
 		log.Debug().Err(err).Send()
 	}
 
+	configCmd.Flags().Bool(configStructs.ExplainConfigName, defaultConfig.Config.Explain, "Print the effective config along with where each of the values came from")
+	configCmd.Flags().Bool(configStructs.SchemaConfigName, defaultConfig.Config.Schema, "Print the JSON Schema of the config file, e.g. for the autocompletion in the editors")
 	configCmd.Flags().BoolP(configStructs.RegenerateConfigName, "r", defaultConfig.Config.Regenerate, fmt.Sprintf("Regenerate the config file with default values to path %s", path.Join(misc.GetDotFolderPath(), "config.yaml")))
 }
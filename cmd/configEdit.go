@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/kubeshark/kubeshark/config"
+	"github.com/kubeshark/kubeshark/misc"
+	"github.com/kubeshark/kubeshark/utils"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+const defaultEditor = "vi"
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit the config file in $VISUAL or $EDITOR, it's only saved if it's valid",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runConfigEdit()
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configEditCmd)
+}
+
+func runConfigEdit() {
+	original, err := os.ReadFile(config.ConfigFilePath)
+	if os.IsNotExist(err) {
+		original, err = defaultConfigTemplate()
+	}
+	if err != nil {
+		log.Error().Err(err).Str("config-path", config.ConfigFilePath).Msg("Failed reading the config file.")
+		os.Exit(1)
+	}
+
+	editFile, err := os.CreateTemp("", fmt.Sprintf("%s-config-*.yaml", misc.Program))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed creating a temporary file.")
+		os.Exit(1)
+	}
+	defer os.Remove(editFile.Name())
+
+	if _, err := editFile.Write(original); err != nil {
+		log.Error().Err(err).Msg("Failed writing a temporary file.")
+		os.Exit(1)
+	}
+	editFile.Close()
+
+	for {
+		if err := runEditor(editFile.Name()); err != nil {
+			log.Error().Err(err).Msg("Failed running the editor.")
+			os.Exit(1)
+		}
+
+		data, err := os.ReadFile(editFile.Name())
+		if err != nil {
+			log.Error().Err(err).Msg("Failed reading the edited config file.")
+			os.Exit(1)
+		}
+
+		if bytes.Equal(data, original) {
+			log.Info().Str("config-path", config.ConfigFilePath).Msg("The config file is unchanged.")
+			return
+		}
+
+		if err := config.ValidateConfigData(data); err != nil {
+			log.Error().Err(err).Msg("The edited config is invalid.")
+			if utils.AskForConfirmation("Edit the config again?") {
+				continue
+			}
+
+			log.Warn().Str("config-path", config.ConfigFilePath).Msg("The changes are discarded.")
+			os.Exit(1)
+		}
+
+		if err := config.WriteConfigData(data); err != nil {
+			log.Error().Err(err).Str("config-path", config.ConfigFilePath).Msg("Failed writing the config file.")
+			os.Exit(1)
+		}
+
+		log.Info().Str("config-path", config.ConfigFilePath).Msg("The config file is saved.")
+		return
+	}
+}
+
+// defaultConfigTemplate is the content of a new config file, the same as of `config -r`
+func defaultConfigTemplate() ([]byte, error) {
+	defaultConfig, err := config.GetConfigWithDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := utils.PrettyYaml(defaultConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(template), nil
+}
+
+// runEditor opens the file in the editor of the user, which may have arguments, e.g. `code --wait`
+func runEditor(filePath string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if strings.TrimSpace(editor) == "" {
+		editor = defaultEditor
+	}
+
+	editorArgs := strings.Fields(editor)
+	editorCmd := exec.Command(editorArgs[0], append(editorArgs[1:], filePath)...)
+	editorCmd.Stdin = os.Stdin
+	editorCmd.Stdout = os.Stdout
+	editorCmd.Stderr = os.Stderr
+
+	return editorCmd.Run()
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/kubeshark/kubeshark/config"
+	"github.com/kubeshark/kubeshark/utils"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var configGetCmd = &cobra.Command{
+	Use:   "get PATH",
+	Short: "Print a value of the effective config, e.g. tap.docker.tag or tap.nodeSelectorTerms[0]",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("unexpected number of arguments, expected the path of the value")
+		}
+
+		value, err := config.GetConfigValue(args[0])
+		if err != nil {
+			log.Error().Err(err).Send()
+			os.Exit(1)
+		}
+
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			template, err := utils.PrettyYaml(value)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed converting the value to YAML.")
+				os.Exit(1)
+			}
+			fmt.Print(template)
+		case nil:
+			fmt.Println("null")
+		default:
+			fmt.Println(value)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configGetCmd)
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/kubeshark/kubeshark/config"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate [PATH]",
+	Short: "Rename the renamed fields in the config file (defaults to the config file in use), keeping its comments",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configFilePath := config.ConfigFilePath
+		if len(args) == 1 {
+			configFilePath = args[0]
+		} else if len(args) > 1 {
+			return errors.New("unexpected number of arguments")
+		}
+
+		configFile, err := config.ReadConfigFile(configFilePath)
+		if err != nil {
+			log.Error().Err(err).Str("config-path", configFilePath).Msg("Failed reading the config file.")
+			os.Exit(1)
+		}
+
+		changes, err := configFile.Migrate()
+		if err != nil {
+			log.Error().Err(err).Str("config-path", configFilePath).Msg("Failed migrating the config file.")
+			os.Exit(1)
+		}
+
+		if len(changes) == 0 {
+			log.Info().Str("config-path", configFilePath).Msg("The config file is up to date.")
+			return nil
+		}
+
+		if err := configFile.Write(); err != nil {
+			log.Error().Err(err).Str("config-path", configFilePath).Msg("Failed writing the config file.")
+			os.Exit(1)
+		}
+
+		for _, change := range changes {
+			log.Info().Str("config-path", configFilePath).Msg(change)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configMigrateCmd)
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/kubeshark/kubeshark/config"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var configSetCmd = &cobra.Command{
+	Use:   "set PATH VALUE...",
+	Short: "Set a value in the config file, keeping its comments, e.g. `config set tap.docker.tag v52.3`. Multiple values set a list",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 2 {
+			return errors.New("unexpected number of arguments, expected the path and the value")
+		}
+
+		flagPath, value, err := config.ParseConfigValue(args[0], args[1:])
+		if err != nil {
+			log.Error().Err(err).Send()
+			os.Exit(1)
+		}
+
+		configFile, err := config.ReadConfigFile(config.ConfigFilePath)
+		if err != nil {
+			log.Error().Err(err).Str("config-path", config.ConfigFilePath).Msg("Failed reading the config file.")
+			os.Exit(1)
+		}
+
+		if err := configFile.Set(flagPath, value); err != nil {
+			log.Error().Err(err).Str("path", args[0]).Msg("Failed setting the value.")
+			os.Exit(1)
+		}
+
+		if err := configFile.Write(); err != nil {
+			log.Error().Err(err).Str("config-path", configFile.Path).Msg("Failed writing the config file.")
+			os.Exit(1)
+		}
+
+		log.Info().Str("config-path", configFile.Path).Str("path", args[0]).Msg("The value is set.")
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configSetCmd)
+}
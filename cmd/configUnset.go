@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/kubeshark/kubeshark/config"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset PATH",
+	Short: "Remove a value from the config file, so that the default applies, keeping the comments of the file",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("unexpected number of arguments, expected the path of the value")
+		}
+
+		flagPath, err := config.ParseConfigPath(args[0])
+		if err != nil {
+			log.Error().Err(err).Send()
+			os.Exit(1)
+		}
+
+		configFile, err := config.ReadConfigFile(config.ConfigFilePath)
+		if err != nil {
+			log.Error().Err(err).Str("config-path", config.ConfigFilePath).Msg("Failed reading the config file.")
+			os.Exit(1)
+		}
+
+		if !configFile.Unset(flagPath) {
+			log.Warn().Str("config-path", configFile.Path).Str("path", args[0]).Msg("The value is not set in the config file.")
+			return nil
+		}
+
+		if err := configFile.Write(); err != nil {
+			log.Error().Err(err).Str("config-path", configFile.Path).Msg("Failed writing the config file.")
+			os.Exit(1)
+		}
+
+		log.Info().Str("config-path", configFile.Path).Str("path", args[0]).Msg("The value is removed.")
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configUnsetCmd)
+}
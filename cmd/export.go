@@ -1,26 +1,51 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
-	"net/http"
+	"io"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"time"
 
 	"github.com/creasty/defaults"
+	"github.com/kubeshark/kubeshark/config"
 	"github.com/kubeshark/kubeshark/config/configStructs"
+	"github.com/kubeshark/kubeshark/errormessage"
 	"github.com/kubeshark/kubeshark/internal/connect"
 	"github.com/kubeshark/kubeshark/kubernetes"
-	"github.com/kubeshark/kubeshark/utils"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
+const exportProgressInterval = 2 * time.Second
+
+// errExportNotResumable is returned when the download cannot be resumed, so retrying it is pointless.
+var errExportNotResumable = errors.New("the export cannot be resumed")
+
 var exportCmd = &cobra.Command{
 	Use:   "export",
-	Short: "Exports the captured traffic into a TAR file that contains PCAP files",
+	Short: "Exports the captured traffic into a TAR file that contains PCAP files, or into HAR files",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		runExport()
+		if config.Config.Export.Format == configStructs.HarExportFormat {
+			if err := runExportHar(); err != nil {
+				os.Exit(1)
+			}
+		} else if config.Config.Export.Direct {
+			runExportDirect()
+		} else {
+			if err := runExport(); err != nil {
+				os.Exit(1)
+			}
+		}
+		return nil
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.Config.Export.Validate(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
 		return nil
 	},
 }
@@ -33,30 +58,223 @@ func init() {
 		log.Debug().Err(err).Send()
 	}
 
+	defaultExportConfig := configStructs.ExportConfig{}
+	if err := defaults.Set(&defaultExportConfig); err != nil {
+		log.Debug().Err(err).Send()
+	}
+
 	exportCmd.Flags().Uint16(configStructs.ProxyFrontPortLabel, defaultTapConfig.Proxy.Front.Port, "Provide a custom port for the Kubeshark")
 	exportCmd.Flags().String(configStructs.ProxyHostLabel, defaultTapConfig.Proxy.Host, "Provide a custom host for the Kubeshark")
 	exportCmd.Flags().StringP(configStructs.ReleaseNamespaceLabel, "s", defaultTapConfig.Release.Namespace, "Release namespace of Kubeshark")
+	exportCmd.Flags().StringP(configStructs.OutputExportName, "o", defaultExportConfig.Output, "Path for the TAR file or the directory of the HAR files, use - to write to stdout (default current <pwd>/<unix timestamp>.tar.gz)")
+	exportCmd.Flags().String(configStructs.FormatExportName, defaultExportConfig.Format, fmt.Sprintf("Export format, %s or %s (HAR 1.2 files of the HTTP traffic, one per service)", configStructs.PcapExportFormat, configStructs.HarExportFormat))
+	exportCmd.Flags().StringP(configStructs.QueryExportName, "q", defaultExportConfig.Query, "KFL query to filter the exported traffic")
+	exportCmd.Flags().String(configStructs.StartExportName, defaultExportConfig.Start, "Start of the time range, an RFC3339 timestamp or a duration ago (e.g. 15m)")
+	exportCmd.Flags().String(configStructs.EndExportName, defaultExportConfig.End, "End of the time range, an RFC3339 timestamp or a duration ago (e.g. 5m)")
+	exportCmd.Flags().StringSlice(configStructs.NodesExportName, defaultExportConfig.Nodes, "Export only the traffic captured on these nodes")
+	exportCmd.Flags().StringSlice(configStructs.PodsExportName, defaultExportConfig.Pods, "Export only the traffic of these pods")
+	exportCmd.Flags().Int(configStructs.RetriesExportName, defaultExportConfig.Retries, "Number of times to resume the download after a connection failure")
+	exportCmd.Flags().Bool(configStructs.DirectExportName, defaultExportConfig.Direct, "Copy the PCAP files directly from the Worker pods, for when the Hub is unreachable")
 }
 
-func runExport() {
-	hubUrl := kubernetes.GetHubUrl()
-	response, err := http.Get(fmt.Sprintf("%s/echo", hubUrl))
-	if err != nil || response.StatusCode != 200 {
-		log.Info().Msg(fmt.Sprintf(utils.Yellow, "Couldn't connect to Hub. Establishing proxy..."))
-		runProxy(false, true)
+// runExport downloads the PCAP files that match the query into a TAR file. The errors are logged as well as returned.
+func runExport() error {
+	resolveConfigSecrets(nil)
+	establishProxyIfNeeded()
+
+	payload, err := getPcapsMergeRequest()
+	if err != nil {
+		log.Error().Err(err).Send()
+		return err
 	}
 
-	dstPath, err := filepath.Abs(fmt.Sprintf("./%d.tar.gz", time.Now().Unix()))
+	out, outFile, dstPath, err := openExportOutput()
 	if err != nil {
-		panic(err)
+		log.Error().Err(err).Send()
+		return err
+	}
+	if outFile != nil {
+		defer outFile.Close()
 	}
 
-	out, err := os.Create(dstPath)
+	progress := newExportProgress(out)
+	defer progress.Stop()
+
+	// The download is streamed, so the client must not time out in the middle of it.
+	connector := connect.NewConnector(kubernetes.GetHubUrl(), connect.DefaultRetries, 0)
+
+	retriesLeft := config.Config.Export.Retries
+	for {
+		err = downloadPcapsMerge(connector, payload, outFile, progress)
+		if err == nil {
+			break
+		}
+
+		if retriesLeft == 0 || errors.Is(err, errExportNotResumable) {
+			log.Error().Err(err).Int64("downloaded", progress.Written()).Msg("Failed exported PCAP download.")
+			return err
+		}
+		retriesLeft--
+
+		log.Warn().Err(err).Int64("downloaded", progress.Written()).Int("retries-left", retriesLeft).Msg("Exported PCAP download interrupted. Resuming...")
+		time.Sleep(connect.DefaultSleep)
+		establishProxyIfNeeded()
+	}
+
+	if config.Config.Export.IsStdout() {
+		log.Info().Int64("size", progress.Written()).Msg("Downloaded exported PCAP to stdout.")
+	} else {
+		log.Info().Str("path", dstPath).Int64("size", progress.Written()).Msg("Downloaded exported PCAP:")
+	}
+
+	return nil
+}
+
+// openExportOutput opens the destination of the export. outFile is nil when the export is written to stdout.
+func openExportOutput() (out io.Writer, outFile *os.File, dstPath string, err error) {
+	if config.Config.Export.IsStdout() {
+		out = os.Stdout
+		return
+	}
+
+	dstPath, err = filepath.Abs(config.Config.Export.OutputPath())
 	if err != nil {
-		panic(err)
+		return
+	}
+
+	outFile, err = os.Create(dstPath)
+	if err != nil {
+		return
+	}
+
+	out = outFile
+	return
+}
+
+func getPcapsMergeRequest() (*connect.PostPcapsMergeRequest, error) {
+	start, end, err := config.Config.Export.TimeRange()
+	if err != nil {
+		return nil, err
+	}
+
+	payload := &connect.PostPcapsMergeRequest{
+		Query: config.Config.Export.Query,
+		Nodes: config.Config.Export.Nodes,
+		Pods:  config.Config.Export.Pods,
+	}
+	if !start.IsZero() {
+		payload.StartTime = start.UnixMilli()
+	}
+	if !end.IsZero() {
+		payload.EndTime = end.UnixMilli()
+	}
+
+	log.Info().
+		Str("query", payload.Query).
+		Int64("start", payload.StartTime).
+		Int64("end", payload.EndTime).
+		Strs("nodes", payload.Nodes).
+		Strs("pods", payload.Pods).
+		Msg("Exporting PCAP:")
+
+	return payload, nil
+}
+
+func downloadPcapsMerge(connector *connect.Connector, payload *connect.PostPcapsMergeRequest, outFile *os.File, progress *exportProgress) error {
+	offset := progress.Written()
+	body, resumed, err := connector.PostPcapsMerge(payload, offset)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if offset > 0 && !resumed {
+		// The Hub restarted the export from the beginning, so the output has to be rewritten.
+		if outFile == nil {
+			return fmt.Errorf("%w, the Hub restarted the export that is written to stdout, %d bytes were already written", errExportNotResumable, offset)
+		}
+
+		log.Warn().Int64("offset", offset).Msg("Hub does not support resuming the PCAP export, restarting the download...")
+		if err := outFile.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := outFile.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		progress.Reset()
+	}
+
+	_, err = io.Copy(progress, body)
+	return err
+}
+
+type exportProgress struct {
+	out     io.Writer
+	written atomic.Int64
+	done    chan struct{}
+}
+
+func newExportProgress(out io.Writer) *exportProgress {
+	progress := &exportProgress{
+		out:  out,
+		done: make(chan struct{}),
+	}
+
+	go progress.report()
+
+	return progress
+}
+
+func (p *exportProgress) Write(b []byte) (int, error) {
+	n, err := p.out.Write(b)
+	p.written.Add(int64(n))
+	return n, err
+}
+
+func (p *exportProgress) Written() int64 {
+	return p.written.Load()
+}
+
+func (p *exportProgress) Reset() {
+	p.written.Store(0)
+}
+
+func (p *exportProgress) Stop() {
+	close(p.done)
+}
+
+func (p *exportProgress) report() {
+	ticker := time.NewTicker(exportProgressInterval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	for {
+		select {
+		case <-ticker.C:
+			written := p.Written()
+			elapsed := time.Since(start)
+			log.Info().
+				Str("downloaded", formatBytes(written)).
+				Str("rate", fmt.Sprintf("%s/s", formatBytes(int64(float64(written)/elapsed.Seconds())))).
+				Dur("elapsed", elapsed.Truncate(time.Second)).
+				Msg("Exporting PCAP...")
+		case <-p.done:
+			return
+		}
+	}
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+
+	div, exp := int64(unit), 0
+	for i := n / unit; i >= unit; i /= unit {
+		div *= unit
+		exp++
 	}
-	defer out.Close()
 
-	connector := connect.NewConnector(kubernetes.GetHubUrl(), connect.DefaultRetries, connect.DefaultTimeout)
-	connector.PostPcapsMerge(out)
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
 }
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kubeshark/kubeshark/config"
+	"github.com/kubeshark/kubeshark/kubernetes"
+	"github.com/kubeshark/kubeshark/misc"
+	"github.com/kubeshark/kubeshark/misc/fsUtils"
+	"github.com/kubeshark/kubeshark/utils"
+	"github.com/rs/zerolog/log"
+	core "k8s.io/api/core/v1"
+)
+
+const exportManifestName = "manifest.json"
+
+type exportManifest struct {
+	CreatedAt time.Time                `json:"createdAt"`
+	Start     string                   `json:"start,omitempty"`
+	End       string                   `json:"end,omitempty"`
+	Nodes     []exportManifestNodeItem `json:"nodes"`
+}
+
+type exportManifestNodeItem struct {
+	Node  string   `json:"node"`
+	Pod   string   `json:"pod"`
+	Files []string `json:"files"`
+	Size  int64    `json:"size"`
+	Error string   `json:"error,omitempty"`
+}
+
+// runExportDirect copies the PCAP files straight out of the Worker pods, bypassing the Hub.
+// It is meant to be used when the Hub or the Front is unreachable.
+func runExportDirect() {
+	kubernetesProvider, err := getKubernetesProviderForCli(false, false)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if config.Config.Export.Query != "" || len(config.Config.Export.Pods) > 0 {
+		log.Warn().Msg(fmt.Sprintf(utils.Yellow, "The query and the pods filters require the Hub, they are ignored in the direct mode."))
+	}
+
+	start, end, err := config.Config.Export.TimeRange()
+	if err != nil {
+		log.Error().Err(err).Send()
+		return
+	}
+
+	pods, err := kubernetesProvider.ListPodsByAppLabel(ctx, config.Config.Tap.Release.Namespace, map[string]string{kubernetes.AppLabelKey: "worker"})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed listing the Worker pods!")
+		return
+	}
+
+	var workerPods []core.Pod
+	for _, pod := range pods {
+		if len(config.Config.Export.Nodes) > 0 && !utils.Contains(config.Config.Export.Nodes, pod.Spec.NodeName) {
+			continue
+		}
+		if !kubernetes.IsPodRunning(&pod) {
+			log.Warn().Str("node", pod.Spec.NodeName).Str("pod", pod.Name).Msg("Skipping the Worker pod that is not running:")
+			continue
+		}
+		workerPods = append(workerPods, pod)
+	}
+
+	if len(workerPods) == 0 {
+		log.Error().
+			Str("namespace", config.Config.Tap.Release.Namespace).
+			Strs("nodes", config.Config.Export.Nodes).
+			Msg(fmt.Sprintf("No %s Worker pods found!", misc.Software))
+		return
+	}
+
+	tmpDir, err := os.MkdirTemp("", fmt.Sprintf("%s_export_", misc.Program))
+	if err != nil {
+		log.Error().Err(err).Send()
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+
+	manifest := exportManifest{
+		CreatedAt: time.Now(),
+		Start:     config.Config.Export.Start,
+		End:       config.Config.Export.End,
+		Nodes:     copyFromWorkerPods(ctx, kubernetesProvider, workerPods, tmpDir),
+	}
+
+	out, outFile, dstPath, err := openExportOutput()
+	if err != nil {
+		log.Error().Err(err).Send()
+		return
+	}
+	if outFile != nil {
+		defer outFile.Close()
+	}
+
+	if err := writeExportArchive(out, tmpDir, &manifest, start, end); err != nil {
+		log.Error().Err(err).Msg("Failed writing the PCAP export!")
+		return
+	}
+
+	if config.Config.Export.IsStdout() {
+		log.Info().Int("nodes", len(manifest.Nodes)).Msg("Exported PCAP to stdout.")
+	} else {
+		log.Info().Str("path", dstPath).Int("nodes", len(manifest.Nodes)).Msg("Exported PCAP:")
+	}
+}
+
+func copyFromWorkerPods(ctx context.Context, kubernetesProvider *kubernetes.Provider, pods []core.Pod, dstDir string) []exportManifestNodeItem {
+	items := make([]exportManifestNodeItem, len(pods))
+
+	var wg sync.WaitGroup
+	for i, pod := range pods {
+		wg.Add(1)
+		go func(i int, pod core.Pod) {
+			defer wg.Done()
+
+			items[i] = exportManifestNodeItem{
+				Node:  pod.Spec.NodeName,
+				Pod:   pod.Name,
+				Files: []string{},
+			}
+
+			log.Info().Str("node", pod.Spec.NodeName).Str("pod", pod.Name).Msg("Copying PCAP files from:")
+			if err := kubernetes.CopyFromPod(ctx, kubernetesProvider, pod, kubernetes.WorkerDataPath, filepath.Join(dstDir, pod.Spec.NodeName)); err != nil {
+				log.Error().Str("node", pod.Spec.NodeName).Str("pod", pod.Name).Err(err).Msg("Failed copying PCAP files!")
+				items[i].Error = err.Error()
+			}
+		}(i, pod)
+	}
+	wg.Wait()
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Node < items[j].Node
+	})
+
+	return items
+}
+
+// writeExportArchive writes the copied files, grouped by node and filtered by the time range,
+// into a gzipped TAR archive along with the manifest itself.
+func writeExportArchive(out io.Writer, srcDir string, manifest *exportManifest, start time.Time, end time.Time) error {
+	gzipWriter := gzip.NewWriter(out)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	for i := range manifest.Nodes {
+		item := &manifest.Nodes[i]
+		nodeDir := filepath.Join(srcDir, item.Node)
+		if _, err := os.Stat(nodeDir); os.IsNotExist(err) {
+			continue
+		}
+
+		err := filepath.WalkDir(nodeDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return err
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			if (!start.IsZero() && info.ModTime().Before(start)) || (!end.IsZero() && info.ModTime().After(end)) {
+				return nil
+			}
+
+			name, err := filepath.Rel(srcDir, path)
+			if err != nil {
+				return err
+			}
+			name = filepath.ToSlash(name)
+
+			if err := fsUtils.AddFileToTar(tarWriter, path, name); err != nil {
+				return err
+			}
+
+			item.Files = append(item.Files, name)
+			item.Size += info.Size()
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		log.Info().Str("node", item.Node).Int("files", len(item.Files)).Int64("size", item.Size).Msg("Added PCAP files of:")
+	}
+
+	manifestMarshalled, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := fsUtils.AddBytesToTar(tarWriter, manifestMarshalled, exportManifestName); err != nil {
+		return err
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+
+	return gzipWriter.Close()
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kubeshark/kubeshark/config"
+	"github.com/kubeshark/kubeshark/internal/connect"
+	"github.com/kubeshark/kubeshark/internal/har"
+	"github.com/kubeshark/kubeshark/kubernetes"
+	"github.com/rs/zerolog/log"
+)
+
+// runExportHar writes the dissected HTTP entries that match the query into a HAR file per service.
+// When the export is written to stdout, all the entries are written into a single HAR. The errors are logged as
+// well as returned, while the entries that fail to convert are skipped.
+func runExportHar() error {
+	resolveConfigSecrets(nil)
+	establishProxyIfNeeded()
+
+	start, end, err := config.Config.Export.TimeRange()
+	if err != nil {
+		log.Error().Err(err).Send()
+		return err
+	}
+
+	filter := "http"
+	if config.Config.Export.Query != "" {
+		filter = fmt.Sprintf("http and (%s)", config.Config.Export.Query)
+	}
+
+	log.Info().Str("filter", filter).Msg("Exporting HAR:")
+
+	connector := connect.NewConnector(kubernetes.GetHubUrl(), connect.DefaultRetries, connect.DefaultTimeout)
+
+	// The export is bounded, by default it's the traffic that's captured until the command started.
+	if start.IsZero() {
+		start = captureStart
+	}
+	if end.IsZero() {
+		end = time.Now()
+	}
+
+	hars := make(har.Services)
+	count := 0
+	err = streamEntries(context.Background(), filter, start, end, 0, func(data []byte) error {
+		var base struct {
+			Id string `json:"id"`
+		}
+		if err := json.Unmarshal(data, &base); err != nil {
+			return err
+		}
+
+		entryData, err := connector.GetEntry(base.Id)
+		if err != nil {
+			log.Warn().Str("id", base.Id).Err(err).Msg("Failed fetching the entry, skipping it.")
+			return nil
+		}
+
+		harEntry, service, err := har.ParseEntry(entryData)
+		if err != nil {
+			log.Warn().Str("id", base.Id).Err(err).Msg("Failed converting the entry to HAR, skipping it.")
+			return nil
+		}
+
+		if config.Config.Export.IsStdout() {
+			service = ""
+		}
+
+		hars.Add(service, harEntry)
+		count++
+
+		return nil
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed streaming the entries!")
+		return err
+	}
+
+	hars.Sort()
+
+	if config.Config.Export.IsStdout() {
+		h, ok := hars[""]
+		if !ok {
+			h = har.NewHAR()
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(h); err != nil {
+			log.Error().Err(err).Msg("Failed writing HAR!")
+			return err
+		}
+
+		log.Info().Int("entries", count).Msg("Exported HAR to stdout.")
+		return nil
+	}
+
+	dstDir, err := filepath.Abs(config.Config.Export.OutputPath())
+	if err != nil {
+		log.Error().Err(err).Send()
+		return err
+	}
+
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		log.Error().Err(err).Send()
+		return err
+	}
+
+	fileNames := hars.FileNames()
+
+	var errs []error
+	for service, h := range hars {
+		path := filepath.Join(dstDir, fileNames[service])
+
+		data, err := json.MarshalIndent(h, "", "  ")
+		if err != nil {
+			log.Error().Str("service", service).Err(err).Msg("Failed marshaling HAR!")
+			errs = append(errs, err)
+			continue
+		}
+
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			log.Error().Str("path", path).Err(err).Msg("Failed writing HAR file!")
+			errs = append(errs, err)
+			continue
+		}
+
+		log.Info().Str("service", service).Int("entries", len(h.Log.Entries)).Str("path", path).Msg("HAR file at:")
+	}
+
+	if count == 0 {
+		log.Warn().Str("filter", filter).Msg("No HTTP entries matched the filter.")
+	}
+
+	return errors.Join(errs...)
+}
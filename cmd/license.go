@@ -9,9 +9,9 @@ import (
 
 var licenseCmd = &cobra.Command{
 	Use:   "license",
-	Short: "Print the license loaded string",
+	Short: "Print the loaded license, redacted unless it's a reference to a secret",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		fmt.Println(config.Config.License)
+		fmt.Println(config.RedactSecret(config.Config.License))
 		return nil
 	},
 }
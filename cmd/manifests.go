@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/creasty/defaults"
+	"github.com/goccy/go-yaml"
+	"github.com/kubeshark/kubeshark/config"
+	"github.com/kubeshark/kubeshark/config/configStructs"
+	"github.com/kubeshark/kubeshark/errormessage"
+	"github.com/kubeshark/kubeshark/kubernetes/helm"
+	"github.com/kubeshark/kubeshark/misc"
+	"github.com/kubeshark/kubeshark/utils"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/releaseutil"
+)
+
+const kustomizationFileName = "kustomization.yaml"
+
+var manifestFileNameRegex = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// splitManifestFileRegex matches the names of the files that are written per resource, see manifestFileName.
+var splitManifestFileRegex = regexp.MustCompile(`^[0-9]{2,}-[a-z0-9.-]+\.yaml$`)
+
+var manifestsCmd = &cobra.Command{
+	Use:   "manifests",
+	Short: fmt.Sprintf("Render the Kubernetes manifests of %s with the config, e.g. for GitOps", misc.Software),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runManifests()
+		return nil
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.Config.Manifests.Validate(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		if err := config.Config.Tap.Validate(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(manifestsCmd)
+
+	defaultTapConfig := configStructs.TapConfig{}
+	if err := defaults.Set(&defaultTapConfig); err != nil {
+		log.Debug().Err(err).Send()
+	}
+
+	defaultManifestsConfig := configStructs.ManifestsConfig{}
+	if err := defaults.Set(&defaultManifestsConfig); err != nil {
+		log.Debug().Err(err).Send()
+	}
+
+	manifestsCmd.Flags().StringP(configStructs.ReleaseNamespaceLabel, "s", defaultTapConfig.Release.Namespace, "Release namespace of Kubeshark")
+	manifestsCmd.Flags().StringP(configStructs.DockerRegistryLabel, "r", defaultTapConfig.Docker.Registry, "The Docker registry that's hosting the images")
+	manifestsCmd.Flags().StringP(configStructs.DockerTagLabel, "t", defaultTapConfig.Docker.Tag, "The tag of the Docker images that are going to be pulled")
+	manifestsCmd.Flags().Bool(configStructs.DumpManifestsName, defaultManifestsConfig.Dump, "Write the manifests into the directory instead of the standard output")
+	manifestsCmd.Flags().String(configStructs.DirectoryManifestsName, defaultManifestsConfig.Directory, "The directory that the manifests are written into")
+	manifestsCmd.Flags().Bool(configStructs.SplitManifestsName, defaultManifestsConfig.Split, "Write a file per resource instead of a single multi-document YAML")
+	manifestsCmd.Flags().Bool(configStructs.KustomizationManifestsName, defaultManifestsConfig.Kustomization, "Also write a kustomization.yaml that lists the manifests")
+}
+
+type manifestHead struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+}
+
+type kustomization struct {
+	ApiVersion string   `yaml:"apiVersion"`
+	Kind       string   `yaml:"kind"`
+	Resources  []string `yaml:"resources"`
+}
+
+func runManifests() {
+	// The secret references are not resolved, the values would end up in the Secret manifests, that may be committed.
+	for _, path := range config.OmitSecretRefs(&config.Config) {
+		log.Warn().Str("path", path).Msg(fmt.Sprintf("The secret reference is left out of the manifests, so its Secret is rendered empty. Fill the value in the cluster, or set %s to the value to render it.", path))
+	}
+
+	manifests, err := helm.NewHelm(
+		config.Config.Tap.Release.Repo,
+		config.Config.Tap.Release.Name,
+		config.Config.Tap.Release.Namespace,
+	).Template()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed rendering the Helm chart!")
+		os.Exit(1)
+	}
+
+	if !config.Config.Manifests.Dump {
+		fmt.Print(joinManifests(manifests))
+		return
+	}
+
+	if err := dumpManifests(manifests, config.Config.Manifests.Directory); err != nil {
+		log.Error().Err(err).Msg("Failed writing the manifests!")
+		os.Exit(1)
+	}
+}
+
+func joinManifests(manifests []releaseutil.Manifest) string {
+	var builder strings.Builder
+	for _, manifest := range manifests {
+		fmt.Fprintf(&builder, "---\n# Source: %s\n%s\n", manifest.Name, strings.TrimSpace(manifest.Content))
+	}
+
+	return builder.String()
+}
+
+// dumpManifests writes the manifests into the directory, either as a single file or as a file per resource,
+// optionally with a kustomization.yaml that lists them.
+func dumpManifests(manifests []releaseutil.Manifest, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	files := make(map[string]string)
+	var fileNames []string
+	if config.Config.Manifests.Split {
+		for i, manifest := range manifests {
+			fileName, err := manifestFileName(i, manifest)
+			if err != nil {
+				return err
+			}
+
+			files[fileName] = fmt.Sprintf("# Source: %s\n%s\n", manifest.Name, strings.TrimSpace(manifest.Content))
+			fileNames = append(fileNames, fileName)
+		}
+	} else {
+		fileName := fmt.Sprintf("%s.yaml", misc.Program)
+		files[fileName] = joinManifests(manifests)
+		fileNames = append(fileNames, fileName)
+	}
+
+	if config.Config.Manifests.Kustomization {
+		data, err := yaml.Marshal(&kustomization{
+			ApiVersion: "kustomize.config.k8s.io/v1beta1",
+			Kind:       "Kustomization",
+			Resources:  fileNames,
+		})
+		if err != nil {
+			return err
+		}
+
+		files[kustomizationFileName] = string(data)
+		fileNames = append(fileNames, kustomizationFileName)
+	}
+
+	if err := removeStaleManifests(dir, fileNames); err != nil {
+		return err
+	}
+
+	for _, fileName := range fileNames {
+		path := filepath.Join(dir, fileName)
+		if err := os.WriteFile(path, []byte(files[fileName]), 0644); err != nil {
+			return err
+		}
+
+		log.Info().Str("path", path).Msg("Written:")
+	}
+
+	return nil
+}
+
+// removeStaleManifests removes the files that are written by a previous run and aren't written again, e.g. the files
+// of the resources that are disabled meanwhile, so that they aren't applied along with the current ones. Other files
+// in the directory are kept.
+func removeStaleManifests(dir string, fileNames []string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || utils.Contains(fileNames, name) {
+			continue
+		}
+
+		if name != fmt.Sprintf("%s.yaml", misc.Program) && name != kustomizationFileName && !splitManifestFileRegex.MatchString(name) {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+
+		log.Info().Str("path", path).Msg("Removed the stale manifest:")
+	}
+
+	return nil
+}
+
+// manifestFileName names the file of a resource by its order of installation, its kind and its name,
+// e.g. 05-deployment-kubeshark-hub.yaml
+func manifestFileName(index int, manifest releaseutil.Manifest) (string, error) {
+	var head manifestHead
+	if err := yaml.Unmarshal([]byte(manifest.Content), &head); err != nil {
+		return "", fmt.Errorf("%s, %w", manifest.Name, err)
+	}
+
+	name := strings.ToLower(fmt.Sprintf("%s-%s", head.Kind, head.Metadata.Name))
+	name = strings.Trim(manifestFileNameRegex.ReplaceAllString(name, "-"), "-")
+
+	return fmt.Sprintf("%02d-%s.yaml", index+1, name), nil
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestRemoveStaleManifests(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"01-namespace-kubeshark.yaml", "02-service-kubeshark-hub.yaml", "03-deployment-old.yaml", "kubeshark.yaml", "kustomization.yaml", "values.yaml", "README.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatalf("unexpected error - %v", err)
+		}
+	}
+
+	if err := removeStaleManifests(dir, []string{"01-namespace-kubeshark.yaml", "02-service-kubeshark-hub.yaml"}); err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+
+	var actual []string
+	for _, entry := range entries {
+		actual = append(actual, entry.Name())
+	}
+	sort.Strings(actual)
+
+	expected := []string{"01-namespace-kubeshark.yaml", "02-service-kubeshark-hub.yaml", "README.md", "values.yaml"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected files - expected: %v, actual: %v", expected, actual)
+	}
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/creasty/defaults"
+	"github.com/kubeshark/kubeshark/config"
+	"github.com/kubeshark/kubeshark/config/configStructs"
+	"github.com/kubeshark/kubeshark/errormessage"
+	"github.com/kubeshark/kubeshark/misc"
+	"github.com/kubeshark/kubeshark/utils"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause",
+	Short: fmt.Sprintf("Stop the traffic capture of a running %s for a while, then start it again", misc.Software),
+	Long: fmt.Sprintf(`Stop the traffic capture of a running %s for a while, then start it again.
+The command waits in the foreground until the capture is resumed. The capture is resumed early if the command is
+interrupted. It's not resumed if it's started, stopped or paused meanwhile by someone else. If the command is killed
+before the capture is resumed, the expired pause is resumed by the next start or tap, status only reports it.`, misc.Software),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 0 {
+			return errors.New("unexpected number of arguments, expected none")
+		}
+
+		runPause()
+		return nil
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.Config.Pause.Validate(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pauseCmd)
+	addCaptureFlags(pauseCmd)
+
+	defaultPauseConfig := configStructs.PauseConfig{}
+	if err := defaults.Set(&defaultPauseConfig); err != nil {
+		log.Debug().Err(err).Send()
+	}
+
+	pauseCmd.Flags().String(configStructs.ForPauseName, defaultPauseConfig.For, "Duration of the pause, e.g. 30s, 10m or 1h")
+}
+
+func runPause() {
+	kubernetesProvider, err := getKubernetesProviderForCli(true, true)
+	if err != nil {
+		os.Exit(1)
+	}
+
+	resolveConfigSecrets(kubernetesProvider)
+
+	duration, err := config.Config.Pause.Duration()
+	if err != nil {
+		log.Error().Err(err).Send()
+		os.Exit(1)
+	}
+
+	pausedUntil := time.Now().Add(duration).UTC().Format(time.RFC3339)
+	runSetCapture(kubernetesProvider, true, pausedUntil)
+
+	log.Info().Str("until", pausedUntil).Msg(fmt.Sprintf(utils.Yellow, "The capture is paused, press Ctrl+C to resume it early."))
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+	utils.WaitForTermination(ctx, cancel)
+
+	err = resumeCapture(kubernetesProvider, pausedUntil)
+	if errors.Is(err, errPauseOverridden) {
+		log.Warn().Err(err).Msg("The capture is left as it is.")
+		return
+	} else if err != nil {
+		log.Error().Err(err).Msg(fmt.Sprintf("Failed resuming the capture! Start it by `%s %s`.", misc.Program, startCmd.Use))
+		os.Exit(1)
+	}
+
+	log.Info().Msg("Resuming the capture...")
+	if !confirmCapture(kubernetesProvider, true) {
+		os.Exit(1)
+	}
+}
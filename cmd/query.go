@@ -0,0 +1,321 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/creasty/defaults"
+	"github.com/gorilla/websocket"
+	"github.com/kubeshark/kubeshark/config"
+	"github.com/kubeshark/kubeshark/config/configStructs"
+	"github.com/kubeshark/kubeshark/errormessage"
+	"github.com/kubeshark/kubeshark/kubernetes"
+	"github.com/kubeshark/kubeshark/utils"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// The table is streamed row by row, so its columns have fixed widths.
+const queryTableRowFormat = "%-25s  %-8s  %-32s  %-32s  %-8s  %-6s  %s\n"
+
+// A bounded stream is also considered caught up once no entry arrives for this long, for when no newer entry does.
+const queryIdleTimeout = 3 * time.Second
+
+// captureStart streams the entries since the start of the capture, i.e. all the stored ones, as a zero since time
+// streams only the new entries.
+var captureStart = time.UnixMilli(1)
+
+var queryCmd = &cobra.Command{
+	Use:   "query [KFL FILTER]",
+	Short: "Stream the dissected entries that match a KFL filter into shell as JSON lines or a table",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runQuery()
+		return nil
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 1 {
+			config.Config.Query.Filter = args[0]
+		} else if len(args) > 1 {
+			return errors.New("unexpected number of arguments")
+		}
+
+		if err := config.Config.Query.Validate(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(queryCmd)
+
+	defaultTapConfig := configStructs.TapConfig{}
+	if err := defaults.Set(&defaultTapConfig); err != nil {
+		log.Debug().Err(err).Send()
+	}
+
+	defaultQueryConfig := configStructs.QueryConfig{}
+	if err := defaults.Set(&defaultQueryConfig); err != nil {
+		log.Debug().Err(err).Send()
+	}
+
+	queryCmd.Flags().Uint16(configStructs.ProxyFrontPortLabel, defaultTapConfig.Proxy.Front.Port, "Provide a custom port for the Kubeshark")
+	queryCmd.Flags().String(configStructs.ProxyHostLabel, defaultTapConfig.Proxy.Host, "Provide a custom host for the Kubeshark")
+	queryCmd.Flags().StringP(configStructs.ReleaseNamespaceLabel, "s", defaultTapConfig.Release.Namespace, "Release namespace of Kubeshark")
+	queryCmd.Flags().Int(configStructs.LimitQueryName, defaultQueryConfig.Limit, "Exit after printing this many entries (0 means no limit)")
+	queryCmd.Flags().BoolP(configStructs.FollowQueryName, "f", defaultQueryConfig.Follow, "Keep streaming the new entries instead of exiting at the first one that's captured after the command started")
+	queryCmd.Flags().String(configStructs.SinceQueryName, defaultQueryConfig.Since, "Start from the entries captured since an RFC3339 timestamp or a duration ago (e.g. 15m), by default from the start of the capture, or from the new entries with --follow")
+	queryCmd.Flags().String(configStructs.FormatQueryName, defaultQueryConfig.Format, fmt.Sprintf("Output format, %s (JSON lines) or %s", configStructs.JsonQueryFormat, configStructs.TableQueryFormat))
+}
+
+type queryEntryEndpoint struct {
+	IP   string `json:"ip"`
+	Port string `json:"port"`
+	Name string `json:"name"`
+}
+
+type queryEntry struct {
+	Timestamp int64 `json:"timestamp"`
+	Protocol  struct {
+		Abbreviation string `json:"abbr"`
+	} `json:"proto"`
+	Method  string             `json:"method"`
+	Summary string             `json:"summary"`
+	Status  int                `json:"status"`
+	Src     queryEntryEndpoint `json:"src"`
+	Dst     queryEntryEndpoint `json:"dst"`
+}
+
+func (endpoint *queryEntryEndpoint) String() string {
+	if endpoint.Name != "" {
+		return endpoint.Name
+	}
+
+	return fmt.Sprintf("%s:%s", endpoint.IP, endpoint.Port)
+}
+
+func runQuery() {
+	resolveConfigSecrets(nil)
+	establishProxyIfNeeded()
+
+	since, _ := config.Config.Query.SinceTime()
+
+	// Without --follow, the entries that are captured after the command started are left out.
+	var until time.Time
+	if !config.Config.Query.Follow {
+		until = time.Now()
+		if since.IsZero() {
+			since = captureStart
+		}
+	}
+
+	log.Info().Str("url", kubernetes.GetHubUrl()).Str("filter", config.Config.Query.Filter).Msg("Querying:")
+
+	table := config.Config.Query.Format == configStructs.TableQueryFormat
+	if table {
+		fmt.Fprintf(os.Stdout, queryTableRowFormat, "TIME", "PROTOCOL", "SOURCE", "DESTINATION", "METHOD", "STATUS", "SUMMARY")
+	}
+
+	err := streamEntries(context.Background(), config.Config.Query.Filter, since, until, config.Config.Query.Limit, func(data []byte) error {
+		return printQueryEntry(data, table)
+	})
+	if err != nil {
+		log.Error().Err(err).Send()
+	}
+}
+
+// streamEntries subscribes to the entry stream of the Hub and calls handle for each entry that matches
+// the filter. It returns once the limit is reached, the context is done or an interrupt is received. Unless until is
+// zero, it also returns at the first entry that's captured after until, or once the stream is idle. The entries that
+// handle fails on are skipped and not counted.
+func streamEntries(ctx context.Context, filter string, since time.Time, until time.Time, limit int, handle func(data []byte) error) error {
+	params := url.Values{}
+	params.Set("q", filter)
+	if !since.IsZero() {
+		params.Set("since", fmt.Sprint(since.UnixMilli()))
+	}
+
+	u := url.URL{
+		Scheme:   "ws",
+		Host:     fmt.Sprintf("%s:%d", config.Config.Tap.Proxy.Host, config.Config.Tap.Proxy.Front.Port),
+		Path:     "/api/ws",
+		RawQuery: params.Encode(),
+	}
+	headers := http.Header{}
+	headers.Set(utils.X_KUBESHARK_CAPTURE_HEADER_KEY, utils.X_KUBESHARK_CAPTURE_HEADER_IGNORE_VALUE)
+	headers.Set("License-Key", config.Config.License)
+
+	c, _, err := websocket.DefaultDialer.Dial(u.String(), headers)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	// The reader blocks until each message is handled, so a slow handler or a blocked stdout doesn't drop
+	// messages. It's stopped by closing stop once the stream is done with.
+	messages := make(chan []byte)
+	done := make(chan struct{})
+	stop := make(chan struct{})
+	defer close(stop)
+
+	var readErr error
+	go func() {
+		defer close(done)
+		for {
+			_, message, err := c.ReadMessage()
+			if err != nil {
+				if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+					readErr = err
+				}
+				return
+			}
+
+			select {
+			case messages <- message:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	bounded := !until.IsZero()
+
+	var idle <-chan time.Time
+	if bounded {
+		idle = time.After(queryIdleTimeout)
+	}
+
+	count := 0
+	for {
+		select {
+		case message := <-messages:
+			data, ok, err := unwrapEntryMessage(message)
+			if err == nil && ok && bounded && entryTime(data).After(until) {
+				log.Debug().Int("entries", count).Time("until", until).Msg("Entries stream is caught up.")
+				closeEntriesStream(c, done)
+				return nil
+			}
+			if err == nil && ok {
+				err = handle(data)
+			}
+			if err != nil {
+				log.Debug().Err(err).Str("message", string(message)).Msg("Skipping a message that is not an entry:")
+				continue
+			}
+			if !ok {
+				continue
+			}
+
+			count++
+			if limit > 0 && count >= limit {
+				closeEntriesStream(c, done)
+				return nil
+			}
+
+			if bounded {
+				idle = time.After(queryIdleTimeout)
+			}
+		case <-idle:
+			log.Debug().Int("entries", count).Msg("Entries stream is caught up.")
+			closeEntriesStream(c, done)
+			return nil
+		case <-done:
+			return readErr
+		case <-ctx.Done():
+			closeEntriesStream(c, done)
+			return nil
+		case <-interrupt:
+			log.Warn().Msg(fmt.Sprintf(utils.Yellow, "Received interrupt, exiting..."))
+			closeEntriesStream(c, done)
+			return nil
+		}
+	}
+}
+
+// entryTime returns the capture time of an entry, or the zero time if it has none.
+func entryTime(data []byte) time.Time {
+	var entry struct {
+		Timestamp int64 `json:"timestamp"`
+	}
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Timestamp == 0 {
+		return time.Time{}
+	}
+
+	return time.UnixMilli(entry.Timestamp)
+}
+
+// unwrapEntryMessage returns the entry of a message of the entries stream. The Hub might wrap
+// the entries into typed messages, in which case ok is false for the messages of other types.
+func unwrapEntryMessage(message []byte) (data []byte, ok bool, err error) {
+	var wrapped struct {
+		MessageType string          `json:"messageType"`
+		Data        json.RawMessage `json:"data"`
+	}
+	if err = json.Unmarshal(message, &wrapped); err != nil {
+		return
+	}
+
+	if wrapped.MessageType == "" {
+		return message, true, nil
+	}
+
+	if wrapped.MessageType != "entry" {
+		return
+	}
+
+	return wrapped.Data, true, nil
+}
+
+func printQueryEntry(data []byte, table bool) error {
+	if !table {
+		buf := new(bytes.Buffer)
+		if err := json.Compact(buf, data); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stdout, buf.String())
+		return nil
+	}
+
+	var entry queryEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, queryTableRowFormat,
+		time.UnixMilli(entry.Timestamp).Format(time.RFC3339),
+		entry.Protocol.Abbreviation,
+		entry.Src.String(),
+		entry.Dst.String(),
+		entry.Method,
+		fmt.Sprint(entry.Status),
+		entry.Summary,
+	)
+	return nil
+}
+
+func closeEntriesStream(c *websocket.Conn, done chan struct{}) {
+	err := c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	if err != nil {
+		log.Error().Err(err).Send()
+		return
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+	}
+}
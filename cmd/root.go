@@ -32,7 +32,11 @@ func init() {
 	}
 
 	rootCmd.PersistentFlags().StringSlice(config.SetCommandName, []string{}, fmt.Sprintf("Override values using --%s", config.SetCommandName))
+	rootCmd.PersistentFlags().StringArray(config.SetJsonCommandName, []string{}, fmt.Sprintf("Override values with JSON using --%s, e.g. tap.nodeSelectorTerms='[{...}]'", config.SetJsonCommandName))
+	rootCmd.PersistentFlags().StringArray(config.SetFileCommandName, []string{}, fmt.Sprintf("Override values with the content of files using --%s, e.g. tap.auth.saml.x509crt=./cert.pem", config.SetFileCommandName))
 	rootCmd.PersistentFlags().BoolP(config.DebugFlag, "d", false, "Enable debug mode")
+	rootCmd.PersistentFlags().StringSlice(config.ConfigFilesFlag, []string{}, "Config files that override the global and the project config files, in the given order")
+	rootCmd.PersistentFlags().String(config.ProfileFlag, "", "Apply the profile of this name, from the profiles section of the config files")
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
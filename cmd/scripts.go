@@ -2,13 +2,17 @@ package cmd
 
 import (
 	"context"
-	"fmt"
-	"net/http"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/creasty/defaults"
 	"github.com/fsnotify/fsnotify"
 	"github.com/kubeshark/kubeshark/config"
 	"github.com/kubeshark/kubeshark/config/configStructs"
+	"github.com/kubeshark/kubeshark/debounce"
 	"github.com/kubeshark/kubeshark/internal/connect"
 	"github.com/kubeshark/kubeshark/kubernetes"
 	"github.com/kubeshark/kubeshark/misc"
@@ -17,6 +21,13 @@ import (
 	"github.com/spf13/cobra"
 )
 
+const scriptsDebounceTimeout = 500 * time.Millisecond
+const scriptsReconcileInterval = 1 * time.Minute
+
+// trackedScripts are the indexes of the Hub scripts that the watcher has created or updated. The watcher deletes
+// only these, the other scripts on the Hub, e.g. the ones that are created in the UI, are left to `scripts sync`.
+var trackedScripts = make(map[int64]bool)
+
 var scriptsCmd = &cobra.Command{
 	Use:   "scripts",
 	Short: "Watch the `scripting.source` directory for changes and update the scripts",
@@ -45,36 +56,31 @@ func runScripts() {
 		return
 	}
 
-	hubUrl := kubernetes.GetHubUrl()
-	response, err := http.Get(fmt.Sprintf("%s/echo", hubUrl))
-	if err != nil || response.StatusCode != 200 {
-		log.Info().Msg(fmt.Sprintf(utils.Yellow, "Couldn't connect to Hub. Establishing proxy..."))
-		runProxy(false, true)
-	}
+	resolveConfigSecrets(nil)
+
+	establishProxyIfNeeded()
 
 	connector = connect.NewConnector(kubernetes.GetHubUrl(), connect.DefaultRetries, connect.DefaultTimeout)
 
 	watchScripts(true)
 }
 
+// watchScripts keeps the scripts on the Hub in sync with the `scripting.source` directory tree. Instead of
+// tracking the Hub indexes of the individual files, every burst of file system events triggers a reconciliation
+// of the whole tree against the scripts on the Hub. It copes with removes, renames and the atomic saves of
+// the editors the same way, and it survives restarts. The tree is also reconciled periodically, to undo
+// the changes that are made on the Hub from elsewhere.
 func watchScripts(block bool) {
-	files := make(map[string]int64)
+	source := config.Config.Scripting.Source
 
-	scripts, err := config.Config.Scripting.GetScripts()
-	if err != nil {
-		log.Error().Err(err).Send()
-		return
+	var mutex sync.Mutex
+	reconcile := func() {
+		mutex.Lock()
+		defer mutex.Unlock()
+		reconcileScripts()
 	}
 
-	for _, script := range scripts {
-		index, err := connector.PostScript(script)
-		if err != nil {
-			log.Error().Err(err).Send()
-			return
-		}
-
-		files[script.Path] = index
-	}
+	reconcile()
 
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
@@ -85,65 +91,59 @@ func watchScripts(block bool) {
 		defer watcher.Close()
 	}
 
+	if err := addScriptsWatches(watcher, source); err != nil {
+		log.Error().Err(err).Send()
+		return
+	}
+
+	debouncer := debounce.NewDebouncer(scriptsDebounceTimeout, func() {
+		go reconcile()
+	})
+
 	go func() {
+		ticker := time.NewTicker(scriptsReconcileInterval)
+		defer ticker.Stop()
+
 		for {
 			select {
 			// watch for events
-			case event := <-watcher.Events:
-				switch event.Op {
-				case fsnotify.Create:
-					script, err := misc.ReadScriptFile(event.Name)
-					if err != nil {
-						log.Error().Err(err).Send()
-						continue
-					}
-
-					index, err := connector.PostScript(script)
-					if err != nil {
-						log.Error().Err(err).Send()
-						continue
-					}
-
-					files[script.Path] = index
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
 
-				case fsnotify.Write:
-					index := files[event.Name]
-					script, err := misc.ReadScriptFile(event.Name)
-					if err != nil {
-						log.Error().Err(err).Send()
+				if event.Has(fsnotify.Create) {
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+						if err := addScriptsWatches(watcher, event.Name); err != nil {
+							log.Error().Err(err).Send()
+						}
+						_ = debouncer.SetOn()
 						continue
 					}
+				}
 
-					err = connector.PutScript(script, index)
-					if err != nil {
-						log.Error().Err(err).Send()
-						continue
-					}
+				if filepath.Ext(event.Name) != ".js" && !event.Has(fsnotify.Remove) && !event.Has(fsnotify.Rename) {
+					continue
+				}
 
-				case fsnotify.Rename:
-					index := files[event.Name]
-					err := connector.DeleteScript(index)
-					if err != nil {
-						log.Error().Err(err).Send()
-						continue
-					}
+				log.Debug().Str("path", event.Name).Str("op", event.Op.String()).Msg("Script change:")
+				_ = debouncer.SetOn()
 
-				default:
-					// pass
-				}
+			// reconcile periodically
+			case <-ticker.C:
+				reconcile()
 
 			// watch for errors
-			case err := <-watcher.Errors:
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
 				log.Error().Err(err).Send()
 			}
 		}
 	}()
 
-	if err := watcher.Add(config.Config.Scripting.Source); err != nil {
-		log.Error().Err(err).Send()
-	}
-
-	log.Info().Str("directory", config.Config.Scripting.Source).Msg("Watching scripts against changes:")
+	log.Info().Str("directory", source).Msg("Watching scripts against changes:")
 
 	if block {
 		ctx, cancel := context.WithCancel(context.Background())
@@ -151,3 +151,96 @@ func watchScripts(block bool) {
 		utils.WaitForTermination(ctx, cancel)
 	}
 }
+
+// addScriptsWatches watches a directory and its subdirectories, as fsnotify is not recursive.
+func addScriptsWatches(watcher *fsnotify.Watcher, dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		log.Debug().Str("directory", path).Msg("Watching scripts directory:")
+		return watcher.Add(path)
+	})
+}
+
+// reconcileScripts applies the difference between the `scripting.source` directory tree and the scripts on the Hub.
+// Nothing is applied when a script fails to parse, e.g. while it is being edited, so it is not deleted from the Hub.
+// The Hub scripts with the titles of the local scripts are updated and tracked, while only the tracked scripts
+// are deleted, see trackedScripts.
+func reconcileScripts() {
+	scripts, err := config.Config.Scripting.GetScripts()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed reading the scripts, skipping the sync.")
+		return
+	}
+
+	current, err := connector.ListScripts()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed listing the scripts on the Hub!")
+		return
+	}
+
+	plan, err := planScriptChanges(scripts, current, trackedScripts)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed planning the scripts sync!")
+		return
+	}
+
+	trackMatchedScripts(scripts, current, plan)
+
+	var changes []*scriptChange
+	for _, change := range plan {
+		if change.action == scriptActionDelete && !trackedScripts[change.index] {
+			log.Debug().Str("title", change.current.Title).Int64("index", change.index).Msg("Keeping an untracked script:")
+			continue
+		}
+		changes = append(changes, change)
+	}
+
+	for _, change := range changes {
+		script := change.current
+		if change.script != nil {
+			script = change.script
+		}
+		log.Info().Str("action", change.action).Str("title", script.Title).Str("path", script.Path).Msg("Syncing script:")
+	}
+
+	if len(changes) == 0 {
+		return
+	}
+
+	for _, change := range applyScriptChanges(changes) {
+		if change.action == scriptActionDelete {
+			delete(trackedScripts, change.index)
+		} else {
+			trackedScripts[change.index] = true
+		}
+	}
+}
+
+// trackMatchedScripts tracks the Hub scripts that the plan keeps for the local scripts of the same titles, even if
+// these are up-to-date, so that these are deleted once the local scripts are.
+func trackMatchedScripts(scripts []*misc.Script, current map[int64]*misc.Script, plan []*scriptChange) {
+	titles := make(map[string]bool)
+	for _, script := range scripts {
+		titles[script.Title] = true
+	}
+
+	deleted := make(map[int64]bool)
+	for _, change := range plan {
+		if change.action == scriptActionDelete {
+			deleted[change.index] = true
+		}
+	}
+
+	for index, script := range current {
+		if titles[script.Title] && !deleted[index] {
+			trackedScripts[index] = true
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/creasty/defaults"
+	"github.com/kubeshark/kubeshark/config"
+	"github.com/kubeshark/kubeshark/config/configStructs"
+	"github.com/kubeshark/kubeshark/internal/connect"
+	"github.com/kubeshark/kubeshark/kubernetes"
+	"github.com/kubeshark/kubeshark/misc"
+	"github.com/kubeshark/kubeshark/utils"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var scriptsSyncCmd = &cobra.Command{
+	Use:   "sync [PATH]",
+	Short: "Sync the scripts on the Hub with a directory tree or a YAML bundle (defaults to `scripting.source`)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		source := config.Config.Scripting.Source
+		if len(args) == 1 {
+			source = args[0]
+		} else if len(args) > 1 {
+			return errors.New("unexpected number of arguments")
+		}
+
+		runScriptsSync(source)
+		return nil
+	},
+}
+
+func init() {
+	scriptsCmd.AddCommand(scriptsSyncCmd)
+
+	defaultTapConfig := configStructs.TapConfig{}
+	if err := defaults.Set(&defaultTapConfig); err != nil {
+		log.Debug().Err(err).Send()
+	}
+
+	scriptsSyncCmd.Flags().Uint16(configStructs.ProxyFrontPortLabel, defaultTapConfig.Proxy.Front.Port, "Provide a custom port for the Kubeshark")
+	scriptsSyncCmd.Flags().String(configStructs.ProxyHostLabel, defaultTapConfig.Proxy.Host, "Provide a custom host for the Kubeshark")
+	scriptsSyncCmd.Flags().StringP(configStructs.ReleaseNamespaceLabel, "s", defaultTapConfig.Release.Namespace, "Release namespace of Kubeshark")
+	scriptsSyncCmd.Flags().Bool(configStructs.PlanScriptingSyncName, false, "Only print the plan, without applying it")
+	scriptsSyncCmd.Flags().BoolP(configStructs.YesScriptingSyncName, "y", false, "Apply the plan without asking for a confirmation")
+}
+
+const (
+	scriptActionCreate = "create"
+	scriptActionUpdate = "update"
+	scriptActionDelete = "delete"
+)
+
+type scriptChange struct {
+	action  string
+	index   int64
+	script  *misc.Script
+	current *misc.Script
+}
+
+func runScriptsSync(source string) {
+	if source == "" {
+		log.Error().Msg("Neither a path is given nor the `scripting.source` field is set.")
+		return
+	}
+
+	scripts, err := misc.ReadScripts(source)
+	if err != nil {
+		log.Error().Err(err).Send()
+		return
+	}
+
+	resolveConfigSecrets(nil)
+	establishProxyIfNeeded()
+
+	connector = connect.NewConnector(kubernetes.GetHubUrl(), connect.DefaultRetries, connect.DefaultTimeout)
+
+	current, err := connector.ListScripts()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed listing the scripts on the Hub!")
+		return
+	}
+
+	changes, err := planScriptChanges(scripts, current, nil)
+	if err != nil {
+		log.Error().Err(err).Send()
+		return
+	}
+
+	printScriptChanges(changes)
+
+	if len(changes) == 0 || config.Config.Scripting.Sync.Plan {
+		return
+	}
+
+	if !config.Config.Scripting.Sync.Yes && !utils.AskForConfirmation("Do you want to apply these changes to the Hub?") {
+		log.Warn().Msg("Sync is cancelled.")
+		return
+	}
+
+	applyScriptChanges(changes)
+}
+
+// planScriptChanges matches the desired scripts with the scripts on the Hub by their titles. The scripts
+// that are on the Hub but not desired, including the duplicates of the same title, are deleted. Of the
+// duplicates, the preferred indexes are matched first.
+func planScriptChanges(scripts []*misc.Script, current map[int64]*misc.Script, preferred map[int64]bool) (changes []*scriptChange, err error) {
+	desired := make(map[string]*misc.Script)
+	for _, script := range scripts {
+		if _, ok := desired[script.Title]; ok {
+			err = fmt.Errorf("duplicate script title %q in %s", script.Title, script.Path)
+			return
+		}
+
+		script.Env, err = normalizeScriptEnv(script.Env)
+		if err != nil {
+			return
+		}
+
+		desired[script.Title] = script
+	}
+
+	indexes := make([]int64, 0, len(current))
+	for index := range current {
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		if preferred[indexes[i]] != preferred[indexes[j]] {
+			return preferred[indexes[i]]
+		}
+		return indexes[i] < indexes[j]
+	})
+
+	matched := make(map[string]bool)
+	for _, index := range indexes {
+		script := current[index]
+		wanted, ok := desired[script.Title]
+		if !ok || matched[script.Title] {
+			changes = append(changes, &scriptChange{action: scriptActionDelete, index: index, current: script})
+			continue
+		}
+
+		matched[script.Title] = true
+		if wanted.Code != script.Code || !scriptEnvEqual(wanted.Env, script.Env) {
+			changes = append(changes, &scriptChange{action: scriptActionUpdate, index: index, script: wanted, current: script})
+		}
+	}
+
+	for _, script := range scripts {
+		if !matched[script.Title] {
+			changes = append(changes, &scriptChange{action: scriptActionCreate, script: script})
+		}
+	}
+
+	return
+}
+
+// normalizeScriptEnv round trips the env through JSON, so it compares equal to the env that is returned by the Hub.
+func normalizeScriptEnv(env map[string]interface{}) (map[string]interface{}, error) {
+	if len(env) == 0 {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+
+	var normalized map[string]interface{}
+	err = json.Unmarshal(data, &normalized)
+	return normalized, err
+}
+
+func scriptEnvEqual(a map[string]interface{}, b map[string]interface{}) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// scriptVersion is a short digest of a script's code, used to tell the versions of a script apart in the plan.
+func scriptVersion(script *misc.Script) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(script.Code)))[:8]
+}
+
+func printScriptChanges(changes []*scriptChange) {
+	var created, updated, deleted int
+	for _, change := range changes {
+		switch change.action {
+		case scriptActionCreate:
+			created++
+			fmt.Fprintf(os.Stdout, utils.Green+"\n", fmt.Sprintf("  + create %q (%s) from %s", change.script.Title, scriptVersion(change.script), change.script.Path))
+		case scriptActionUpdate:
+			updated++
+			var fields []string
+			if change.script.Code != change.current.Code {
+				fields = append(fields, fmt.Sprintf("code: %s -> %s", scriptVersion(change.current), scriptVersion(change.script)))
+			}
+			if !scriptEnvEqual(change.script.Env, change.current.Env) {
+				fields = append(fields, "env")
+			}
+			fmt.Fprintf(os.Stdout, utils.Yellow+"\n", fmt.Sprintf("  ~ update %q [%d] (%s)", change.script.Title, change.index, strings.Join(fields, ", ")))
+		case scriptActionDelete:
+			deleted++
+			fmt.Fprintf(os.Stdout, utils.Red+"\n", fmt.Sprintf("  - delete %q [%d] (%s)", change.current.Title, change.index, scriptVersion(change.current)))
+		}
+	}
+
+	if len(changes) == 0 {
+		fmt.Fprintln(os.Stdout, "No changes. The scripts on the Hub are up-to-date.")
+		return
+	}
+
+	fmt.Fprintf(os.Stdout, "\nPlan: %d to create, %d to update, %d to delete.\n", created, updated, deleted)
+}
+
+// applyScriptChanges applies the changes to the Hub and returns the ones that succeeded, the indexes of the
+// created scripts included.
+func applyScriptChanges(changes []*scriptChange) (applied []*scriptChange) {
+	var failed int
+	for _, change := range changes {
+		var err error
+		switch change.action {
+		case scriptActionCreate:
+			change.index, err = connector.PostScript(change.script)
+		case scriptActionUpdate:
+			err = connector.PutScript(change.script, change.index)
+		case scriptActionDelete:
+			err = connector.DeleteScript(change.index)
+		}
+
+		if err != nil {
+			failed++
+			log.Error().Str("action", change.action).Int64("index", change.index).Err(err).Msg("Failed applying a script change!")
+			continue
+		}
+
+		applied = append(applied, change)
+	}
+
+	if failed > 0 {
+		log.Error().Int("failed", failed).Int("total", len(changes)).Msg("Sync is partially applied.")
+		return
+	}
+
+	log.Info().Int("changes", len(changes)).Msg("Sync is applied.")
+	return
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kubeshark/kubeshark/misc"
+)
+
+func TestPlanScriptChanges(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Scripts   []*misc.Script
+		Current   map[int64]*misc.Script
+		Preferred map[int64]bool
+		Expected  []string
+		Err       string
+	}{
+		{
+			Name:     "up-to-date",
+			Scripts:  []*misc.Script{{Path: "a.js", Title: "a", Code: "1"}},
+			Current:  map[int64]*misc.Script{0: {Title: "a", Code: "1"}},
+			Expected: nil,
+		},
+		{
+			Name:     "new and changed scripts",
+			Scripts:  []*misc.Script{{Path: "a.js", Title: "a", Code: "2"}, {Path: "b.js", Title: "b", Code: "1"}},
+			Current:  map[int64]*misc.Script{0: {Title: "a", Code: "1"}},
+			Expected: []string{"update a [0]", "create b"},
+		},
+		{
+			Name:     "changed env",
+			Scripts:  []*misc.Script{{Path: "a.js", Title: "a", Code: "1", Env: map[string]interface{}{"limit": 10}}},
+			Current:  map[int64]*misc.Script{0: {Title: "a", Code: "1", Env: map[string]interface{}{"limit": float64(5)}}},
+			Expected: []string{"update a [0]"},
+		},
+		{
+			Name:     "env as the Hub returns it",
+			Scripts:  []*misc.Script{{Path: "a.js", Title: "a", Code: "1", Env: map[string]interface{}{"limit": 10}}},
+			Current:  map[int64]*misc.Script{0: {Title: "a", Code: "1", Env: map[string]interface{}{"limit": float64(10)}}},
+			Expected: nil,
+		},
+		{
+			Name:     "renamed file",
+			Scripts:  []*misc.Script{{Path: "renamed.js", Title: "a", Code: "1"}},
+			Current:  map[int64]*misc.Script{0: {Path: "a.js", Title: "a", Code: "1"}},
+			Expected: nil,
+		},
+		{
+			Name:     "renamed title",
+			Scripts:  []*misc.Script{{Path: "a.js", Title: "renamed", Code: "1"}},
+			Current:  map[int64]*misc.Script{0: {Title: "a", Code: "1"}},
+			Expected: []string{"delete a [0]", "create renamed"},
+		},
+		{
+			Name:     "untracked scripts on the Hub",
+			Scripts:  []*misc.Script{{Path: "a.js", Title: "a", Code: "1"}},
+			Current:  map[int64]*misc.Script{0: {Title: "a", Code: "1"}, 1: {Title: "b", Code: "1"}, 2: {Title: "c", Code: "1"}},
+			Expected: []string{"delete b [1]", "delete c [2]"},
+		},
+		{
+			Name:     "no scripts",
+			Current:  map[int64]*misc.Script{0: {Title: "a", Code: "1"}},
+			Expected: []string{"delete a [0]"},
+		},
+		{
+			Name:     "duplicate titles on the Hub",
+			Scripts:  []*misc.Script{{Path: "a.js", Title: "a", Code: "2"}},
+			Current:  map[int64]*misc.Script{3: {Title: "a", Code: "1"}, 1: {Title: "a", Code: "2"}},
+			Expected: []string{"delete a [3]"},
+		},
+		{
+			Name:      "duplicate titles on the Hub with a preferred index",
+			Scripts:   []*misc.Script{{Path: "a.js", Title: "a", Code: "2"}},
+			Current:   map[int64]*misc.Script{3: {Title: "a", Code: "1"}, 1: {Title: "a", Code: "2"}},
+			Preferred: map[int64]bool{3: true},
+			Expected:  []string{"update a [3]", "delete a [1]"},
+		},
+		{
+			Name:    "duplicate titles in the source",
+			Scripts: []*misc.Script{{Path: "a.js", Title: "a", Code: "1"}, {Path: "dir/a.js", Title: "a", Code: "2"}},
+			Err:     `duplicate script title "a" in dir/a.js`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			changes, err := planScriptChanges(test.Scripts, test.Current, test.Preferred)
+
+			if test.Err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.Err) {
+					t.Errorf("unexpected error - expected: %v, actual: %v", test.Err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error - %v", err)
+			}
+
+			var actual []string
+			for _, change := range changes {
+				switch change.action {
+				case scriptActionCreate:
+					actual = append(actual, fmt.Sprintf("%s %s", change.action, change.script.Title))
+				default:
+					actual = append(actual, fmt.Sprintf("%s %s [%d]", change.action, change.current.Title, change.index))
+				}
+			}
+
+			if !reflect.DeepEqual(actual, test.Expected) {
+				t.Errorf("unexpected changes - expected: %v, actual: %v", test.Expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/creasty/defaults"
+	"github.com/kubeshark/kubeshark/config"
+	"github.com/kubeshark/kubeshark/config/configStructs"
+	"github.com/kubeshark/kubeshark/misc"
+	"github.com/kubeshark/kubeshark/utils"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var scriptsTestCmd = &cobra.Command{
+	Use:   "test <FILE>",
+	Short: "Run a script locally against the recorded entries of a fixture, with the Hub helpers stubbed out",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !runScriptsTest(args[0]) {
+			os.Exit(1)
+		}
+		return nil
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("unexpected number of arguments, expected the path of a script")
+		}
+
+		if !utils.Contains(misc.ScriptHooks, config.Config.Scripting.Test.Hook) {
+			return fmt.Errorf("unknown hook %q, expected one of: %s", config.Config.Scripting.Test.Hook, strings.Join(misc.ScriptHooks, ", "))
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	scriptsCmd.AddCommand(scriptsTestCmd)
+
+	defaultScriptingConfig := configStructs.ScriptingConfig{}
+	if err := defaults.Set(&defaultScriptingConfig); err != nil {
+		log.Debug().Err(err).Send()
+	}
+
+	scriptsTestCmd.Flags().String(configStructs.FixtureScriptingTestName, defaultScriptingConfig.Test.Fixture, "JSON file of the recorded entries to run the hook against (JSON array or JSON lines, e.g. the output of `query`)")
+	scriptsTestCmd.Flags().String(configStructs.HookScriptingTestName, defaultScriptingConfig.Test.Hook, fmt.Sprintf("The hook to call for each entry of the fixture, one of: %s", strings.Join(misc.ScriptHooks, ", ")))
+}
+
+// runScriptsTest evaluates the script and runs the hook against every entry of the fixture.
+// It returns false if the script fails to evaluate, defines no hooks or throws in any run.
+func runScriptsTest(path string) bool {
+	script, err := misc.ReadScriptFile(path)
+	if err != nil {
+		log.Error().Str("path", path).Err(err).Msg("Failed parsing the script!")
+		return false
+	}
+
+	harness, err := misc.NewScriptHarness(script, config.Config.Scripting.Env)
+	if err != nil {
+		log.Error().Err(err).Send()
+		return false
+	}
+
+	fmt.Fprintf(os.Stdout, "Script %q defines: %s\n", script.Title, strings.Join(harness.Hooks(), ", "))
+	printScriptCalls(harness.Calls())
+
+	if config.Config.Scripting.Test.Fixture == "" {
+		log.Info().Msg("No fixture is given, only the script is validated.")
+		return true
+	}
+
+	hook := config.Config.Scripting.Test.Hook
+	if !harness.HasHook(hook) {
+		log.Error().Str("hook", hook).Msg("The script does not define the hook!")
+		return false
+	}
+
+	entries, err := misc.ReadScriptFixture(config.Config.Scripting.Test.Fixture)
+	if err != nil {
+		log.Error().Err(err).Send()
+		return false
+	}
+
+	failed := 0
+	for i, entry := range entries {
+		result := harness.RunHook(hook, entry)
+
+		fmt.Fprintf(os.Stdout, "\n#%d %s:\n", i+1, hook)
+		printScriptCalls(result.Calls)
+		if result.Return != "" {
+			fmt.Fprintf(os.Stdout, "  returned: %s\n", result.Return)
+		}
+		if result.Error != "" {
+			failed++
+			fmt.Fprintf(os.Stdout, utils.Red+"\n", fmt.Sprintf("  error: %s", result.Error))
+		}
+	}
+
+	fmt.Fprintf(os.Stdout, "\nRan %s against %d entries, %d failed.\n", hook, len(entries), failed)
+
+	return failed == 0
+}
+
+func printScriptCalls(calls []misc.ScriptCall) {
+	for _, call := range calls {
+		fmt.Fprintf(os.Stdout, "  %s\n", call.String())
+	}
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/kubeshark/kubeshark/misc"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var startCmd = &cobra.Command{
+	Use:   "start",
+	Short: fmt.Sprintf("Start the traffic capture of a running %s, that is stopped or paused", misc.Software),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 0 {
+			return errors.New("unexpected number of arguments, expected none")
+		}
+
+		runStart()
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(startCmd)
+	addCaptureFlags(startCmd)
+}
+
+func runStart() {
+	kubernetesProvider, err := getKubernetesProviderForCli(true, true)
+	if err != nil {
+		os.Exit(1)
+	}
+
+	resolveConfigSecrets(kubernetesProvider)
+
+	if pausedUntil, expired := expiredPause(kubernetesProvider); expired {
+		log.Warn().Str("until", pausedUntil).Msg("The capture is still paused after its pause expired, starting it...")
+	}
+
+	runSetCapture(kubernetesProvider, false, "")
+}
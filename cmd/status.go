@@ -0,0 +1,474 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/creasty/defaults"
+	"github.com/kubeshark/kubeshark/config"
+	"github.com/kubeshark/kubeshark/config/configStructs"
+	"github.com/kubeshark/kubeshark/errormessage"
+	"github.com/kubeshark/kubeshark/kubernetes"
+	"github.com/kubeshark/kubeshark/kubernetes/helm"
+	"github.com/kubeshark/kubeshark/misc"
+	"github.com/kubeshark/kubeshark/utils"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	core "k8s.io/api/core/v1"
+)
+
+// Only the warning events that are seen within this window are reported.
+const statusEventsWindow = 1 * time.Hour
+const statusEventsLimit = 20
+const statusTimeout = 30 * time.Second
+
+var statusComponents = []string{"hub", "front", "worker"}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: fmt.Sprintf("Summarize the health of the %s deployment", misc.Software),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runStatus()
+		return nil
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.Config.Status.Validate(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+
+	defaultTapConfig := configStructs.TapConfig{}
+	if err := defaults.Set(&defaultTapConfig); err != nil {
+		log.Debug().Err(err).Send()
+	}
+
+	defaultStatusConfig := configStructs.StatusConfig{}
+	if err := defaults.Set(&defaultStatusConfig); err != nil {
+		log.Debug().Err(err).Send()
+	}
+
+	statusCmd.Flags().Uint16(configStructs.ProxyFrontPortLabel, defaultTapConfig.Proxy.Front.Port, "Provide a custom port for the Kubeshark")
+	statusCmd.Flags().String(configStructs.ProxyHostLabel, defaultTapConfig.Proxy.Host, "Provide a custom host for the Kubeshark")
+	statusCmd.Flags().StringP(configStructs.ReleaseNamespaceLabel, "s", defaultTapConfig.Release.Namespace, "Release namespace of Kubeshark")
+	statusCmd.Flags().String(configStructs.FormatStatusName, defaultStatusConfig.Format, fmt.Sprintf("Output format, %s or %s", configStructs.TableStatusFormat, configStructs.JsonStatusFormat))
+}
+
+type statusRelease struct {
+	Name         string    `json:"name"`
+	Namespace    string    `json:"namespace"`
+	Chart        string    `json:"chart,omitempty"`
+	ChartVersion string    `json:"chartVersion,omitempty"`
+	AppVersion   string    `json:"appVersion,omitempty"`
+	Revision     int       `json:"revision,omitempty"`
+	Status       string    `json:"status,omitempty"`
+	Updated      time.Time `json:"updated,omitempty"`
+	Error        string    `json:"error,omitempty"`
+}
+
+type statusPod struct {
+	Component string `json:"component"`
+	Name      string `json:"name"`
+	Node      string `json:"node"`
+	Phase     string `json:"phase"`
+	Ready     bool   `json:"ready"`
+	Restarts  int32  `json:"restarts"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+type statusWorkers struct {
+	Desired int32  `json:"desired"`
+	Ready   int32  `json:"ready"`
+	Error   string `json:"error,omitempty"`
+}
+
+type statusEvent struct {
+	Time    time.Time `json:"time"`
+	Object  string    `json:"object"`
+	Reason  string    `json:"reason"`
+	Message string    `json:"message"`
+	Count   int32     `json:"count"`
+}
+
+type statusEndpoint struct {
+	Name      string `json:"name"`
+	Url       string `json:"url"`
+	Reachable bool   `json:"reachable"`
+	Error     string `json:"error,omitempty"`
+}
+
+type statusStorage struct {
+	Node  string `json:"node"`
+	Pod   string `json:"pod"`
+	Used  int64  `json:"used"`
+	Limit string `json:"limit"`
+	Error string `json:"error,omitempty"`
+}
+
+type statusReport struct {
+	Release statusRelease     `json:"release"`
+	Pods    []statusPod       `json:"pods"`
+	Workers statusWorkers     `json:"workers"`
+	Events  []statusEvent     `json:"events"`
+	Config  map[string]string `json:"config"`
+	Proxy   []statusEndpoint  `json:"proxy"`
+	Storage []statusStorage   `json:"storage"`
+	Healthy bool              `json:"healthy"`
+}
+
+func runStatus() {
+	kubernetesProvider, err := getKubernetesProviderForCli(true, true)
+	if err != nil {
+		return
+	}
+
+	// The status is read-only, so an expired pause is only reported.
+	if pausedUntil, expired := expiredPause(kubernetesProvider); expired {
+		log.Warn().Str("until", pausedUntil).Msg(fmt.Sprintf("The capture is still paused after its pause expired, resume it by `%s start`.", misc.Program))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+	defer cancel()
+
+	report := collectStatus(ctx, kubernetesProvider)
+
+	if config.Config.Status.Format == configStructs.JsonStatusFormat {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Error().Err(err).Send()
+		}
+		return
+	}
+
+	printStatus(report)
+}
+
+func collectStatus(ctx context.Context, kubernetesProvider *kubernetes.Provider) *statusReport {
+	namespace := config.Config.Tap.Release.Namespace
+
+	report := &statusReport{
+		Release: getStatusRelease(),
+		Pods:    []statusPod{},
+		Events:  []statusEvent{},
+		Config:  map[string]string{},
+		Storage: []statusStorage{},
+	}
+
+	var workerPods []core.Pod
+	for _, component := range statusComponents {
+		pods, err := kubernetesProvider.ListPodsByAppLabel(ctx, namespace, map[string]string{kubernetes.AppLabelKey: component})
+		if err != nil {
+			log.Error().Str("component", component).Err(err).Msg("Failed listing the pods!")
+			continue
+		}
+
+		for _, pod := range pods {
+			report.Pods = append(report.Pods, getStatusPod(component, &pod))
+		}
+
+		if component == "worker" {
+			workerPods = pods
+		}
+	}
+
+	daemonSet, err := kubernetesProvider.GetDaemonSet(ctx, namespace, kubernetes.WorkerDaemonSetName)
+	if err != nil {
+		report.Workers.Error = err.Error()
+	} else {
+		report.Workers.Desired = daemonSet.Status.DesiredNumberScheduled
+		report.Workers.Ready = daemonSet.Status.NumberReady
+	}
+
+	report.Events = getStatusEvents(ctx, kubernetesProvider, namespace)
+
+	configMap, err := kubernetesProvider.GetConfigMap(ctx, namespace, kubernetes.SELF_RESOURCES_PREFIX+kubernetes.SUFFIX_CONFIG_MAP)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed getting the config map!")
+	} else if configMap.Data != nil {
+		report.Config = configMap.Data
+	}
+
+	report.Proxy = []statusEndpoint{
+		getStatusEndpoint("hub", fmt.Sprintf("%s/echo", kubernetes.GetHubUrl())),
+		getStatusEndpoint("front", kubernetes.GetProxyOnPort(config.Config.Tap.Proxy.Front.Port)),
+	}
+
+	report.Storage = getStatusStorage(ctx, kubernetesProvider, workerPods)
+
+	report.Healthy = report.Release.Error == "" && report.Workers.Error == "" && report.Workers.Ready == report.Workers.Desired
+	for _, pod := range report.Pods {
+		if !pod.Ready {
+			report.Healthy = false
+		}
+	}
+
+	return report
+}
+
+func getStatusRelease() statusRelease {
+	release := statusRelease{
+		Name:      config.Config.Tap.Release.Name,
+		Namespace: config.Config.Tap.Release.Namespace,
+	}
+
+	rel, err := helm.NewHelm(
+		config.Config.Tap.Release.Repo,
+		config.Config.Tap.Release.Name,
+		config.Config.Tap.Release.Namespace,
+	).Status()
+	if err != nil {
+		release.Error = err.Error()
+		return release
+	}
+
+	if rel.Chart != nil && rel.Chart.Metadata != nil {
+		release.Chart = rel.Chart.Metadata.Name
+		release.ChartVersion = rel.Chart.Metadata.Version
+		release.AppVersion = rel.Chart.Metadata.AppVersion
+	}
+	release.Revision = rel.Version
+	if rel.Info != nil {
+		release.Status = rel.Info.Status.String()
+		release.Updated = rel.Info.LastDeployed.Time
+	}
+
+	return release
+}
+
+func getStatusPod(component string, pod *core.Pod) statusPod {
+	item := statusPod{
+		Component: component,
+		Name:      pod.Name,
+		Node:      pod.Spec.NodeName,
+		Phase:     string(pod.Status.Phase),
+		Ready:     kubernetes.IsPodReady(pod),
+		Reason:    pod.Status.Reason,
+	}
+
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		item.Restarts += containerStatus.RestartCount
+		if containerStatus.State.Waiting != nil && containerStatus.State.Waiting.Reason != "" {
+			item.Reason = containerStatus.State.Waiting.Reason
+		}
+	}
+
+	if item.Reason == "" && !item.Ready {
+		for _, condition := range pod.Status.Conditions {
+			if condition.Status != core.ConditionTrue && condition.Reason != "" {
+				item.Reason = condition.Reason
+				break
+			}
+		}
+	}
+
+	return item
+}
+
+func getStatusEvents(ctx context.Context, kubernetesProvider *kubernetes.Provider, namespace string) []statusEvent {
+	events := []statusEvent{}
+
+	warnings, err := kubernetesProvider.ListWarningEvents(ctx, namespace)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed listing the events!")
+		return events
+	}
+
+	since := time.Now().Add(-statusEventsWindow)
+	for _, event := range warnings {
+		eventTime := event.LastTimestamp.Time
+		if eventTime.IsZero() {
+			eventTime = event.EventTime.Time
+		}
+		if eventTime.Before(since) {
+			continue
+		}
+
+		events = append(events, statusEvent{
+			Time:    eventTime,
+			Object:  fmt.Sprintf("%s/%s", strings.ToLower(event.InvolvedObject.Kind), event.InvolvedObject.Name),
+			Reason:  event.Reason,
+			Message: event.Message,
+			Count:   event.Count,
+		})
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Time.After(events[j].Time)
+	})
+
+	if len(events) > statusEventsLimit {
+		events = events[:statusEventsLimit]
+	}
+
+	return events
+}
+
+func getStatusEndpoint(name string, url string) statusEndpoint {
+	endpoint := statusEndpoint{
+		Name: name,
+		Url:  url,
+	}
+
+	client := &http.Client{Timeout: 2 * time.Second}
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		endpoint.Error = err.Error()
+		return endpoint
+	}
+	utils.AddIgnoreCaptureHeader(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		endpoint.Error = err.Error()
+		return endpoint
+	}
+	defer resp.Body.Close()
+
+	endpoint.Reachable = resp.StatusCode < http.StatusInternalServerError
+	if !endpoint.Reachable {
+		endpoint.Error = resp.Status
+	}
+
+	return endpoint
+}
+
+// getStatusStorage measures the disk usage of the Worker data directories.
+func getStatusStorage(ctx context.Context, kubernetesProvider *kubernetes.Provider, pods []core.Pod) []statusStorage {
+	items := make([]statusStorage, 0, len(pods))
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for _, pod := range pods {
+		if !kubernetes.IsPodRunning(&pod) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(pod core.Pod) {
+			defer wg.Done()
+
+			item := statusStorage{
+				Node:  pod.Spec.NodeName,
+				Pod:   pod.Name,
+				Limit: config.Config.Tap.StorageLimit,
+			}
+
+			out, err := kubernetes.ExecInPod(ctx, kubernetesProvider, pod, kubernetes.WorkerContainerName, []string{"du", "-sk", kubernetes.WorkerDataPath})
+			if err == nil {
+				var kilobytes int64
+				fields := strings.Fields(out)
+				if len(fields) == 0 {
+					err = errors.New("unexpected output of du")
+				} else if kilobytes, err = strconv.ParseInt(fields[0], 10, 64); err == nil {
+					item.Used = kilobytes * 1024
+				}
+			}
+			if err != nil {
+				item.Error = err.Error()
+			}
+
+			mutex.Lock()
+			items = append(items, item)
+			mutex.Unlock()
+		}(pod)
+	}
+	wg.Wait()
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Node < items[j].Node
+	})
+
+	return items
+}
+
+func printStatus(report *statusReport) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "RELEASE\tNAMESPACE\tCHART\tAPP VERSION\tREVISION\tSTATUS\tUPDATED")
+	if report.Release.Error != "" {
+		fmt.Fprintf(w, "%s\t%s\t\t\t\t%s\t\n", report.Release.Name, report.Release.Namespace, report.Release.Error)
+	} else {
+		fmt.Fprintf(w, "%s\t%s\t%s-%s\t%s\t%d\t%s\t%s\n",
+			report.Release.Name,
+			report.Release.Namespace,
+			report.Release.Chart,
+			report.Release.ChartVersion,
+			report.Release.AppVersion,
+			report.Release.Revision,
+			report.Release.Status,
+			report.Release.Updated.Format(time.RFC3339),
+		)
+	}
+
+	fmt.Fprintln(w)
+	if report.Workers.Error != "" {
+		fmt.Fprintf(w, "Workers: %s\n", report.Workers.Error)
+	} else {
+		fmt.Fprintf(w, "Workers: %d/%d nodes ready\n", report.Workers.Ready, report.Workers.Desired)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "COMPONENT\tPOD\tNODE\tPHASE\tREADY\tRESTARTS\tREASON")
+	for _, pod := range report.Pods {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%d\t%s\n", pod.Component, pod.Name, pod.Node, pod.Phase, pod.Ready, pod.Restarts, pod.Reason)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "PROXY\tURL\tREACHABLE\tERROR")
+	for _, endpoint := range report.Proxy {
+		fmt.Fprintf(w, "%s\t%s\t%t\t%s\n", endpoint.Name, endpoint.Url, endpoint.Reachable, endpoint.Error)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "NODE\tSTORAGE USED\tLIMIT\tERROR")
+	for _, item := range report.Storage {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", item.Node, formatBytes(item.Used), item.Limit, item.Error)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "CONFIG\tVALUE")
+	keys := make([]string, 0, len(report.Config))
+	for key := range report.Config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s\t%s\n", key, report.Config[key])
+	}
+
+	fmt.Fprintln(w)
+	if len(report.Events) == 0 {
+		fmt.Fprintf(w, "No warning events in the last %s.\n", statusEventsWindow)
+	} else {
+		fmt.Fprintln(w, "LAST SEEN\tOBJECT\tREASON\tCOUNT\tMESSAGE")
+		for _, event := range report.Events {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", event.Time.Format(time.RFC3339), event.Object, event.Reason, event.Count, event.Message)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		log.Error().Err(err).Send()
+	}
+
+	if report.Healthy {
+		log.Info().Msg(fmt.Sprintf(utils.Green, fmt.Sprintf("%s is healthy.", misc.Software)))
+	} else {
+		log.Warn().Msg(fmt.Sprintf(utils.Yellow, fmt.Sprintf("%s is not healthy.", misc.Software)))
+	}
+}
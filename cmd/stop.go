@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/creasty/defaults"
+	"github.com/kubeshark/kubeshark/config/configStructs"
+	"github.com/kubeshark/kubeshark/misc"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var stopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: fmt.Sprintf("Stop the traffic capture of a running %s, the Workers stay deployed", misc.Software),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 0 {
+			return errors.New("unexpected number of arguments, expected none")
+		}
+
+		runStop()
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(stopCmd)
+	addCaptureFlags(stopCmd)
+}
+
+// addCaptureFlags adds the flags that are shared by the commands that start, stop or pause the capture.
+func addCaptureFlags(cmd *cobra.Command) {
+	defaultTapConfig := configStructs.TapConfig{}
+	if err := defaults.Set(&defaultTapConfig); err != nil {
+		log.Debug().Err(err).Send()
+	}
+
+	cmd.Flags().Uint16(configStructs.ProxyFrontPortLabel, defaultTapConfig.Proxy.Front.Port, "Provide a custom port for the Kubeshark")
+	cmd.Flags().String(configStructs.ProxyHostLabel, defaultTapConfig.Proxy.Host, "Provide a custom host for the Kubeshark")
+	cmd.Flags().StringP(configStructs.ReleaseNamespaceLabel, "s", defaultTapConfig.Release.Namespace, "Release namespace of Kubeshark")
+}
+
+func runStop() {
+	kubernetesProvider, err := getKubernetesProviderForCli(true, true)
+	if err != nil {
+		os.Exit(1)
+	}
+
+	resolveConfigSecrets(kubernetesProvider)
+
+	runSetCapture(kubernetesProvider, true, "")
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/creasty/defaults"
 	"github.com/kubeshark/kubeshark/config"
@@ -12,23 +13,52 @@ import (
 )
 
 var tapCmd = &cobra.Command{
-	Use:   "tap [POD REGEX]",
+	Use:   "tap [POD REGEX | KIND/NAME...]",
 	Short: "Capture the network traffic in your Kubernetes cluster",
+	Long: `Capture the network traffic in your Kubernetes cluster.
+
+The pods are targeted by a regex of their names, by workloads like deploy/checkout, sts/db or svc/payments,
+whose pods are resolved through their owner references or the endpoints of the service, and by label selectors.
+
+If --duration, --maxEntries or --maxBytes is set, the capture is time-boxed and ends once any of these is reached.
+Then the traffic is exported into --export-dir, along with the logs, and Kubeshark is uninstalled, unless it was
+installed before. The exit code of a time-boxed capture is 0 if traffic was captured and exported, 1 if the capture
+failed or was interrupted, 2 if no traffic was captured, 3 if the export failed and 4 if the uninstall failed.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(config.Config.Tap.Contexts) > 0 {
+			runTapContexts(config.Config.Tap.Contexts)
+			return nil
+		}
+
 		tap()
 		return nil
 	},
 	PreRunE: func(cmd *cobra.Command, args []string) error {
-		if len(args) == 1 {
-			config.Config.Tap.PodRegexStr = args[0]
-		} else if len(args) > 1 {
-			return errors.New("unexpected number of arguments")
+		regex, workloads, err := parseTargetArgs(args)
+		if err != nil {
+			return err
+		}
+
+		if regex != "" {
+			config.Config.Tap.PodRegexStr = regex
+		}
+
+		if len(workloads) > 0 {
+			config.Config.Tap.Workloads = workloads
 		}
 
 		if err := config.Config.Tap.Validate(); err != nil {
 			return errormessage.FormatError(err)
 		}
 
+		if err := checkOidcProvider(&config.Config.Tap.Auth); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		if err := checkContexts(config.Config.Tap.Contexts); err != nil {
+			return errormessage.FormatError(err)
+		}
+
 		return nil
 	},
 }
@@ -49,16 +79,47 @@ func init() {
 	tapCmd.Flags().String(configStructs.ProxyHostLabel, defaultTapConfig.Proxy.Host, "Provide a custom host for the proxy/port-forward")
 	tapCmd.Flags().StringSliceP(configStructs.NamespacesLabel, "n", defaultTapConfig.Namespaces, "Namespaces selector")
 	tapCmd.Flags().StringSliceP(configStructs.ExcludedNamespacesLabel, "e", defaultTapConfig.ExcludedNamespaces, "Excluded namespaces")
+	tapCmd.Flags().StringP(configStructs.LabelSelectorLabel, "l", defaultTapConfig.LabelSelector, "Target the pods that match the label selector, e.g. app=checkout")
+	tapCmd.Flags().String(configStructs.NamespaceSelectorLabel, defaultTapConfig.NamespaceSelector, "Target the namespaces that match the label selector, e.g. team=core")
 	tapCmd.Flags().StringP(configStructs.ReleaseNamespaceLabel, "s", defaultTapConfig.Release.Namespace, "Release namespace of Kubeshark")
 	tapCmd.Flags().Bool(configStructs.PersistentStorageLabel, defaultTapConfig.PersistentStorage, "Enable persistent storage (PersistentVolumeClaim)")
 	tapCmd.Flags().Bool(configStructs.PersistentStorageStaticLabel, defaultTapConfig.PersistentStorageStatic, "Persistent storage static provision")
-	tapCmd.Flags().String(configStructs.EfsFileSytemIdAndPathLabel, defaultTapConfig.EfsFileSytemIdAndPath, "EFS file system ID")
+	tapCmd.Flags().String(configStructs.EfsFileSystemIdAndPathLabel, defaultTapConfig.EfsFileSystemIdAndPath, "EFS file system ID")
+	tapCmd.Flags().String("efsFileSytemIdAndPath", defaultTapConfig.EfsFileSystemIdAndPath, "EFS file system ID")
+	if err := tapCmd.Flags().MarkDeprecated("efsFileSytemIdAndPath", fmt.Sprintf("use --%s instead", configStructs.EfsFileSystemIdAndPathLabel)); err != nil {
+		log.Debug().Err(err).Send()
+	}
 	tapCmd.Flags().String(configStructs.StorageLimitLabel, defaultTapConfig.StorageLimit, "Override the default storage limit (per node)")
 	tapCmd.Flags().String(configStructs.StorageClassLabel, defaultTapConfig.StorageClass, "Override the default storage class of the PersistentVolumeClaim (per node)")
-	tapCmd.Flags().Bool(configStructs.DryRunLabel, defaultTapConfig.DryRun, "Preview of all pods matching the regex, without tapping them")
+	tapCmd.Flags().Bool(configStructs.DryRunLabel, defaultTapConfig.DryRun, "Preview of all pods matching the targeting rules, without tapping them")
 	tapCmd.Flags().Bool(configStructs.ServiceMeshLabel, defaultTapConfig.ServiceMesh, "Capture the encrypted traffic if the cluster is configured with a service mesh and with mTLS")
 	tapCmd.Flags().Bool(configStructs.TlsLabel, defaultTapConfig.Tls, "Capture the traffic that's encrypted with OpenSSL or Go crypto/tls libraries")
 	tapCmd.Flags().Bool(configStructs.IgnoreTaintedLabel, defaultTapConfig.IgnoreTainted, "Ignore tainted pods while running Worker DaemonSet")
 	tapCmd.Flags().Bool(configStructs.IngressEnabledLabel, defaultTapConfig.Ingress.Enabled, "Enable Ingress")
 	tapCmd.Flags().Bool(configStructs.TelemetryEnabledLabel, defaultTapConfig.Telemetry.Enabled, "Enable/disable Telemetry")
+	tapCmd.Flags().Bool(configStructs.ReuseLabel, defaultTapConfig.Reuse, "Keep an existing installation as it is, even if the config has changed")
+	tapCmd.Flags().Bool(configStructs.UpgradeLabel, defaultTapConfig.Upgrade, "Upgrade an existing installation with the config, without asking for confirmation")
+	tapCmd.Flags().Bool(configStructs.EphemeralLabel, defaultTapConfig.Ephemeral, "Uninstall Kubeshark on exit, if it was installed by this tap")
+	tapCmd.Flags().String(configStructs.DurationLabel, defaultTapConfig.Duration, "Capture for this long, e.g. 10m, then export the traffic, dump the logs and uninstall")
+	tapCmd.Flags().Int(configStructs.MaxEntriesLabel, defaultTapConfig.MaxEntries, "Capture until this many entries are seen, then export the traffic, dump the logs and uninstall")
+	tapCmd.Flags().String(configStructs.MaxBytesLabel, defaultTapConfig.MaxBytes, "Capture until this much traffic is seen, e.g. 100Mi, then export the traffic, dump the logs and uninstall")
+	tapCmd.Flags().String(configStructs.ExportDirLabel, defaultTapConfig.Export.Dir, "Directory of the export and the logs of a time-boxed capture (default current <pwd>/kubeshark_<timestamp>)")
+	tapCmd.Flags().StringSlice(configStructs.ExportFormatsLabel, defaultTapConfig.Export.Formats, fmt.Sprintf("Export formats of a time-boxed capture, %s and/or %s", configStructs.PcapExportFormat, configStructs.HarExportFormat))
+	tapCmd.Flags().StringSlice(configStructs.ContextsLabel, defaultTapConfig.Contexts, "Tap several clusters at once by their kubeconfig contexts, each proxied on the next port after --proxy-front-port")
+}
+
+// parseTargetArgs splits the arguments into the pod regex, there may be a single one, and the workloads.
+func parseTargetArgs(args []string) (regex string, workloads []string, err error) {
+	for _, arg := range args {
+		if configStructs.IsWorkloadRef(arg) {
+			workloads = append(workloads, arg)
+		} else if regex != "" {
+			err = errors.New("unexpected number of arguments, expected a single pod regex")
+			return
+		} else {
+			regex = arg
+		}
+	}
+
+	return
 }
@@ -0,0 +1,342 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kubeshark/kubeshark/config"
+	"github.com/kubeshark/kubeshark/config/configStructs"
+	"github.com/kubeshark/kubeshark/kubernetes"
+	"github.com/kubeshark/kubeshark/kubernetes/helm"
+	"github.com/kubeshark/kubeshark/misc"
+	"github.com/kubeshark/kubeshark/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// The clusters are expected to become reachable in this long, after which the combined status is printed anyway.
+const contextsReadyTimeout = componentReadyTimeout + time.Minute
+
+// The flags that are set per cluster, so they are removed from the arguments that are passed to the cluster runs.
+var contextsOverriddenFlags = []string{
+	configStructs.ContextsLabel,
+	configStructs.ProxyFrontPortLabel,
+}
+
+// The `--set` keys that are set per cluster, likewise.
+var contextsOverriddenSetKeys = []string{
+	fmt.Sprintf("tap.%s", configStructs.ContextsLabel),
+	"kube.context",
+	"headless",
+}
+
+type tapContext struct {
+	name  string
+	port  uint16
+	cmd   *exec.Cmd
+	err   error
+	mutex sync.Mutex
+}
+
+func (c *tapContext) setErr(err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.err = err
+}
+
+func (c *tapContext) getErr() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.err
+}
+
+func (c *tapContext) url() string {
+	return kubernetes.GetProxyOnPort(c.port)
+}
+
+// runTapContexts runs a `tap` per kubeconfig context, each as a child process with its own proxy port.
+// The outputs of the children are prefixed by their contexts, and the Ctrl-C of the terminal reaches
+// all of them as they share the process group. It exits non-zero if tap fails in any of the clusters.
+func runTapContexts(contexts []string) {
+	executable, err := os.Executable()
+	if err != nil {
+		log.Error().Err(err).Send()
+		os.Exit(1)
+	}
+
+	args := removeContextsOverriddenFlags(os.Args[1:])
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	var taps []*tapContext
+	var wg sync.WaitGroup
+	for i, name := range contexts {
+		c := &tapContext{
+			name: name,
+			port: config.Config.Tap.Proxy.Front.Port + uint16(i),
+		}
+		taps = append(taps, c)
+
+		// The contexts are emptied explicitly, so a config file that sets them does not make the children recurse.
+		c.cmd = exec.Command(executable, append(args,
+			fmt.Sprintf("--%s=", configStructs.ContextsLabel),
+			fmt.Sprintf("--%s=%d", configStructs.ProxyFrontPortLabel, c.port),
+			fmt.Sprintf("--%s=kube.context=%s", config.SetCommandName, name),
+			fmt.Sprintf("--%s=headless=true", config.SetCommandName),
+		)...)
+
+		if err := startPrefixed(c.cmd, name); err != nil {
+			c.setErr(err)
+			log.Error().Str("context", name).Err(err).Msg("Failed starting tap in the cluster!")
+			continue
+		}
+
+		log.Info().Str("context", name).Uint16("port", c.port).Msg("Tapping the cluster:")
+
+		wg.Add(1)
+		go func(c *tapContext) {
+			defer wg.Done()
+			c.setErr(c.cmd.Wait())
+		}(c)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	go func() {
+		waitForTapContexts(taps, done)
+		printTapContexts(taps)
+	}()
+
+	for {
+		select {
+		case sig := <-interrupt:
+			// The terminal delivers the interrupt to the children by itself, only a termination is relayed.
+			if sig == syscall.SIGTERM {
+				for _, c := range taps {
+					if c.cmd.Process != nil {
+						_ = c.cmd.Process.Signal(syscall.SIGTERM)
+					}
+				}
+			}
+		case <-done:
+			failed := false
+			for _, c := range taps {
+				if err := c.getErr(); err != nil {
+					log.Error().Str("context", c.name).Err(err).Msg("Tap has failed in the cluster!")
+					failed = true
+				} else {
+					log.Info().Str("context", c.name).Msg("Tap has finished in the cluster.")
+				}
+			}
+
+			if failed {
+				os.Exit(1)
+			}
+			return
+		}
+	}
+}
+
+// waitForTapContexts waits until the fronts of all the clusters are reachable, the children exit or the timeout.
+func waitForTapContexts(taps []*tapContext, done <-chan struct{}) {
+	client := &http.Client{Timeout: 2 * time.Second}
+	timeout := time.After(contextsReadyTimeout)
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			reachable := 0
+			for _, c := range taps {
+				if isUrlReachable(client, c.url()) {
+					reachable++
+				}
+			}
+			if reachable == len(taps) {
+				return
+			}
+		case <-timeout:
+			return
+		case <-done:
+			return
+		}
+	}
+}
+
+func printTapContexts(taps []*tapContext) {
+	client := &http.Client{Timeout: 2 * time.Second}
+
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTEXT\tURL\tSTATUS")
+	for _, c := range taps {
+		status := "reachable"
+		if err := c.getErr(); err != nil {
+			status = fmt.Sprintf("failed: %v", err)
+		} else if !isUrlReachable(client, c.url()) {
+			status = "unreachable"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", c.name, c.url(), status)
+	}
+	if err := w.Flush(); err != nil {
+		log.Error().Err(err).Send()
+	}
+}
+
+func isUrlReachable(client *http.Client, url string) bool {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return false
+	}
+	utils.AddIgnoreCaptureHeader(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode < http.StatusInternalServerError
+}
+
+// startPrefixed starts the command with its outputs prefixed line by line.
+func startPrefixed(cmd *exec.Cmd, prefix string) error {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	prefix = fmt.Sprintf(utils.Cyan, fmt.Sprintf("[%s]", prefix))
+	go copyPrefixed(os.Stdout, stdout, prefix)
+	go copyPrefixed(os.Stderr, stderr, prefix)
+
+	return nil
+}
+
+func copyPrefixed(dst io.Writer, src io.Reader, prefix string) {
+	scanner := bufio.NewScanner(src)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fmt.Fprintf(dst, "%s %s\n", prefix, scanner.Text())
+	}
+}
+
+// removeContextsOverriddenFlags removes the flags that are set per cluster, in both of the `--flag value`
+// and the `--flag=value` forms, including the `--set key=value` overrides of the same settings.
+func removeContextsOverriddenFlags(args []string) []string {
+	var result []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if arg == "--"+config.SetCommandName && i+1 < len(args) && isContextsOverriddenSet(args[i+1]) {
+			i++
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, fmt.Sprintf("--%s=", config.SetCommandName)); ok && isContextsOverriddenSet(value) {
+			continue
+		}
+
+		overridden := false
+		for _, flag := range contextsOverriddenFlags {
+			if arg == "--"+flag {
+				overridden = true
+				i++
+				break
+			}
+			if strings.HasPrefix(arg, fmt.Sprintf("--%s=", flag)) {
+				overridden = true
+				break
+			}
+		}
+
+		if !overridden {
+			result = append(result, arg)
+		}
+	}
+
+	return result
+}
+
+func isContextsOverriddenSet(value string) bool {
+	for _, key := range contextsOverriddenSetKeys {
+		if strings.HasPrefix(value, key+"=") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cleanContexts uninstalls the release from each of the clusters. It carries on after a failure
+// and reports the outcome per cluster.
+func cleanContexts(contexts []string) {
+	failures := make(map[string]error)
+	for _, name := range contexts {
+		config.Config.Kube.Context = name
+
+		resp, err := helm.NewHelm(
+			config.Config.Tap.Release.Repo,
+			config.Config.Tap.Release.Name,
+			config.Config.Tap.Release.Namespace,
+		).Uninstall()
+		if err != nil {
+			failures[name] = err
+			log.Error().Str("context", name).Err(err).Msg("Failed uninstalling the Helm release!")
+			continue
+		}
+
+		log.Info().Str("context", name).Msgf("Uninstalled the Helm release: %s", resp.Release.Name)
+	}
+
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTEXT\tSTATUS")
+	for _, name := range contexts {
+		status := "uninstalled"
+		if err, ok := failures[name]; ok {
+			status = fmt.Sprintf("failed: %v", err)
+		}
+		fmt.Fprintf(w, "%s\t%s\n", name, status)
+	}
+	if err := w.Flush(); err != nil {
+		log.Error().Err(err).Send()
+	}
+
+	if len(failures) > 0 {
+		log.Error().Int("failed", len(failures)).Int("total", len(contexts)).Msg(fmt.Sprintf("%s is not removed from all the clusters.", misc.Software))
+	}
+}
+
+// checkContexts ensures that the contexts exist in the kubeconfig.
+func checkContexts(contexts []string) error {
+	for _, name := range contexts {
+		if _, err := kubernetes.NewProvider(config.Config.KubeConfigPath(), name); err != nil {
+			return fmt.Errorf("context %q, %w", name, err)
+		}
+	}
+
+	return nil
+}
@@ -3,48 +3,41 @@ package cmd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"regexp"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/kubeshark/kubeshark/internal/connect"
+	"github.com/kubeshark/kubeshark/internal/oidc"
 	"github.com/kubeshark/kubeshark/kubernetes/helm"
 	"github.com/kubeshark/kubeshark/misc"
 	"github.com/kubeshark/kubeshark/utils"
 
-	core "k8s.io/api/core/v1"
-
 	"github.com/kubeshark/kubeshark/config"
 	"github.com/kubeshark/kubeshark/config/configStructs"
 	"github.com/kubeshark/kubeshark/errormessage"
 	"github.com/kubeshark/kubeshark/kubernetes"
 	"github.com/rs/zerolog/log"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
 )
 
 const cleanupTimeout = time.Minute
+const componentReadyTimeout = 2 * time.Minute
 
 type tapState struct {
 	startTime        time.Time
 	targetNamespaces []string
+	installed        bool
 }
 
 var state tapState
 var connector *connect.Connector
 
-type Readiness struct {
-	Hub   bool
-	Front bool
-	Proxy bool
-	sync.Mutex
-}
-
-var ready *Readiness
-
 func tap() {
-	ready = &Readiness{}
 	state.startTime = time.Now()
 	log.Info().Str("registry", config.Config.Tap.Docker.Registry).Str("tag", config.Config.Tap.Docker.Tag).Msg("Using Docker:")
 
@@ -60,6 +53,8 @@ func tap() {
 		return
 	}
 
+	resolveConfigSecrets(kubernetesProvider)
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel() // cancel will be called when this function exits
 
@@ -82,38 +77,135 @@ func tap() {
 
 	log.Info().Msg(fmt.Sprintf("Waiting for the creation of %s resources...", misc.Software))
 
-	rel, err := helm.NewHelm(
+	h := helm.NewHelm(
 		config.Config.Tap.Release.Repo,
 		config.Config.Tap.Release.Name,
 		config.Config.Tap.Release.Namespace,
-	).Install()
-	if err != nil {
-		if err.Error() != "cannot re-use a name that is still in use" {
+	)
+
+	defer finishTapExecution(kubernetesProvider)
+
+	existing, err := h.Status()
+	if err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
+		log.Error().Err(err).Send()
+		os.Exit(1)
+	}
+
+	if existing == nil {
+		// An interrupted install would leave the release behind, so the signals are held until the install is over,
+		// and then the release is removed.
+		releaseTermination := func() bool { return false }
+		if config.Config.Tap.Ephemeral {
+			releaseTermination = utils.HoldTermination(fmt.Sprintf("Installing %s, it's removed once the install is over, please wait...", misc.Software))
+		}
+
+		rel, err := h.Install()
+		interrupted := releaseTermination()
+		if err != nil {
 			log.Error().Err(err).Send()
+			// A failed install may leave the release behind too.
+			if failed, _ := h.Status(); failed != nil {
+				state.installed = true
+			}
+			finishTapExecution(kubernetesProvider)
 			os.Exit(1)
 		}
-		log.Info().Msg("Found an existing installation, skipping Helm install...")
-
-		updateConfig(kubernetesProvider)
-		postFrontStarted(ctx, kubernetesProvider, cancel)
-	} else {
 		log.Info().Msgf("Installed the Helm release: %s", rel.Name)
+		state.installed = true
+
+		if interrupted {
+			cancel()
+		}
 
 		go watchHubEvents(ctx, kubernetesProvider, cancel)
-		go watchHubPod(ctx, kubernetesProvider, cancel)
-		go watchFrontPod(ctx, kubernetesProvider, cancel)
-	}
+		go watchComponents(ctx, kubernetesProvider, cancel)
+		go watchWorkers(ctx, kubernetesProvider)
+	} else if shouldUpgrade(existing) {
+		rel, err := h.Upgrade()
+		if err != nil {
+			log.Error().Err(err).Msg("Failed upgrading the Helm release!")
+			if rollbackErr := h.Rollback(existing.Version); rollbackErr != nil {
+				log.Error().Err(rollbackErr).Int("revision", existing.Version).Msg("Failed rolling back the Helm release!")
+			} else {
+				log.Warn().Int("revision", existing.Version).Msg("Rolled back the Helm release to:")
+			}
+			finishTapExecution(kubernetesProvider)
+			os.Exit(1)
+		}
+		log.Info().Int("revision", rel.Version).Msgf("Upgraded the Helm release: %s", rel.Name)
 
-	defer finishTapExecution(kubernetesProvider)
+		go watchHubEvents(ctx, kubernetesProvider, cancel)
+		go watchComponents(ctx, kubernetesProvider, cancel)
+		go watchWorkers(ctx, kubernetesProvider)
+	} else {
+		log.Info().Int("revision", existing.Version).Msg("Found an existing installation, skipping Helm install...")
+
+		updateConfig(kubernetesProvider)
+		resumeExpiredPause(kubernetesProvider)
+		go watchComponents(ctx, kubernetesProvider, cancel)
+	}
 
 	// block until exit signal or error
 	utils.WaitForTermination(ctx, cancel)
 
-	if !config.Config.Tap.Ingress.Enabled {
+	if config.Config.Tap.IsSession() {
+		os.Exit(finishTapSession(kubernetesProvider))
+	}
+
+	if !config.Config.Tap.Ingress.Enabled && !config.Config.Tap.Ephemeral {
 		printProxyCommandSuggestion()
 	}
 }
 
+// shouldUpgrade decides whether the existing release is upgraded or reused. Unless it's decided by the flags,
+// the changed values are printed and the user is asked for confirmation. The headless mode reuses the release.
+func shouldUpgrade(existing *release.Release) bool {
+	if config.Config.Tap.Reuse {
+		return false
+	}
+
+	if config.Config.Tap.Upgrade {
+		return true
+	}
+
+	values, err := helm.ConfigValues()
+	if err != nil {
+		log.Error().Err(err).Send()
+		return false
+	}
+
+	changes := helm.DiffValues(existing.Config, values)
+	if len(changes) == 0 {
+		log.Info().Msg("The existing installation is up-to-date with the config.")
+		return false
+	}
+
+	fmt.Fprintf(os.Stdout, "The config differs from the values of the existing installation (revision %d):\n", existing.Version)
+	printValueChanges(changes)
+
+	if config.Config.HeadlessMode {
+		log.Warn().
+			Str("flag", fmt.Sprintf("--%s", configStructs.UpgradeLabel)).
+			Msg(fmt.Sprintf(utils.Yellow, "Reusing the existing installation. To apply the changes, run tap with:"))
+		return false
+	}
+
+	return utils.AskForConfirmation("Upgrade the existing installation?")
+}
+
+func printValueChanges(changes []*helm.ValueChange) {
+	for _, change := range changes {
+		switch {
+		case change.IsAdded():
+			fmt.Fprintf(os.Stdout, utils.Green+"\n", fmt.Sprintf("  + %s", change))
+		case change.IsRemoved():
+			fmt.Fprintf(os.Stdout, utils.Red+"\n", fmt.Sprintf("  - %s", change))
+		default:
+			fmt.Fprintf(os.Stdout, utils.Yellow+"\n", fmt.Sprintf("  ~ %s", change))
+		}
+	}
+}
+
 func printProxyCommandSuggestion() {
 	log.Warn().
 		Str("command", fmt.Sprintf("%s proxy", misc.Program)).
@@ -122,6 +214,19 @@ func printProxyCommandSuggestion() {
 
 func finishTapExecution(kubernetesProvider *kubernetes.Provider) {
 	finishSelfExecution(kubernetesProvider)
+
+	if !config.Config.Tap.Ephemeral {
+		return
+	}
+
+	if !state.installed {
+		log.Info().Str("namespace", config.Config.Tap.Release.Namespace).Msg("Keeping the existing installation, this tap didn't install it.")
+		return
+	}
+
+	if err := removeRelease(kubernetesProvider); err != nil {
+		log.Error().Err(err).Msg(fmt.Sprintf("Failed removing %s! Remove it by `%s %s`.", misc.Software, misc.Program, cleanCmd.Use))
+	}
 }
 
 /*
@@ -130,14 +235,19 @@ The alternative would be to wait for Hub to be ready and then query it for the p
 the arguably worse drawback of taking a relatively very long time before the user sees which pods are targeted, if any.
 */
 func printTargetedPodsPreview(ctx context.Context, kubernetesProvider *kubernetes.Provider, namespaces []string) error {
-	if matchingPods, err := kubernetesProvider.ListAllRunningPodsMatchingRegex(ctx, config.Config.Tap.PodRegex(), namespaces); err != nil {
+	podTargets, err := kubernetes.NewTargeting(&config.Config.Tap).PodTargets()
+	if err != nil {
+		return err
+	}
+
+	if targetedPods, err := kubernetesProvider.ListTargetedPods(ctx, podTargets, namespaces); err != nil {
 		return err
 	} else {
-		if len(matchingPods) == 0 {
+		if len(targetedPods) == 0 {
 			printNoPodsFoundSuggestion(namespaces)
 		}
-		for _, targetedPod := range matchingPods {
-			log.Info().Msg(fmt.Sprintf("Targeted pod: %s", fmt.Sprintf(utils.Green, targetedPod.Name)))
+		for _, targetedPod := range targetedPods {
+			log.Info().Strs("rules", targetedPod.Rules).Msg(fmt.Sprintf("Targeted pod: %s", fmt.Sprintf(utils.Green, targetedPod.Name)))
 		}
 		return nil
 	}
@@ -148,184 +258,67 @@ func printNoPodsFoundSuggestion(targetNamespaces []string) {
 	if !utils.Contains(targetNamespaces, kubernetes.K8sAllNamespaces) {
 		suggestionStr = ". You can also try selecting a different namespace with -n or target all namespaces with -A"
 	}
-	log.Warn().Msg(fmt.Sprintf("Did not find any currently running pods that match the regex argument, %s will automatically target matching pods if any are created later%s", misc.Software, suggestionStr))
+	log.Warn().Msg(fmt.Sprintf("Did not find any currently running pods that match the targeting rules, %s will automatically target matching pods if any are created later%s", misc.Software, suggestionStr))
 }
 
-func watchHubPod(ctx context.Context, kubernetesProvider *kubernetes.Provider, cancel context.CancelFunc) {
-	podExactRegex := regexp.MustCompile(fmt.Sprintf("^%s", kubernetes.HubPodName))
-	podWatchHelper := kubernetes.NewPodWatchHelper(kubernetesProvider, podExactRegex)
-	eventChan, errorChan := kubernetes.FilteredWatch(ctx, podWatchHelper, []string{config.Config.Tap.Release.Namespace}, podWatchHelper)
-	isPodReady := false
-
-	timeAfter := time.After(120 * time.Second)
-	for {
-		select {
-		case wEvent, ok := <-eventChan:
-			if !ok {
-				eventChan = nil
-				continue
-			}
-
-			switch wEvent.Type {
-			case kubernetes.EventAdded:
-				log.Info().Str("pod", kubernetes.HubPodName).Msg("Added:")
-			case kubernetes.EventDeleted:
-				log.Info().Str("pod", kubernetes.HubPodName).Msg("Removed:")
-				cancel()
-				return
-			case kubernetes.EventModified:
-				modifiedPod, err := wEvent.ToPod()
-				if err != nil {
-					log.Error().Str("pod", kubernetes.HubPodName).Err(err).Msg("While watching pod.")
-					cancel()
-					continue
-				}
-
-				log.Debug().
-					Str("pod", kubernetes.HubPodName).
-					Interface("phase", modifiedPod.Status.Phase).
-					Interface("containers-statuses", modifiedPod.Status.ContainerStatuses).
-					Msg("Watching pod.")
-
-				if modifiedPod.Status.Phase == core.PodRunning && !isPodReady {
-					isPodReady = true
-
-					ready.Lock()
-					ready.Hub = true
-					ready.Unlock()
-					log.Info().Str("pod", kubernetes.HubPodName).Msg("Ready.")
-				}
-
-				ready.Lock()
-				proxyDone := ready.Proxy
-				hubPodReady := ready.Hub
-				frontPodReady := ready.Front
-				ready.Unlock()
-
-				if !proxyDone && hubPodReady && frontPodReady {
-					ready.Lock()
-					ready.Proxy = true
-					ready.Unlock()
-					postFrontStarted(ctx, kubernetesProvider, cancel)
-				}
-			case kubernetes.EventBookmark:
-				break
-			case kubernetes.EventError:
-				break
-			}
-		case err, ok := <-errorChan:
-			if !ok {
-				errorChan = nil
-				continue
-			}
-
-			log.Error().
-				Str("pod", kubernetes.HubPodName).
-				Str("namespace", config.Config.Tap.Release.Namespace).
-				Err(err).
-				Msg("Failed creating pod.")
-			cancel()
-
-		case <-timeAfter:
-			if !isPodReady {
-				log.Error().
-					Str("pod", kubernetes.HubPodName).
-					Msg("Pod was not ready in time.")
-				cancel()
-			}
-		case <-ctx.Done():
-			log.Debug().
-				Str("pod", kubernetes.HubPodName).
-				Msg("Watching pod, context done.")
-			return
-		}
+// tapComponents are the components that are waited for, before the proxy to the front is established.
+func tapComponents() []*kubernetes.ComponentSpec {
+	return []*kubernetes.ComponentSpec{
+		{
+			Name:      "hub",
+			NameRegex: regexp.MustCompile(fmt.Sprintf("^%s", kubernetes.HubPodName)),
+			Namespace: config.Config.Tap.Release.Namespace,
+			Timeout:   componentReadyTimeout,
+		},
+		{
+			Name:      "front",
+			NameRegex: regexp.MustCompile(fmt.Sprintf("^%s", kubernetes.FrontPodName)),
+			Namespace: config.Config.Tap.Release.Namespace,
+			Timeout:   componentReadyTimeout,
+		},
 	}
 }
 
-func watchFrontPod(ctx context.Context, kubernetesProvider *kubernetes.Provider, cancel context.CancelFunc) {
-	podExactRegex := regexp.MustCompile(fmt.Sprintf("^%s", kubernetes.FrontPodName))
-	podWatchHelper := kubernetes.NewPodWatchHelper(kubernetesProvider, podExactRegex)
-	eventChan, errorChan := kubernetes.FilteredWatch(ctx, podWatchHelper, []string{config.Config.Tap.Release.Namespace}, podWatchHelper)
-	isPodReady := false
+func watchComponents(ctx context.Context, kubernetesProvider *kubernetes.Provider, cancel context.CancelFunc) {
+	tracker := kubernetes.NewReadinessTracker(kubernetesProvider, tapComponents()...)
+	tracker.Start(ctx)
 
-	timeAfter := time.After(120 * time.Second)
+	proxyDone := false
 	for {
 		select {
-		case wEvent, ok := <-eventChan:
-			if !ok {
-				eventChan = nil
-				continue
-			}
-
-			switch wEvent.Type {
-			case kubernetes.EventAdded:
-				log.Info().Str("pod", kubernetes.FrontPodName).Msg("Added:")
-			case kubernetes.EventDeleted:
-				log.Info().Str("pod", kubernetes.FrontPodName).Msg("Removed:")
+		case event := <-tracker.Events():
+			switch event.Type {
+			case kubernetes.ComponentAdded:
+				log.Info().Str("pod", event.Pod).Msg("Added:")
+			case kubernetes.ComponentReady:
+				log.Info().Str("pod", event.Pod).Msg("Ready.")
+			case kubernetes.ComponentNotReady:
+				log.Warn().Str("pod", event.Pod).Msg("Not ready.")
+			case kubernetes.ComponentRemoved:
+				log.Info().Str("pod", event.Pod).Msg("Removed:")
+			case kubernetes.ComponentTimedOut:
+				log.Error().
+					Str("component", event.Component).
+					Msg("Pod was not ready in time.")
+				cancel()
+			case kubernetes.ComponentFailed:
+				log.Error().
+					Str("component", event.Component).
+					Str("namespace", config.Config.Tap.Release.Namespace).
+					Err(event.Err).
+					Msg("Failed creating pod.")
 				cancel()
-				return
-			case kubernetes.EventModified:
-				modifiedPod, err := wEvent.ToPod()
-				if err != nil {
-					log.Error().Str("pod", kubernetes.FrontPodName).Err(err).Msg("While watching pod.")
-					cancel()
-					continue
-				}
-
-				log.Debug().
-					Str("pod", kubernetes.FrontPodName).
-					Interface("phase", modifiedPod.Status.Phase).
-					Interface("containers-statuses", modifiedPod.Status.ContainerStatuses).
-					Msg("Watching pod.")
-
-				if modifiedPod.Status.Phase == core.PodRunning && !isPodReady {
-					isPodReady = true
-					ready.Lock()
-					ready.Front = true
-					ready.Unlock()
-					log.Info().Str("pod", kubernetes.FrontPodName).Msg("Ready.")
-				}
-
-				ready.Lock()
-				proxyDone := ready.Proxy
-				hubPodReady := ready.Hub
-				frontPodReady := ready.Front
-				ready.Unlock()
-
-				if !proxyDone && hubPodReady && frontPodReady {
-					ready.Lock()
-					ready.Proxy = true
-					ready.Unlock()
-					postFrontStarted(ctx, kubernetesProvider, cancel)
-				}
-			case kubernetes.EventBookmark:
-				break
-			case kubernetes.EventError:
-				break
 			}
-		case err, ok := <-errorChan:
-			if !ok {
-				errorChan = nil
+		case <-tracker.AllReady():
+			if proxyDone {
+				log.Info().Msg(fmt.Sprintf("%s is ready again.", misc.Software))
 				continue
 			}
 
-			log.Error().
-				Str("pod", kubernetes.FrontPodName).
-				Str("namespace", config.Config.Tap.Release.Namespace).
-				Err(err).
-				Msg("Failed creating pod.")
-
-		case <-timeAfter:
-			if !isPodReady {
-				log.Error().
-					Str("pod", kubernetes.FrontPodName).
-					Msg("Pod was not ready in time.")
-				cancel()
-			}
+			proxyDone = true
+			postFrontStarted(ctx, kubernetesProvider, cancel)
 		case <-ctx.Done():
-			log.Debug().
-				Str("pod", kubernetes.FrontPodName).
-				Msg("Watching pod, context done.")
+			log.Debug().Msg("Watching components, context done.")
 			return
 		}
 	}
@@ -424,8 +417,8 @@ func postFrontStarted(ctx context.Context, kubernetesProvider *kubernetes.Provid
 		utils.OpenBrowser(url)
 	}
 
-	for !ready.Hub {
-		time.Sleep(100 * time.Millisecond)
+	if config.Config.Tap.IsSession() {
+		go runTapSession(ctx, kubernetesProvider, cancel)
 	}
 
 	if config.Config.Scripting.Source != "" && config.Config.Scripting.WatchScripts {
@@ -439,6 +432,18 @@ func updateConfig(kubernetesProvider *kubernetes.Provider) {
 	_, _ = kubernetes.SetConfig(kubernetesProvider, kubernetes.CONFIG_NAMESPACES, strings.Join(config.Config.Tap.Namespaces, ","))
 	_, _ = kubernetes.SetConfig(kubernetesProvider, kubernetes.CONFIG_EXCLUDED_NAMESPACES, strings.Join(config.Config.Tap.ExcludedNamespaces, ","))
 
+	// The existing installation would keep capturing the pods of its former targeting, so the failures are reported.
+	targeting := []struct{ key, value string }{
+		{kubernetes.CONFIG_LABEL_SELECTOR, config.Config.Tap.LabelSelector},
+		{kubernetes.CONFIG_WORKLOADS, strings.Join(config.Config.Tap.Workloads, ",")},
+		{kubernetes.CONFIG_NAMESPACE_SELECTOR, config.Config.Tap.NamespaceSelector},
+	}
+	for _, c := range targeting {
+		if _, err := kubernetes.SetConfig(kubernetesProvider, c.key, c.value); err != nil {
+			log.Error().Str("config", c.key).Err(err).Msg("Failed updating the targeting, the former one may still be in effect!")
+		}
+	}
+
 	data, err := json.Marshal(config.Config.Scripting.Env)
 	if err != nil {
 		log.Error().Str("config", kubernetes.CONFIG_SCRIPTING_ENV).Err(err).Send()
@@ -465,4 +470,47 @@ func updateConfig(kubernetesProvider *kubernetes.Provider) {
 	_, _ = kubernetes.SetConfig(kubernetesProvider, kubernetes.CONFIG_AUTH_ENABLED, authEnabled)
 	_, _ = kubernetes.SetConfig(kubernetesProvider, kubernetes.CONFIG_AUTH_TYPE, config.Config.Tap.Auth.Type)
 	_, _ = kubernetes.SetConfig(kubernetesProvider, kubernetes.CONFIG_AUTH_SAML_IDP_METADATA_URL, config.Config.Tap.Auth.Saml.IdpMetadataUrl)
+
+	data, err = json.Marshal(config.Config.Tap.Auth.Oidc.Roles)
+	if err != nil {
+		log.Error().Str("config", kubernetes.CONFIG_AUTH_OIDC_ROLES).Err(err).Send()
+		return
+	}
+
+	// A stale OIDC config would let the users in with the former provider or roles, so the failures are reported.
+	oidc := []struct{ key, value string }{
+		{kubernetes.CONFIG_AUTH_OIDC_ISSUER, config.Config.Tap.Auth.Oidc.Issuer},
+		{kubernetes.CONFIG_AUTH_OIDC_CLIENT_ID, config.Config.Tap.Auth.Oidc.ClientId},
+		{kubernetes.CONFIG_AUTH_OIDC_SCOPES, strings.Join(config.Config.Tap.Auth.Oidc.Scopes, " ")},
+		{kubernetes.CONFIG_AUTH_OIDC_ROLES_CLAIM, config.Config.Tap.Auth.Oidc.RolesClaim},
+		{kubernetes.CONFIG_AUTH_OIDC_ROLES, string(data)},
+	}
+	for _, c := range oidc {
+		if _, err := kubernetes.SetConfig(kubernetesProvider, c.key, c.value); err != nil {
+			log.Error().Str("config", c.key).Err(err).Msg("Failed updating the OIDC config, the former one may still be in effect!")
+		}
+	}
+}
+
+// checkOidcProvider checks the OIDC config against the discovery document of the provider, if the OIDC authentication is enabled.
+func checkOidcProvider(authConfig *configStructs.AuthConfig) error {
+	if !authConfig.Enabled || authConfig.Type != configStructs.AuthTypeOidc {
+		return nil
+	}
+
+	document, err := oidc.Discover(authConfig.Oidc.Issuer)
+	if err != nil {
+		return fmt.Errorf("failed fetching the OIDC discovery document of %s, %w", authConfig.Oidc.Issuer, err)
+	}
+
+	warnings, err := document.Check(&authConfig.Oidc)
+	for _, warning := range warnings {
+		log.Warn().Str("issuer", authConfig.Oidc.Issuer).Msg(warning)
+	}
+	if err != nil {
+		return fmt.Errorf("the OIDC config doesn't match the provider, %w", err)
+	}
+
+	log.Info().Str("issuer", authConfig.Oidc.Issuer).Msg("OIDC provider is verified.")
+	return nil
 }
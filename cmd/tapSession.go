@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/kubeshark/kubeshark/config"
+	"github.com/kubeshark/kubeshark/config/configStructs"
+	"github.com/kubeshark/kubeshark/kubernetes"
+	"github.com/kubeshark/kubeshark/misc"
+	"github.com/kubeshark/kubeshark/misc/fsUtils"
+	"github.com/kubeshark/kubeshark/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// The exit codes of a time-boxed capture session, see TapConfig.IsSession.
+const (
+	sessionExitCaptured     = 0
+	sessionExitFailed       = 1 // Kubeshark failed, or the session was interrupted before it was over.
+	sessionExitNoTraffic    = 2 // The session is over, but no entries were captured.
+	sessionExitExportFailed = 3 // The traffic was captured, but it couldn't be exported.
+	sessionExitCleanFailed  = 4 // The traffic was captured and exported, but Kubeshark couldn't be uninstalled.
+)
+
+type tapSession struct {
+	startTime time.Time
+	started   atomic.Bool
+	over      atomic.Bool
+	entries   atomic.Int64
+	bytes     atomic.Int64
+	endTime   atomic.Int64 // In Unix milliseconds, the traffic that's captured after it is not exported.
+
+	// The capture state of an existing installation before the session, that's restored once the session is over.
+	priorStopped     bool
+	priorPausedUntil string
+}
+
+var session tapSession
+
+// tapSessionExports are the exports of each format. These establish the proxy, whose messages refer to the tap
+// command, so they're looked up in init to avoid an initialization cycle.
+var tapSessionExports map[string]func() error
+
+func init() {
+	tapSessionExports = map[string]func() error{
+		configStructs.PcapExportFormat: runExport,
+		configStructs.HarExportFormat:  runExportHar,
+	}
+}
+
+// runTapSession starts the capture and counts the captured entries, until the duration elapses or a limit is reached.
+// Then it cancels the tap, which is finished by finishTapSession.
+func runTapSession(ctx context.Context, kubernetesProvider *kubernetes.Provider, cancel context.CancelFunc) {
+	defer cancel()
+
+	if !state.installed {
+		configMap, err := kubernetesProvider.GetConfigMap(ctx, config.Config.Tap.Release.Namespace, kubernetes.SELF_RESOURCES_PREFIX+kubernetes.SUFFIX_CONFIG_MAP)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed getting the capture state!")
+			return
+		}
+		session.priorStopped = configMap.Data[kubernetes.CONFIG_STOPPED] == "true"
+		session.priorPausedUntil = configMap.Data[kubernetes.CONFIG_PAUSED_UNTIL]
+	}
+
+	if err := setCapture(kubernetesProvider, false, ""); err != nil {
+		log.Error().Err(err).Msg("Failed starting the capture!")
+		return
+	}
+
+	session.startTime = time.Now()
+	session.started.Store(true)
+
+	sessionCtx, stopSession := context.WithCancel(ctx)
+	defer stopSession()
+
+	if duration := config.Config.Tap.SessionDuration(); duration > 0 {
+		var stopTimer context.CancelFunc
+		sessionCtx, stopTimer = context.WithTimeout(sessionCtx, duration)
+		defer stopTimer()
+	}
+
+	maxEntries := config.Config.Tap.MaxEntries
+	maxBytes := config.Config.Tap.SessionMaxBytes()
+
+	log.Info().
+		Str("duration", config.Config.Tap.Duration).
+		Int("max-entries", maxEntries).
+		Str("max-bytes", config.Config.Tap.MaxBytes).
+		Msg(fmt.Sprintf(utils.Green, "Capturing..."))
+
+	err := streamEntries(sessionCtx, "", session.startTime, time.Time{}, maxEntries, func(data []byte) error {
+		session.entries.Add(1)
+		if session.bytes.Add(entrySize(data)) >= maxBytes && maxBytes > 0 {
+			stopSession()
+		}
+		return nil
+	})
+	session.endTime.Store(time.Now().UnixMilli())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed streaming the entries!")
+		return
+	}
+
+	entries, bytes := session.entries.Load(), session.bytes.Load()
+	if errors.Is(sessionCtx.Err(), context.DeadlineExceeded) || (maxEntries > 0 && entries >= int64(maxEntries)) || (maxBytes > 0 && bytes >= maxBytes) {
+		session.over.Store(true)
+		log.Info().Int64("entries", entries).Str("size", formatBytes(bytes)).Msg("The capture session is over.")
+	} else {
+		log.Warn().Int64("entries", entries).Str("size", formatBytes(bytes)).Msg("The capture session was interrupted.")
+	}
+}
+
+// entrySize returns the size of the request and the response of an entry, or the size of the entry itself if the
+// Hub doesn't report these.
+func entrySize(data []byte) int64 {
+	var sizes struct {
+		RequestSize  int64 `json:"requestSize"`
+		ResponseSize int64 `json:"responseSize"`
+	}
+	if err := json.Unmarshal(data, &sizes); err != nil || sizes.RequestSize+sizes.ResponseSize == 0 {
+		return int64(len(data))
+	}
+
+	return sizes.RequestSize + sizes.ResponseSize
+}
+
+// finishTapSession exports the captured traffic and dumps the logs into the export directory, then uninstalls
+// Kubeshark if the tap has installed it. It returns the exit code of the session.
+func finishTapSession(kubernetesProvider *kubernetes.Provider) int {
+	exitCode := sessionExitCaptured
+	switch {
+	case !session.over.Load():
+		exitCode = sessionExitFailed
+	case session.entries.Load() == 0:
+		exitCode = sessionExitNoTraffic
+	}
+
+	dir, err := filepath.Abs(tapSessionDir())
+	if err == nil {
+		err = os.MkdirAll(dir, 0755)
+	}
+	if err != nil {
+		// Kubeshark is still uninstalled, even though nothing can be exported.
+		log.Error().Err(err).Msg("Failed creating the export directory!")
+		if exitCode != sessionExitFailed {
+			exitCode = sessionExitExportFailed
+		}
+	} else {
+		if session.started.Load() {
+			if err := exportTapSession(dir); err != nil && exitCode != sessionExitFailed {
+				exitCode = sessionExitExportFailed
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+		defer cancel()
+
+		logsPath := filepath.Join(dir, fmt.Sprintf("%s_logs.zip", misc.Program))
+		if err := fsUtils.DumpLogs(ctx, kubernetesProvider, logsPath, config.Config.Logs.Grep); err != nil {
+			log.Error().Err(err).Msg("Failed to dump logs.")
+		}
+	}
+
+	if state.installed {
+		if err := removeRelease(kubernetesProvider); err != nil {
+			log.Error().Err(err).Msg(fmt.Sprintf("Failed removing %s! Remove it by `%s %s`.", misc.Software, misc.Program, cleanCmd.Use))
+			if exitCode == sessionExitCaptured {
+				exitCode = sessionExitCleanFailed
+			}
+		}
+	} else {
+		if session.started.Load() && session.priorStopped {
+			if err := setCapture(kubernetesProvider, true, session.priorPausedUntil); err != nil {
+				log.Error().Err(err).Msg(fmt.Sprintf("Failed stopping the capture again! Stop it by `%s stop`.", misc.Program))
+			}
+		}
+		log.Info().Str("namespace", config.Config.Tap.Release.Namespace).Msg("Keeping the existing installation, the capture session didn't install it.")
+	}
+
+	log.Info().
+		Str("dir", dir).
+		Int64("entries", session.entries.Load()).
+		Str("size", formatBytes(session.bytes.Load())).
+		Int("exit-code", exitCode).
+		Msg("Finished the capture session:")
+
+	return exitCode
+}
+
+// tapSessionDir returns the export directory of the session, a timestamped one in the working directory by default.
+func tapSessionDir() string {
+	if config.Config.Tap.Export.Dir != "" {
+		return config.Config.Tap.Export.Dir
+	}
+
+	return fmt.Sprintf("%s_%s", misc.Program, state.startTime.Format("2006_01_02__15_04_05"))
+}
+
+// exportTapSession exports the traffic that's captured during the session in each of the formats. The export is
+// bounded by the end of the session, as the capture goes on meanwhile.
+func exportTapSession(dir string) error {
+	endTime := time.Now()
+	if end := session.endTime.Load(); end > 0 {
+		endTime = time.UnixMilli(end)
+	}
+
+	config.Config.Export.Start = session.startTime.Format(time.RFC3339Nano)
+	config.Config.Export.End = endTime.Format(time.RFC3339Nano)
+
+	var errs []error
+	for _, format := range utils.Unique(config.Config.Tap.Export.Formats) {
+		switch format {
+		case configStructs.PcapExportFormat:
+			config.Config.Export.Output = filepath.Join(dir, fmt.Sprintf("%s.tar.gz", misc.Program))
+		case configStructs.HarExportFormat:
+			config.Config.Export.Output = filepath.Join(dir, "har")
+		}
+		errs = append(errs, tapSessionExports[format]())
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,349 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubeshark/kubeshark/config"
+	"github.com/kubeshark/kubeshark/kubernetes"
+	"github.com/kubeshark/kubeshark/utils"
+	"github.com/rs/zerolog/log"
+	core "k8s.io/api/core/v1"
+)
+
+// The rollout is expected to finish in this long, otherwise the nodes that are not ready are reported.
+const workersRolloutTimeout = 3 * time.Minute
+
+// The container states that a Worker pod does not recover from without an intervention.
+var workerFailureReasons = []string{
+	"CrashLoopBackOff",
+	"ImagePullBackOff",
+	"ErrImagePull",
+	"InvalidImageName",
+	"CreateContainerConfigError",
+	"CreateContainerError",
+	"RunContainerError",
+	"Error",
+	"OOMKilled",
+}
+
+// The events that explain why a Worker pod is not scheduled, created or started.
+var workerFailureEventReasons = []string{
+	"FailedScheduling",
+	"FailedCreate",
+	"Failed",
+	"BackOff",
+	"FailedMount",
+	"FailedCreatePodSandBox",
+}
+
+type workerNodeState struct {
+	pod    string
+	ready  bool
+	reason string
+}
+
+// workersRollout tracks the readiness of the Worker pods per node.
+type workersRollout struct {
+	kubernetesProvider *kubernetes.Provider
+	nodes              map[string]*workerNodeState
+	pods               map[string]string
+	lastReady          int
+	lastDesired        int32
+	done               bool
+	sync.Mutex
+}
+
+func watchWorkers(ctx context.Context, kubernetesProvider *kubernetes.Provider) {
+	rollout := &workersRollout{
+		kubernetesProvider: kubernetesProvider,
+		nodes:              make(map[string]*workerNodeState),
+		pods:               make(map[string]string),
+		lastReady:          -1,
+	}
+
+	go watchWorkerEvents(ctx, rollout)
+	watchWorkerPods(ctx, rollout)
+}
+
+func watchWorkerPods(ctx context.Context, rollout *workersRollout) {
+	podRegex := regexp.MustCompile(fmt.Sprintf("^%s-", kubernetes.WorkerPodName))
+	podWatchHelper := kubernetes.NewPodWatchHelper(rollout.kubernetesProvider, podRegex)
+	eventChan, errorChan := kubernetes.FilteredWatch(ctx, podWatchHelper, []string{config.Config.Tap.Release.Namespace}, podWatchHelper)
+
+	timeAfter := time.After(workersRolloutTimeout)
+	for {
+		select {
+		case wEvent, ok := <-eventChan:
+			if !ok {
+				eventChan = nil
+				continue
+			}
+
+			pod, err := wEvent.ToPod()
+			if err != nil {
+				continue
+			}
+
+			switch wEvent.Type {
+			case kubernetes.EventAdded, kubernetes.EventModified:
+				rollout.update(ctx, pod)
+			case kubernetes.EventDeleted:
+				rollout.remove(ctx, pod)
+			}
+		case err, ok := <-errorChan:
+			if !ok {
+				errorChan = nil
+				continue
+			}
+
+			log.Error().
+				Str("pod", kubernetes.WorkerPodName).
+				Str("namespace", config.Config.Tap.Release.Namespace).
+				Err(err).
+				Msg("While watching the Worker pods.")
+
+		case <-timeAfter:
+			rollout.reportPending()
+		case <-ctx.Done():
+			log.Debug().
+				Str("pod", kubernetes.WorkerPodName).
+				Msg("Watching the Worker pods, context done.")
+			return
+		}
+	}
+}
+
+func watchWorkerEvents(ctx context.Context, rollout *workersRollout) {
+	nameRegex := regexp.MustCompile(fmt.Sprintf("^%s", kubernetes.WorkerPodName))
+	eventWatchHelper := kubernetes.NewEventWatchHelper(rollout.kubernetesProvider, nameRegex, "")
+	eventChan, errorChan := kubernetes.FilteredWatch(ctx, eventWatchHelper, []string{config.Config.Tap.Release.Namespace}, eventWatchHelper)
+	for {
+		select {
+		case wEvent, ok := <-eventChan:
+			if !ok {
+				eventChan = nil
+				continue
+			}
+
+			event, err := wEvent.ToEvent()
+			if err != nil {
+				continue
+			}
+
+			if state.startTime.After(event.CreationTimestamp.Time) {
+				continue
+			}
+
+			log.Debug().
+				Str("event", event.Name).
+				Str("name", event.Regarding.Name).
+				Str("kind", event.Regarding.Kind).
+				Str("reason", event.Reason).
+				Str("note", event.Note).
+				Msg("Watching the Worker events.")
+
+			if !utils.Contains(workerFailureEventReasons, event.Reason) {
+				continue
+			}
+
+			node := rollout.nodeOf(event.Regarding.Name)
+			logger := log.Warn().
+				Str("kind", event.Regarding.Kind).
+				Str("name", event.Regarding.Name).
+				Str("reason", event.Reason).
+				Str("note", event.Note)
+			if node != "" {
+				logger = logger.Str("node", node)
+			}
+			if hint := workerFailureHint(event.Reason, event.Note); hint != "" {
+				logger = logger.Str("hint", hint)
+			}
+			logger.Msg(fmt.Sprintf(utils.Yellow, "Worker failure:"))
+		case err, ok := <-errorChan:
+			if !ok {
+				errorChan = nil
+				continue
+			}
+
+			log.Error().
+				Str("pod", kubernetes.WorkerPodName).
+				Err(err).
+				Msg("While watching the Worker events.")
+
+		case <-ctx.Done():
+			log.Debug().
+				Str("pod", kubernetes.WorkerPodName).
+				Msg("Watching the Worker events, context done.")
+			return
+		}
+	}
+}
+
+func (rollout *workersRollout) update(ctx context.Context, pod *core.Pod) {
+	rollout.Lock()
+	defer rollout.Unlock()
+
+	node := pod.Spec.NodeName
+	if node == "" {
+		// Not scheduled yet, the FailedScheduling events tell why.
+		return
+	}
+
+	rollout.pods[pod.Name] = node
+
+	nodeState, ok := rollout.nodes[node]
+	if !ok {
+		nodeState = &workerNodeState{}
+		rollout.nodes[node] = nodeState
+	}
+	nodeState.pod = pod.Name
+	nodeState.ready = kubernetes.IsPodReady(pod)
+
+	reason, message := getWorkerFailure(pod)
+	if reason != nodeState.reason && reason != "" {
+		logger := log.Warn().
+			Str("node", node).
+			Str("pod", pod.Name).
+			Str("reason", reason).
+			Str("message", message)
+		if hint := workerFailureHint(reason, message); hint != "" {
+			logger = logger.Str("hint", hint)
+		}
+		logger.Msg(fmt.Sprintf(utils.Yellow, "Worker is failing on node:"))
+	}
+	nodeState.reason = reason
+
+	rollout.reportProgress(ctx)
+}
+
+func (rollout *workersRollout) remove(ctx context.Context, pod *core.Pod) {
+	rollout.Lock()
+	defer rollout.Unlock()
+
+	node, ok := rollout.pods[pod.Name]
+	if !ok {
+		return
+	}
+	delete(rollout.pods, pod.Name)
+
+	if nodeState, ok := rollout.nodes[node]; ok && nodeState.pod == pod.Name {
+		delete(rollout.nodes, node)
+	}
+
+	rollout.reportProgress(ctx)
+}
+
+func (rollout *workersRollout) nodeOf(podName string) string {
+	rollout.Lock()
+	defer rollout.Unlock()
+
+	return rollout.pods[podName]
+}
+
+// reportProgress logs the number of ready nodes whenever it changes. It is called with the lock held.
+func (rollout *workersRollout) reportProgress(ctx context.Context) {
+	readyCount := 0
+	for _, nodeState := range rollout.nodes {
+		if nodeState.ready {
+			readyCount++
+		}
+	}
+
+	desired := int32(len(rollout.nodes))
+	if daemonSet, err := rollout.kubernetesProvider.GetDaemonSet(ctx, config.Config.Tap.Release.Namespace, kubernetes.WorkerDaemonSetName); err == nil {
+		desired = daemonSet.Status.DesiredNumberScheduled
+	}
+
+	if readyCount == rollout.lastReady && desired == rollout.lastDesired {
+		return
+	}
+	rollout.lastReady = readyCount
+	rollout.lastDesired = desired
+
+	if int32(readyCount) >= desired && desired > 0 {
+		if !rollout.done {
+			log.Info().Msg(fmt.Sprintf(utils.Green, fmt.Sprintf("Workers are ready: %d/%d nodes", readyCount, desired)))
+		}
+		rollout.done = true
+		return
+	}
+
+	log.Info().Msg(fmt.Sprintf("Workers are rolling out: %d/%d nodes ready", readyCount, desired))
+}
+
+// reportPending names the nodes that are still not ready once the rollout times out.
+func (rollout *workersRollout) reportPending() {
+	rollout.Lock()
+	defer rollout.Unlock()
+
+	var pending []string
+	for node, nodeState := range rollout.nodes {
+		if nodeState.ready {
+			continue
+		}
+
+		reason := nodeState.reason
+		if reason == "" {
+			reason = "not ready"
+		}
+		pending = append(pending, fmt.Sprintf("%s (%s)", node, reason))
+	}
+	sort.Strings(pending)
+
+	if len(pending) == 0 && int32(rollout.lastReady) >= rollout.lastDesired {
+		return
+	}
+
+	log.Warn().
+		Int("ready", rollout.lastReady).
+		Int32("desired", rollout.lastDesired).
+		Strs("nodes", pending).
+		Msg(fmt.Sprintf(utils.Yellow, fmt.Sprintf("Workers did not roll out in %s. The traffic of the nodes that are not ready is not captured:", workersRolloutTimeout)))
+}
+
+// getWorkerFailure returns the reason and the message of a failing container, including the init
+// containers that load the kernel module.
+func getWorkerFailure(pod *core.Pod) (reason string, message string) {
+	statuses := append(append([]core.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, containerStatus := range statuses {
+		if waiting := containerStatus.State.Waiting; waiting != nil && utils.Contains(workerFailureReasons, waiting.Reason) {
+			return waiting.Reason, fmt.Sprintf("%s: %s", containerStatus.Name, waiting.Message)
+		}
+
+		if terminated := containerStatus.State.Terminated; terminated != nil && terminated.ExitCode != 0 {
+			return terminated.Reason, fmt.Sprintf("%s: exit code %d %s", containerStatus.Name, terminated.ExitCode, terminated.Message)
+		}
+
+		if terminated := containerStatus.LastTerminationState.Terminated; terminated != nil && terminated.ExitCode != 0 && !containerStatus.Ready {
+			return terminated.Reason, fmt.Sprintf("%s: exit code %d %s", containerStatus.Name, terminated.ExitCode, terminated.Message)
+		}
+	}
+
+	return
+}
+
+// workerFailureHint suggests the likely cause of a Worker failure.
+func workerFailureHint(reason string, message string) string {
+	lowerMessage := strings.ToLower(message)
+
+	switch {
+	case reason == "ImagePullBackOff" || reason == "ErrImagePull" || reason == "InvalidImageName":
+		return "the image cannot be pulled, check `tap.docker.registry`, `tap.docker.tag` and `tap.docker.imagePullSecrets`"
+	case strings.Contains(lowerMessage, "taint"):
+		return "the node is tainted, set `tap.ignoreTainted` to schedule the Workers on the tainted nodes"
+	case strings.Contains(lowerMessage, "capabilit") || strings.Contains(lowerMessage, "security context constraint") || strings.Contains(lowerMessage, "podsecurity") || strings.Contains(lowerMessage, "operation not permitted"):
+		return "missing capabilities, check `tap.capabilities` and the security policies of the cluster"
+	case strings.Contains(lowerMessage, "pf-ring") || strings.Contains(lowerMessage, "pf_ring") || strings.Contains(lowerMessage, "kernel module"):
+		return "the kernel module failed to load, check `tap.kernelModule`"
+	case strings.Contains(lowerMessage, "insufficient"):
+		return "the node does not have enough resources, check `tap.resources`"
+	}
+
+	return ""
+}
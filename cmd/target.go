@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/creasty/defaults"
+	"github.com/kubeshark/kubeshark/config"
+	"github.com/kubeshark/kubeshark/config/configStructs"
+	"github.com/kubeshark/kubeshark/kubernetes"
+	"github.com/kubeshark/kubeshark/kubernetes/helm"
+	"github.com/kubeshark/kubeshark/misc"
+	"github.com/kubeshark/kubeshark/utils"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+var targetCmd = &cobra.Command{
+	Use:   "target",
+	Short: fmt.Sprintf("List or change the targeted pods of a running %s, without reinstalling it", misc.Software),
+}
+
+func init() {
+	rootCmd.AddCommand(targetCmd)
+}
+
+// addTargetFlags adds the flags of the namespaces, that are shared by the subcommands that change the targeting.
+func addTargetFlags(cmd *cobra.Command, usage string) {
+	defaultTapConfig := configStructs.TapConfig{}
+	if err := defaults.Set(&defaultTapConfig); err != nil {
+		log.Debug().Err(err).Send()
+	}
+
+	cmd.Flags().StringSliceP(configStructs.NamespacesLabel, "n", []string{}, fmt.Sprintf("Namespaces to %s", usage))
+	cmd.Flags().StringSliceP(configStructs.ExcludedNamespacesLabel, "e", []string{}, fmt.Sprintf("Excluded namespaces to %s", usage))
+	cmd.Flags().StringP(configStructs.ReleaseNamespaceLabel, "s", defaultTapConfig.Release.Namespace, "Release namespace of Kubeshark")
+}
+
+// runTargetEdit applies the edit to the targeting in the config map, unless the config map is changed meanwhile by
+// someone else. Then the edit is applied again to the new targeting if retryOnConflict is set, otherwise it fails.
+// The edit may refuse the change by returning an error.
+func runTargetEdit(edit func(targeting *kubernetes.Targeting) error, retryOnConflict bool) {
+	kubernetesProvider, err := getKubernetesProviderForCli(true, true)
+	if err != nil {
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	releaseNamespace := config.Config.Tap.Release.Namespace
+
+	for {
+		before, resourceVersion, err := kubernetesProvider.GetTargeting(ctx, releaseNamespace)
+		if err != nil {
+			log.Error().Err(err).Str("namespace", releaseNamespace).Msg(fmt.Sprintf("Failed reading the targeting, is %s running?", misc.Software))
+			os.Exit(1)
+		}
+
+		after := copyTargeting(before)
+		if err := edit(after); err != nil {
+			log.Error().Err(err).Msg("Refused changing the targeting!")
+			os.Exit(1)
+		}
+
+		if err := after.Validate(); err != nil {
+			log.Error().Err(err).Msg("Invalid targeting!")
+			os.Exit(1)
+		}
+
+		if reflect.DeepEqual(before, after) {
+			log.Info().Msg("The targeting is unchanged.")
+			return
+		}
+
+		// The targeting in the config map may be invalid, e.g. if it's edited by hand, and it's fixed by this edit.
+		var beforePods []*kubernetes.TargetedPod
+		if err := before.Validate(); err != nil {
+			log.Warn().Err(err).Msg("The current targeting is invalid, its targeted pods are not listed.")
+		} else if beforePods, err = listTargetedPods(ctx, kubernetesProvider, before); err != nil {
+			log.Error().Err(err).Msg("Failed listing the targeted pods!")
+			os.Exit(1)
+		}
+
+		err = kubernetesProvider.UpdateTargeting(ctx, releaseNamespace, after, resourceVersion)
+		if k8serrors.IsConflict(err) && retryOnConflict {
+			log.Warn().Msg("The targeting was changed meanwhile by someone else, applying the change again...")
+			continue
+		} else if k8serrors.IsConflict(err) {
+			log.Error().Err(err).Msg("The targeting was changed meanwhile by someone else, nothing is changed. Check it by `target list` and run the command again!")
+			os.Exit(1)
+		} else if err != nil {
+			log.Error().Err(err).Msg("Failed updating the targeting!")
+			os.Exit(1)
+		}
+
+		log.Info().Str("namespace", releaseNamespace).Msg("Updated the targeting:")
+		printValueChanges(helm.DiffValues(targetingValues(before), targetingValues(after)))
+
+		afterPods, err := listTargetedPods(ctx, kubernetesProvider, after)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed listing the targeted pods!")
+			os.Exit(1)
+		}
+
+		printTargetedPodChanges(beforePods, afterPods)
+		return
+	}
+}
+
+func copyTargeting(targeting *kubernetes.Targeting) *kubernetes.Targeting {
+	targetingCopy := *targeting
+	targetingCopy.Namespaces = append([]string(nil), targeting.Namespaces...)
+	targetingCopy.ExcludedNamespaces = append([]string(nil), targeting.ExcludedNamespaces...)
+	targetingCopy.Workloads = append([]string(nil), targeting.Workloads...)
+	return &targetingCopy
+}
+
+func targetingValues(targeting *kubernetes.Targeting) (values map[string]interface{}) {
+	data, err := json.Marshal(targeting)
+	if err != nil {
+		log.Error().Err(err).Send()
+		return
+	}
+
+	if err := json.Unmarshal(data, &values); err != nil {
+		log.Error().Err(err).Send()
+	}
+
+	return
+}
+
+func listTargetedPods(ctx context.Context, kubernetesProvider *kubernetes.Provider, targeting *kubernetes.Targeting) ([]*kubernetes.TargetedPod, error) {
+	namespaces, err := kubernetesProvider.ListTargetedNamespaces(ctx, targeting.Namespaces, targeting.ExcludedNamespaces, targeting.NamespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	podTargets, err := targeting.PodTargets()
+	if err != nil {
+		return nil, err
+	}
+
+	return kubernetesProvider.ListTargetedPods(ctx, podTargets, namespaces)
+}
+
+func targetedPodName(pod *kubernetes.TargetedPod) string {
+	return fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)
+}
+
+// printTargetedPodChanges prints the pods that are targeted after the change, and the ones that are not anymore.
+func printTargetedPodChanges(beforePods []*kubernetes.TargetedPod, afterPods []*kubernetes.TargetedPod) {
+	var beforeNames []string
+	for _, pod := range beforePods {
+		beforeNames = append(beforeNames, targetedPodName(pod))
+	}
+
+	var afterNames []string
+	for _, pod := range afterPods {
+		afterNames = append(afterNames, targetedPodName(pod))
+	}
+
+	fmt.Fprintf(os.Stdout, "Targeted pods: %d -> %d\n", len(beforePods), len(afterPods))
+	for _, pod := range afterPods {
+		line := fmt.Sprintf("%s (%s)", targetedPodName(pod), strings.Join(pod.Rules, ", "))
+		if utils.Contains(beforeNames, targetedPodName(pod)) {
+			fmt.Fprintf(os.Stdout, "    %s\n", line)
+		} else {
+			fmt.Fprintf(os.Stdout, utils.Green+"\n", fmt.Sprintf("  + %s", line))
+		}
+	}
+
+	for _, pod := range beforePods {
+		if !utils.Contains(afterNames, targetedPodName(pod)) {
+			fmt.Fprintf(os.Stdout, utils.Red+"\n", fmt.Sprintf("  - %s", targetedPodName(pod)))
+		}
+	}
+}
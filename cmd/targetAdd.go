@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/kubeshark/kubeshark/config"
+	"github.com/kubeshark/kubeshark/config/configStructs"
+	"github.com/kubeshark/kubeshark/kubernetes"
+	"github.com/kubeshark/kubeshark/misc"
+	"github.com/kubeshark/kubeshark/utils"
+	"github.com/spf13/cobra"
+)
+
+var targetAddCmd = &cobra.Command{
+	Use:   "add [KIND/NAME...]",
+	Short: "Add workloads, e.g. deploy/checkout or svc/payments, and namespaces to the targeting",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateWorkloadArgs(args); err != nil {
+			return err
+		}
+
+		runTargetEdit(func(targeting *kubernetes.Targeting) error {
+			// No namespaces means all of these, so adding one would narrow the capture instead.
+			if len(targeting.Namespaces) == 0 && len(config.Config.Target.Namespaces) > 0 {
+				return fmt.Errorf("all the namespaces are targeted, adding some would narrow the capture to these, use `%s target set` to target only these namespaces", misc.Program)
+			}
+
+			targeting.Workloads = utils.Unique(append(targeting.Workloads, args...))
+			targeting.Namespaces = utils.Unique(append(targeting.Namespaces, config.Config.Target.Namespaces...))
+			targeting.ExcludedNamespaces = utils.Unique(append(targeting.ExcludedNamespaces, config.Config.Target.ExcludedNamespaces...))
+			return nil
+		}, true)
+		return nil
+	},
+}
+
+func init() {
+	targetCmd.AddCommand(targetAddCmd)
+
+	addTargetFlags(targetAddCmd, "add")
+}
+
+func validateWorkloadArgs(args []string) error {
+	for _, arg := range args {
+		if _, err := configStructs.ParseWorkload(arg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/creasty/defaults"
+	"github.com/kubeshark/kubeshark/config"
+	"github.com/kubeshark/kubeshark/config/configStructs"
+	"github.com/kubeshark/kubeshark/misc"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var targetListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Print the targeting and the pods that are targeted",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 {
+			return errors.New("unexpected number of arguments")
+		}
+
+		runTargetList()
+		return nil
+	},
+}
+
+func init() {
+	targetCmd.AddCommand(targetListCmd)
+
+	defaultTapConfig := configStructs.TapConfig{}
+	if err := defaults.Set(&defaultTapConfig); err != nil {
+		log.Debug().Err(err).Send()
+	}
+
+	targetListCmd.Flags().StringP(configStructs.ReleaseNamespaceLabel, "s", defaultTapConfig.Release.Namespace, "Release namespace of Kubeshark")
+}
+
+func runTargetList() {
+	kubernetesProvider, err := getKubernetesProviderForCli(true, true)
+	if err != nil {
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	targeting, _, err := kubernetesProvider.GetTargeting(ctx, config.Config.Tap.Release.Namespace)
+	if err != nil {
+		log.Error().Err(err).Str("namespace", config.Config.Tap.Release.Namespace).Msg(fmt.Sprintf("Failed reading the targeting, is %s running?", misc.Software))
+		os.Exit(1)
+	}
+
+	if err := targeting.Validate(); err != nil {
+		log.Error().Err(err).Msg("Invalid targeting!")
+		os.Exit(1)
+	}
+
+	targetedPods, err := listTargetedPods(ctx, kubernetesProvider, targeting)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed listing the targeted pods!")
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Regex:\t%s\n", targeting.Regex)
+	fmt.Fprintf(w, "Namespaces:\t%s\n", formatTargetList(targeting.Namespaces, "all"))
+	fmt.Fprintf(w, "Excluded namespaces:\t%s\n", formatTargetList(targeting.ExcludedNamespaces, "none"))
+	fmt.Fprintf(w, "Namespace selector:\t%s\n", formatTargetValue(targeting.NamespaceSelector, "none"))
+	fmt.Fprintf(w, "Label selector:\t%s\n", formatTargetValue(targeting.LabelSelector, "none"))
+	fmt.Fprintf(w, "Workloads:\t%s\n", formatTargetList(targeting.Workloads, "all"))
+	if err := w.Flush(); err != nil {
+		log.Error().Err(err).Send()
+	}
+
+	fmt.Fprintf(os.Stdout, "\nTargeted pods: %d\n", len(targetedPods))
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, pod := range targetedPods {
+		fmt.Fprintf(w, "  %s\t%s\n", targetedPodName(pod), strings.Join(pod.Rules, ", "))
+	}
+	if err := w.Flush(); err != nil {
+		log.Error().Err(err).Send()
+	}
+}
+
+func formatTargetList(values []string, empty string) string {
+	return formatTargetValue(strings.Join(values, ", "), empty)
+}
+
+func formatTargetValue(value string, empty string) string {
+	if value == "" {
+		return fmt.Sprintf("(%s)", empty)
+	}
+
+	return value
+}
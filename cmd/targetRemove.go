@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kubeshark/kubeshark/config"
+	"github.com/kubeshark/kubeshark/kubernetes"
+	"github.com/kubeshark/kubeshark/misc"
+	"github.com/kubeshark/kubeshark/utils"
+	"github.com/spf13/cobra"
+)
+
+var targetRemoveCmd = &cobra.Command{
+	Use:   "remove [KIND/NAME...]",
+	Short: "Remove workloads and namespaces from the targeting. The last workload and namespace are kept, as removing them would target all of these",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateWorkloadArgs(args); err != nil {
+			return err
+		}
+
+		runTargetEdit(func(targeting *kubernetes.Targeting) error {
+			namespaces := utils.Diff(targeting.Namespaces, config.Config.Target.Namespaces)
+			// No namespaces means all of these, so removing the last one would widen the capture instead.
+			if len(targeting.Namespaces) > 0 && len(namespaces) == 0 {
+				return fmt.Errorf("removing the last of the namespaces %s would target all of these, use `%s target set` to target all the namespaces", strings.Join(targeting.Namespaces, ","), misc.Program)
+			}
+
+			workloads := utils.Diff(targeting.Workloads, args)
+			// The same goes for the workloads, no workloads means all the pods.
+			if len(targeting.Workloads) > 0 && len(workloads) == 0 {
+				return fmt.Errorf("removing the last of the workloads %s would target all the pods, use `%s target set` to target all of these", strings.Join(targeting.Workloads, ","), misc.Program)
+			}
+
+			targeting.Workloads = workloads
+			targeting.Namespaces = namespaces
+			targeting.ExcludedNamespaces = utils.Diff(targeting.ExcludedNamespaces, config.Config.Target.ExcludedNamespaces)
+			return nil
+		}, true)
+		return nil
+	},
+}
+
+func init() {
+	targetCmd.AddCommand(targetRemoveCmd)
+
+	addTargetFlags(targetRemoveCmd, "remove")
+}
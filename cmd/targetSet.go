@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"github.com/kubeshark/kubeshark/config"
+	"github.com/kubeshark/kubeshark/config/configStructs"
+	"github.com/kubeshark/kubeshark/kubernetes"
+	"github.com/kubeshark/kubeshark/utils"
+	"github.com/spf13/cobra"
+)
+
+var targetSetCmd = &cobra.Command{
+	Use:   "set [POD REGEX | KIND/NAME...]",
+	Short: "Replace the targeting, the rules that are not given target everything",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		regex, workloads, err := parseTargetArgs(args)
+		if err != nil {
+			return err
+		}
+
+		if regex == "" {
+			regex = ".*"
+		}
+
+		// Replacing doesn't depend on the current targeting, so a concurrent change is not overridden silently.
+		runTargetEdit(func(targeting *kubernetes.Targeting) error {
+			*targeting = kubernetes.Targeting{
+				Regex:              regex,
+				Namespaces:         utils.Unique(config.Config.Target.Namespaces),
+				ExcludedNamespaces: utils.Unique(config.Config.Target.ExcludedNamespaces),
+				LabelSelector:      config.Config.Target.LabelSelector,
+				Workloads:          utils.Unique(workloads),
+				NamespaceSelector:  config.Config.Target.NamespaceSelector,
+			}
+			return nil
+		}, false)
+		return nil
+	},
+}
+
+func init() {
+	targetCmd.AddCommand(targetSetCmd)
+
+	addTargetFlags(targetSetCmd, "set")
+	targetSetCmd.Flags().StringP(configStructs.LabelSelectorLabel, "l", "", "Target the pods that match the label selector, e.g. app=checkout")
+	targetSetCmd.Flags().String(configStructs.NamespaceSelectorLabel, "", "Target the namespaces that match the label selector, e.g. team=core")
+}
@@ -1,19 +1,16 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"os"
-	"path"
 	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
 
 	"github.com/creasty/defaults"
-	"github.com/goccy/go-yaml"
-	"github.com/kubeshark/kubeshark/misc"
 	"github.com/kubeshark/kubeshark/misc/version"
 	"github.com/kubeshark/kubeshark/utils"
 	"github.com/rs/zerolog"
@@ -23,18 +20,35 @@ import (
 )
 
 const (
-	Separator      = "="
-	SetCommandName = "set"
-	FieldNameTag   = "yaml"
-	ReadonlyTag    = "readonly"
-	DebugFlag      = "debug"
+	Separator          = "="
+	SetCommandName     = "set"
+	SetJsonCommandName = "set-json"
+	SetFileCommandName = "set-file"
+	FieldNameTag       = "yaml"
+	ReadonlyTag        = "readonly"
+	SecretTag          = "secret"
+	DebugFlag          = "debug"
 )
 
+// setCommandNames are the --set flags in the order that they're applied.
+var setCommandNames = []string{SetJsonCommandName, SetCommandName, SetFileCommandName}
+
+// commandSections maps the commands to their config sections, when these are named differently.
+var commandSections = map[string]string{
+	"clean":   "tap",
+	"console": "tap",
+	"pro":     "tap",
+	"proxy":   "tap",
+	"scripts": "scripting",
+}
+
 var (
 	Config         ConfigStruct
 	DebugMode      bool
 	cmdName        string
 	ConfigFilePath string
+	// LoadedConfigFiles are the config files that the config is loaded from, in the order of their layers.
+	LoadedConfigFiles []string
 )
 
 func InitConfig(cmd *cobra.Command) error {
@@ -54,6 +68,7 @@ func InitConfig(cmd *cobra.Command) error {
 
 	if !utils.Contains([]string{
 		"console",
+		"query",
 		"pro",
 		"manifests",
 		"license",
@@ -63,35 +78,54 @@ func InitConfig(cmd *cobra.Command) error {
 
 	Config = CreateDefaultConfig()
 	Config.Tap.Debug = DebugMode
-	cmdName = cmd.Name()
-	if utils.Contains([]string{
-		"clean",
-		"console",
-		"pro",
-		"proxy",
-		"scripts",
-	}, cmdName) {
-		cmdName = "tap"
-	}
+	cmdName = getCommandSection(cmd)
 
 	if err := defaults.Set(&Config); err != nil {
 		return err
 	}
 
-	ConfigFilePath = path.Join(misc.GetDotFolderPath(), "config.yaml")
-	if err := loadConfigFile(&Config, utils.Contains([]string{
+	configSources = nil
+
+	configFiles, err := cmd.Flags().GetStringSlice(ConfigFilesFlag)
+	if err != nil {
+		return err
+	}
+
+	profile, err := cmd.Flags().GetString(ProfileFlag)
+	if err != nil {
+		return err
+	}
+
+	profileFound, err := loadConfigFiles(&Config, configFiles, profile, utils.Contains([]string{
 		"manifests",
 		"license",
-	}, cmd.Use)); err != nil {
-		if !os.IsNotExist(err) {
-			return fmt.Errorf("invalid config, %w\n"+
-				"you can regenerate the file by removing it (%v) and using `kubeshark config -r`", err, ConfigFilePath)
-		}
+	}, cmd.Use))
+	if err != nil {
+		return fmt.Errorf("invalid config, %w\n"+
+			"you can regenerate the file by removing it (%v) and using `kubeshark config -r`", err, ConfigFilePath)
+	}
+
+	if profile != "" && !profileFound {
+		return fmt.Errorf("the profile %q is not defined in any of the config files", profile)
 	}
 
-	cmd.Flags().Visit(initFlag)
+	if err := loadEnv(&Config); err != nil {
+		return fmt.Errorf("invalid environment variable, %w", err)
+	}
+
+	// The flags override the --set values, so these are applied last.
+	for _, setCommandName := range setCommandNames {
+		if f := cmd.Flags().Lookup(setCommandName); f != nil && f.Changed {
+			initFlag(f)
+		}
+	}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if !utils.Contains(setCommandNames, f.Name) {
+			initFlag(f)
+		}
+	})
 
-	log.Debug().Interface("config", Config).Msg("Init config is finished.")
+	log.Debug().Interface("config", RedactSecrets(Config)).Msg("Init config is finished.")
 
 	return nil
 }
@@ -108,90 +142,134 @@ func GetConfigWithDefaults() (*ConfigStruct, error) {
 	return &defaultConf, nil
 }
 
+// OmitReadonly returns a copy of the config without the settings that are only for the commands, e.g. to print it as
+// the values of the chart.
+func OmitReadonly(config ConfigStruct) ConfigStruct {
+	setZeroForReadonlyFields(reflect.ValueOf(&config).Elem())
+	return config
+}
+
 func WriteConfig(config *ConfigStruct) error {
 	template, err := utils.PrettyYaml(config)
 	if err != nil {
 		return fmt.Errorf("failed converting config to yaml, err: %v", err)
 	}
 
-	data := []byte(template)
+	return writeConfigFile(ConfigFilePath, []byte(template))
+}
 
-	if _, err := os.Stat(ConfigFilePath); os.IsNotExist(err) {
-		err = os.MkdirAll(filepath.Dir(ConfigFilePath), 0700)
+func writeConfigFile(filePath string, data []byte) error {
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		err = os.MkdirAll(filepath.Dir(filePath), 0700)
 		if err != nil {
 			return fmt.Errorf("failed creating directories, err: %v", err)
 		}
 	}
 
-	if err := os.WriteFile(ConfigFilePath, data, 0644); err != nil {
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
 		return fmt.Errorf("failed writing config, err: %v", err)
 	}
 
 	return nil
 }
 
-func loadConfigFile(config *ConfigStruct, silent bool) error {
-	cwd, err := os.Getwd()
-	if err != nil {
-		return err
-	}
-
-	cwdConfig := filepath.Join(cwd, fmt.Sprintf("%s.yaml", misc.Program))
-	reader, err := os.Open(cwdConfig)
-	if err != nil {
-		reader, err = os.Open(ConfigFilePath)
-		if err != nil {
-			return err
-		}
-	} else {
-		ConfigFilePath = cwdConfig
-	}
-	defer reader.Close()
-
-	buf, err := io.ReadAll(reader)
-	if err != nil {
-		return err
-	}
-
-	if err := yaml.Unmarshal(buf, config); err != nil {
-		return err
-	}
-
-	if !silent {
-		log.Info().Str("path", ConfigFilePath).Msg("Found config file!")
+func initFlag(f *pflag.Flag) {
+	if utils.Contains([]string{ConfigFilesFlag, ProfileFlag}, f.Name) {
+		return
 	}
 
-	return nil
-}
-
-func initFlag(f *pflag.Flag) {
 	configElemValue := reflect.ValueOf(&Config).Elem()
 
 	var flagPath []string
-	flagPath = append(flagPath, cmdName)
+	flagPath = append(flagPath, getFlagSection(configElemValue, f.Name)...)
 
 	flagPath = append(flagPath, strings.Split(f.Name, "-")...)
 
+	// The deprecation of a renamed flag is reported by cobra
+	if renamedPath, ok := renamedConfigPath(strings.Join(flagPath, ".")); ok {
+		flagPath = strings.Split(renamedPath, ".")
+	}
+
 	sliceValue, isSliceValue := f.Value.(pflag.SliceValue)
 	if !isSliceValue {
 		if err := mergeFlagValue(configElemValue, flagPath, strings.Join(flagPath, "."), f.Value.String()); err != nil {
 			log.Warn().Err(err).Send()
+		} else {
+			recordSource(strings.Join(flagPath, "."), fmt.Sprintf("--%s", f.Name))
 		}
 		return
 	}
 
-	if f.Name == SetCommandName {
+	switch f.Name {
+	case SetCommandName:
 		if err := mergeSetFlag(configElemValue, sliceValue.GetSlice()); err != nil {
 			log.Warn().Err(err).Send()
 		}
 		return
+	case SetJsonCommandName:
+		if err := mergeSetJsonFlag(configElemValue, sliceValue.GetSlice()); err != nil {
+			log.Warn().Err(err).Send()
+		}
+		return
+	case SetFileCommandName:
+		if err := mergeSetFileFlag(configElemValue, sliceValue.GetSlice()); err != nil {
+			log.Warn().Err(err).Send()
+		}
+		return
 	}
 
 	if err := mergeFlagValues(configElemValue, flagPath, strings.Join(flagPath, "."), sliceValue.GetSlice()); err != nil {
 		log.Warn().Err(err).Send()
+	} else {
+		recordSource(strings.Join(flagPath, "."), fmt.Sprintf("--%s", f.Name))
 	}
 }
 
+// getCommandSection returns the config section of a command. The section of a subcommand
+// is nested in the section of its parent command, e.g. `scripting.sync` for `scripts sync`.
+func getCommandSection(cmd *cobra.Command) string {
+	section := cmd.Name()
+	if commandSection, ok := commandSections[section]; ok {
+		section = commandSection
+	}
+
+	if cmd.HasParent() && cmd.Parent().HasParent() {
+		return fmt.Sprintf("%s.%s", getCommandSection(cmd.Parent()), section)
+	}
+
+	return section
+}
+
+// getFlagSection returns the config section of a command's flag. A flag that is not defined in the
+// command's own section is looked up in the sections of the parent commands, and then in the `tap`
+// section (e.g. the proxy and the release flags).
+func getFlagSection(configElemValue reflect.Value, flagName string) []string {
+	noopMergeFunction := func(flagName string, currentFieldType reflect.Type, _ reflect.Value) error {
+		if currentFieldType.Kind() == reflect.Struct {
+			return fmt.Errorf("flag \"%s\" not found", flagName)
+		}
+		return nil
+	}
+
+	// The flag is looked up in a zero config, so that the lookup doesn't add map keys or slice elements to the config.
+	configElemValue = reflect.New(configElemValue.Type()).Elem()
+
+	section := strings.Split(cmdName, ".")
+	for len(section) > 0 {
+		flagPath := append(append([]string{}, section...), strings.Split(flagName, "-")...)
+		if err := mergeFlag(configElemValue, flagPath, flagName, noopMergeFunction); err == nil {
+			return section
+		}
+
+		section = section[:len(section)-1]
+	}
+
+	return []string{"tap"}
+}
+
+// mergeSetFlag merges the --set values. The key is a path of config fields, map keys and slice indexes,
+// e.g. tap.labels.team=x or tap.nodeSelectorTerms[0].matchExpressions[0].key=x. A dot that is part of a
+// map key is escaped, e.g. tap.annotations.example\.com/team=x
 func mergeSetFlag(configElemValue reflect.Value, setValues []string) error {
 	var setErrors []string
 	setMap := map[string][]string{}
@@ -209,17 +287,77 @@ func mergeSetFlag(configElemValue reflect.Value, setValues []string) error {
 	}
 
 	for argumentKey, argumentValues := range setMap {
-		flagPath := strings.Split(argumentKey, ".")
+		argumentKey = migrateConfigPath(argumentKey, fmt.Sprintf("--%s", SetCommandName))
+		flagPath, err := parseFlagPath(argumentKey)
+		if err != nil {
+			setErrors = append(setErrors, fmt.Sprintf("%v", err))
+			continue
+		}
 
 		if len(argumentValues) > 1 {
 			if err := mergeFlagValues(configElemValue, flagPath, argumentKey, argumentValues); err != nil {
 				setErrors = append(setErrors, fmt.Sprintf("%v", err))
+				continue
 			}
 		} else {
 			if err := mergeFlagValue(configElemValue, flagPath, argumentKey, argumentValues[0]); err != nil {
 				setErrors = append(setErrors, fmt.Sprintf("%v", err))
+				continue
 			}
 		}
+
+		recordSource(argumentKey, fmt.Sprintf("--%s", SetCommandName))
+	}
+
+	if len(setErrors) > 0 {
+		return errors.New(strings.Join(setErrors, "\n"))
+	}
+
+	return nil
+}
+
+// mergeSetJsonFlag merges the --set-json values, e.g. tap.nodeSelectorTerms=[{"matchExpressions":[...]}].
+// The JSON is decoded onto the current value, so the struct fields and the map keys that it doesn't have are kept.
+func mergeSetJsonFlag(configElemValue reflect.Value, setValues []string) error {
+	return mergeSetValues(configElemValue, SetJsonCommandName, setValues, mergeFlagJsonValue)
+}
+
+// mergeSetFileFlag merges the --set-file values, i.e. the value is set to the content of the file, e.g. tap.auth.saml.x509crt=./cert.pem
+func mergeSetFileFlag(configElemValue reflect.Value, setValues []string) error {
+	return mergeSetValues(configElemValue, SetFileCommandName, setValues, func(configElemValue reflect.Value, flagPath []string, fullFlagName string, filePath string) error {
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return fmt.Errorf("invalid file for flag name %s, %w", fullFlagName, err)
+		}
+
+		return mergeFlagValue(configElemValue, flagPath, fullFlagName, string(data))
+	})
+}
+
+// mergeSetValues merges the <flag name>=<flag value> arguments of a --set-* flag in the given order.
+func mergeSetValues(configElemValue reflect.Value, setCommandName string, setValues []string, merge func(configElemValue reflect.Value, flagPath []string, fullFlagName string, flagValue string) error) error {
+	var setErrors []string
+
+	for _, setValue := range setValues {
+		argumentKey, argumentValue, ok := strings.Cut(setValue, Separator)
+		if !ok {
+			setErrors = append(setErrors, fmt.Sprintf("Ignoring %s argument %s (%s argument format: <flag name>=<flag value>)", setCommandName, setValue, setCommandName))
+			continue
+		}
+
+		argumentKey = migrateConfigPath(argumentKey, fmt.Sprintf("--%s", setCommandName))
+		flagPath, err := parseFlagPath(argumentKey)
+		if err != nil {
+			setErrors = append(setErrors, fmt.Sprintf("%v", err))
+			continue
+		}
+
+		if err := merge(configElemValue, flagPath, argumentKey, argumentValue); err != nil {
+			setErrors = append(setErrors, fmt.Sprintf("%v", err))
+			continue
+		}
+
+		recordSource(argumentKey, fmt.Sprintf("--%s", setCommandName))
 	}
 
 	if len(setErrors) > 0 {
@@ -229,15 +367,85 @@ func mergeSetFlag(configElemValue reflect.Value, setValues []string) error {
 	return nil
 }
 
+// parseFlagPath splits a flag name into the path of its fields, map keys and slice indexes, e.g. a.b[0].c
+// into a, b, [0] and c. A backslash escapes the next character, e.g. a dot that is part of a map key.
+func parseFlagPath(flagName string) ([]string, error) {
+	invalidFlagNameErr := fmt.Errorf("invalid flag name \"%s\"", flagName)
+
+	var flagPath []string
+	var name strings.Builder
+	afterIndex := false
+
+	for i := 0; i < len(flagName); i++ {
+		switch flagName[i] {
+		case '\\':
+			if i+1 < len(flagName) {
+				i++
+			}
+			name.WriteByte(flagName[i])
+		case '.':
+			if name.Len() == 0 && !afterIndex {
+				return nil, invalidFlagNameErr
+			}
+
+			if name.Len() > 0 {
+				flagPath = append(flagPath, name.String())
+				name.Reset()
+			}
+			afterIndex = false
+		case '[':
+			end := strings.IndexByte(flagName[i:], ']')
+			if end == -1 || name.Len() == 0 && !afterIndex {
+				return nil, invalidFlagNameErr
+			}
+
+			if name.Len() > 0 {
+				flagPath = append(flagPath, name.String())
+				name.Reset()
+			}
+			flagPath = append(flagPath, flagName[i:i+end+1])
+			i += end
+			afterIndex = true
+		default:
+			if afterIndex {
+				return nil, invalidFlagNameErr
+			}
+			name.WriteByte(flagName[i])
+		}
+	}
+
+	if name.Len() > 0 {
+		flagPath = append(flagPath, name.String())
+	} else if !afterIndex {
+		return nil, invalidFlagNameErr
+	}
+
+	return flagPath, nil
+}
+
+// parseFlagIndex parses a slice index of a flag path, e.g. [0]
+func parseFlagIndex(flagName string) (int, bool) {
+	if !strings.HasPrefix(flagName, "[") || !strings.HasSuffix(flagName, "]") {
+		return 0, false
+	}
+
+	index, err := strconv.Atoi(flagName[1 : len(flagName)-1])
+	if err != nil || index < 0 {
+		return 0, false
+	}
+
+	return index, true
+}
+
 func mergeFlagValue(configElemValue reflect.Value, flagPath []string, fullFlagName string, flagValue string) error {
-	mergeFunction := func(flagName string, currentFieldStruct reflect.StructField, currentFieldElemValue reflect.Value, currentElemValue reflect.Value) error {
-		currentFieldKind := currentFieldStruct.Type.Kind()
+	mergeFunction := func(flagName string, currentFieldType reflect.Type, currentFieldElemValue reflect.Value) error {
+		currentFieldKind := currentFieldType.Kind()
 
 		if currentFieldKind == reflect.Slice {
-			return mergeFlagValues(currentElemValue, []string{flagName}, fullFlagName, []string{flagValue})
+			return setFlagValues(flagName, currentFieldType, currentFieldElemValue, []string{flagValue})
 		}
 
-		parsedValue, err := getParsedValue(currentFieldKind, flagValue)
+		parsedValue, err := getParsedFieldValue(currentFieldType, flagValue)
 		if err != nil {
 			return fmt.Errorf("invalid value %s for flag name %s, expected %s", flagValue, flagName, currentFieldKind)
 		}
@@ -250,59 +458,224 @@ func mergeFlagValue(configElemValue reflect.Value, flagPath []string, fullFlagNa
 }
 
 func mergeFlagValues(configElemValue reflect.Value, flagPath []string, fullFlagName string, flagValues []string) error {
-	mergeFunction := func(flagName string, currentFieldStruct reflect.StructField, currentFieldElemValue reflect.Value, currentElemValue reflect.Value) error {
-		currentFieldKind := currentFieldStruct.Type.Kind()
+	mergeFunction := func(flagName string, currentFieldType reflect.Type, currentFieldElemValue reflect.Value) error {
+		return setFlagValues(flagName, currentFieldType, currentFieldElemValue, flagValues)
+	}
 
-		if currentFieldKind != reflect.Slice {
-			return fmt.Errorf("invalid values %s for flag name %s, expected %s", strings.Join(flagValues, ","), flagName, currentFieldKind)
-		}
+	return mergeFlag(configElemValue, flagPath, fullFlagName, mergeFunction)
+}
 
-		flagValueKind := currentFieldStruct.Type.Elem().Kind()
+func mergeFlagJsonValue(configElemValue reflect.Value, flagPath []string, fullFlagName string, flagValue string) error {
+	mergeFunction := func(flagName string, currentFieldType reflect.Type, currentFieldElemValue reflect.Value) error {
+		// The current value is copied through JSON, so that it's unchanged if the JSON value is invalid.
+		decodedValue := reflect.New(currentFieldType)
+		currentData, err := json.Marshal(currentFieldElemValue.Interface())
+		if err != nil {
+			return err
+		}
 
-		parsedValues := reflect.MakeSlice(reflect.SliceOf(currentFieldStruct.Type.Elem()), 0, 0)
-		for _, flagValue := range flagValues {
-			parsedValue, err := getParsedValue(flagValueKind, flagValue)
-			if err != nil {
-				return fmt.Errorf("invalid value %s for flag name %s, expected %s", flagValue, flagName, flagValueKind)
-			}
+		if err := json.Unmarshal(currentData, decodedValue.Interface()); err != nil {
+			return err
+		}
 
-			parsedValues = reflect.Append(parsedValues, parsedValue)
+		if err := json.Unmarshal([]byte(flagValue), decodedValue.Interface()); err != nil {
+			return fmt.Errorf("invalid JSON value %s for flag name %s, %v", flagValue, flagName, err)
 		}
 
-		currentFieldElemValue.Set(parsedValues)
+		currentFieldElemValue.Set(decodedValue.Elem())
 		return nil
 	}
 
 	return mergeFlag(configElemValue, flagPath, fullFlagName, mergeFunction)
 }
 
-func mergeFlag(currentElemValue reflect.Value, currentFlagPath []string, fullFlagName string, mergeFunction func(flagName string, currentFieldStruct reflect.StructField, currentFieldElemValue reflect.Value, currentElemValue reflect.Value) error) error {
+func setFlagValues(flagName string, currentFieldType reflect.Type, currentFieldElemValue reflect.Value, flagValues []string) error {
+	if currentFieldType.Kind() != reflect.Slice {
+		return fmt.Errorf("invalid values %s for flag name %s, expected %s", strings.Join(flagValues, ","), flagName, currentFieldType.Kind())
+	}
+
+	flagValueType := currentFieldType.Elem()
+
+	parsedValues := reflect.MakeSlice(currentFieldType, 0, 0)
+	for _, flagValue := range flagValues {
+		parsedValue, err := getParsedFieldValue(flagValueType, flagValue)
+		if err != nil {
+			return fmt.Errorf("invalid value %s for flag name %s, expected %s", flagValue, flagName, flagValueType.Kind())
+		}
+
+		parsedValues = reflect.Append(parsedValues, parsedValue)
+	}
+
+	currentFieldElemValue.Set(parsedValues)
+	return nil
+}
+
+type mergeFunction func(flagName string, currentFieldType reflect.Type, currentFieldElemValue reflect.Value) error
+
+// mergeFlag walks the flag path through the struct fields, the map keys and the slice indexes, and merges the
+// value at its end by the merge function. The maps and the slices are only updated if the merge succeeds,
+// a slice is extended by zero values up to the index.
+func mergeFlag(currentElemValue reflect.Value, currentFlagPath []string, fullFlagName string, merge mergeFunction) error {
 	if len(currentFlagPath) == 0 {
 		return fmt.Errorf("flag \"%s\" not found", fullFlagName)
 	}
 
+	switch currentElemValue.Kind() {
+	case reflect.Struct:
+		currentFieldElemValue, ok := findFlagField(currentElemValue, currentFlagPath[0])
+		if !ok {
+			break
+		}
+
+		return mergeFlagField(currentFlagPath[0], currentFieldElemValue, currentFlagPath[1:], fullFlagName, merge)
+	case reflect.Map:
+		mapType := currentElemValue.Type()
+		key, err := getParsedFieldValue(mapType.Key(), currentFlagPath[0])
+		if err != nil {
+			return fmt.Errorf("invalid key %s for flag name %s, expected %s", currentFlagPath[0], fullFlagName, mapType.Key().Kind())
+		}
+
+		// The map values are not addressable, so a copy is merged and then put in the map.
+		mapValue := reflect.New(mapType.Elem()).Elem()
+		if currentMapValue := currentElemValue.MapIndex(key); currentMapValue.IsValid() {
+			mapValue.Set(currentMapValue)
+		}
+
+		if err := mergeFlagField(currentFlagPath[0], mapValue, currentFlagPath[1:], fullFlagName, merge); err != nil {
+			return err
+		}
+
+		if currentElemValue.IsNil() {
+			currentElemValue.Set(reflect.MakeMap(mapType))
+		}
+		currentElemValue.SetMapIndex(key, mapValue)
+		return nil
+	case reflect.Slice:
+		index, ok := parseFlagIndex(currentFlagPath[0])
+		if !ok {
+			return fmt.Errorf("invalid index %s for flag name %s, expected [<index>]", currentFlagPath[0], fullFlagName)
+		}
+
+		length := currentElemValue.Len()
+		if index >= length {
+			length = index + 1
+		}
+
+		sliceValue := reflect.MakeSlice(currentElemValue.Type(), length, length)
+		reflect.Copy(sliceValue, currentElemValue)
+
+		if err := mergeFlagField(currentFlagPath[0], sliceValue.Index(index), currentFlagPath[1:], fullFlagName, merge); err != nil {
+			return err
+		}
+
+		currentElemValue.Set(sliceValue)
+		return nil
+	case reflect.Interface:
+		// Only a map can be walked into, e.g. of the scripting env.
+		mapValue := map[string]interface{}{}
+		if !currentElemValue.IsNil() {
+			currentMapValue, ok := currentElemValue.Elem().Interface().(map[string]interface{})
+			if !ok {
+				break
+			}
+
+			for key, value := range currentMapValue {
+				mapValue[key] = value
+			}
+		}
+
+		if err := mergeFlag(reflect.ValueOf(mapValue), currentFlagPath, fullFlagName, merge); err != nil {
+			return err
+		}
+
+		currentElemValue.Set(reflect.ValueOf(mapValue))
+		return nil
+	}
+
+	return fmt.Errorf("flag \"%s\" not found", fullFlagName)
+}
+
+// mergeFlagField merges the rest of the flag path into a struct field, a map value or a slice element.
+func mergeFlagField(flagName string, currentFieldElemValue reflect.Value, currentFlagPath []string, fullFlagName string, merge mergeFunction) error {
+	if currentFieldElemValue.Kind() == reflect.Pointer {
+		pointerValue := reflect.New(currentFieldElemValue.Type().Elem())
+		if !currentFieldElemValue.IsNil() {
+			pointerValue.Elem().Set(currentFieldElemValue.Elem())
+		}
+
+		if err := mergeFlagField(flagName, pointerValue.Elem(), currentFlagPath, fullFlagName, merge); err != nil {
+			return err
+		}
+
+		currentFieldElemValue.Set(pointerValue)
+		return nil
+	}
+
+	if len(currentFlagPath) == 0 {
+		return merge(flagName, currentFieldElemValue.Type(), currentFieldElemValue)
+	}
+
+	return mergeFlag(currentFieldElemValue, currentFlagPath, fullFlagName, merge)
+}
+
+// findFlagField returns the field of the struct by its name in the config file, the inlined structs included.
+func findFlagField(currentElemValue reflect.Value, flagName string) (reflect.Value, bool) {
 	for i := 0; i < currentElemValue.NumField(); i++ {
 		currentFieldStruct := currentElemValue.Type().Field(i)
-		currentFieldElemValue := currentElemValue.FieldByName(currentFieldStruct.Name)
-
-		if currentFieldStruct.Type.Kind() == reflect.Struct && getFieldNameByTag(currentFieldStruct) == currentFlagPath[0] {
-			return mergeFlag(currentFieldElemValue, currentFlagPath[1:], fullFlagName, mergeFunction)
+		name, inline, ok := yamlFieldName(currentFieldStruct)
+		if !ok {
+			continue
 		}
 
-		if len(currentFlagPath) > 1 || getFieldNameByTag(currentFieldStruct) != currentFlagPath[0] {
+		if inline {
+			if currentFieldStruct.Type.Kind() != reflect.Struct {
+				continue
+			}
+
+			if currentFieldElemValue, ok := findFlagField(currentElemValue.Field(i), flagName); ok {
+				return currentFieldElemValue, true
+			}
 			continue
 		}
 
-		return mergeFunction(currentFlagPath[0], currentFieldStruct, currentFieldElemValue, currentElemValue)
+		if name == flagName {
+			return currentElemValue.Field(i), true
+		}
 	}
 
-	return fmt.Errorf("flag \"%s\" not found", fullFlagName)
+	return reflect.Value{}, false
 }
 
 func getFieldNameByTag(field reflect.StructField) string {
 	return strings.Split(field.Tag.Get(FieldNameTag), ",")[0]
 }
 
+// getParsedFieldValue parses a value of the type, which may be a named type, e.g. v1.NodeSelectorOperator.
+// A value of an interface{} is parsed as a bool, an integer or null if it's one of these, like Helm does.
+func getParsedFieldValue(t reflect.Type, value string) (reflect.Value, error) {
+	if t.Kind() == reflect.Interface {
+		if value == "null" {
+			return reflect.Zero(t), nil
+		}
+
+		var parsedValue interface{} = value
+		if value == "true" || value == "false" {
+			parsedValue = value == "true"
+		} else if intValue, err := strconv.ParseInt(value, 10, 64); err == nil {
+			parsedValue = intValue
+		}
+
+		return reflect.ValueOf(&parsedValue).Elem(), nil
+	}
+
+	parsedValue, err := getParsedValue(t.Kind(), value)
+	if err != nil {
+		return parsedValue, err
+	}
+
+	return parsedValue.Convert(t), nil
+}
+
 func getParsedValue(kind reflect.Kind, value string) (reflect.Value, error) {
 	switch kind {
 	case reflect.String:
@@ -384,6 +757,20 @@ func getParsedValue(kind reflect.Kind, value string) (reflect.Value, error) {
 		}
 
 		return reflect.ValueOf(uintArgumentValue), nil
+	case reflect.Float32:
+		floatArgumentValue, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			break
+		}
+
+		return reflect.ValueOf(float32(floatArgumentValue)), nil
+	case reflect.Float64:
+		floatArgumentValue, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			break
+		}
+
+		return reflect.ValueOf(floatArgumentValue), nil
 	}
 
 	return reflect.ValueOf(nil), errors.New("value to parse does not match type")
@@ -72,6 +72,23 @@ func CreateDefaultConfig() ConfigStruct {
 						},
 					},
 				},
+				Oidc: configStructs.OidcConfig{
+					ClientSecretRef: configStructs.SecretKeyRef{
+						Key: "clientSecret",
+					},
+					Scopes:     []string{"openid", "profile", "email"},
+					RolesClaim: "groups",
+					Roles: map[string]configStructs.Role{
+						"admin": {
+							Filter:                  "",
+							CanDownloadPCAP:         true,
+							CanUseScripting:         true,
+							CanUpdateTargetedPods:   true,
+							CanStopTrafficCapturing: true,
+							ShowAdminConsoleLink:    true,
+						},
+					},
+				},
 			},
 			EnabledDissectors: []string{
 				"amqp",
@@ -94,24 +111,26 @@ type KubeConfig struct {
 	Context       string `yaml:"context" json:"context"`
 }
 
-type ManifestsConfig struct {
-	Dump bool `yaml:"dump" json:"dump"`
-}
-
 type ConfigStruct struct {
 	Tap                  configStructs.TapConfig       `yaml:"tap" json:"tap"`
 	Logs                 configStructs.LogsConfig      `yaml:"logs" json:"logs"`
+	Export               configStructs.ExportConfig    `yaml:"export,omitempty" json:"-"`
+	Query                configStructs.QueryConfig     `yaml:"query,omitempty" json:"-"`
+	Status               configStructs.StatusConfig    `yaml:"status,omitempty" json:"-"`
 	Config               configStructs.ConfigConfig    `yaml:"config,omitempty" json:"config,omitempty"`
+	Target               configStructs.TargetConfig    `yaml:"target,omitempty" json:"-"`
+	Pause                configStructs.PauseConfig     `yaml:"pause,omitempty" json:"-"`
 	Kube                 KubeConfig                    `yaml:"kube" json:"kube"`
 	DumpLogs             bool                          `yaml:"dumpLogs" json:"dumpLogs" default:"false"`
 	HeadlessMode         bool                          `yaml:"headless" json:"headless" default:"false"`
-	License              string                        `yaml:"license" json:"license" default:""`
+	License              string                        `yaml:"license" json:"license" default:"" secret:""`
 	CloudLicenseEnabled  bool                          `yaml:"cloudLicenseEnabled" json:"cloudLicenseEnabled" default:"true"`
 	SupportChatEnabled   bool                          `yaml:"supportChatEnabled" json:"supportChatEnabled" default:"true"`
 	InternetConnectivity bool                          `yaml:"internetConnectivity" json:"internetConnectivity" default:"true"`
 	Scripting            configStructs.ScriptingConfig `yaml:"scripting" json:"scripting"`
-	Manifests            ManifestsConfig               `yaml:"manifests,omitempty" json:"manifests,omitempty"`
+	Manifests            configStructs.ManifestsConfig `yaml:"manifests,omitempty" json:"-"`
 	Timezone             string                        `yaml:"timezone" json:"timezone"`
+	Profiles             map[string]interface{}        `yaml:"profiles,omitempty" json:"-"`
 }
 
 func (config *ConfigStruct) ImagePullPolicy() v1.PullPolicy {
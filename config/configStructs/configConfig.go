@@ -2,8 +2,12 @@ package configStructs
 
 const (
 	RegenerateConfigName = "regenerate"
+	SchemaConfigName     = "schema"
+	ExplainConfigName    = "explain"
 )
 
 type ConfigConfig struct {
 	Regenerate bool `yaml:"regenerate,omitempty" json:"regenerate,omitempty" default:"false" readonly:""`
+	Schema     bool `yaml:"schema,omitempty" json:"schema,omitempty" default:"false" readonly:""`
+	Explain    bool `yaml:"explain,omitempty" json:"explain,omitempty" default:"false" readonly:""`
 }
@@ -0,0 +1,114 @@
+package configStructs
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"time"
+)
+
+const (
+	OutputExportName  = "output"
+	QueryExportName   = "query"
+	StartExportName   = "start"
+	EndExportName     = "end"
+	NodesExportName   = "nodes"
+	PodsExportName    = "pods"
+	RetriesExportName = "retries"
+	DirectExportName  = "direct"
+	FormatExportName  = "format"
+	PcapExportFormat  = "pcap"
+	HarExportFormat   = "har"
+	StdoutExport      = "-"
+)
+
+type ExportConfig struct {
+	Output  string   `yaml:"output,omitempty" json:"output,omitempty" default:"" readonly:""`
+	Query   string   `yaml:"query,omitempty" json:"query,omitempty" default:"" readonly:""`
+	Start   string   `yaml:"start,omitempty" json:"start,omitempty" default:"" readonly:""`
+	End     string   `yaml:"end,omitempty" json:"end,omitempty" default:"" readonly:""`
+	Nodes   []string `yaml:"nodes,omitempty" json:"nodes,omitempty" default:"[]" readonly:""`
+	Pods    []string `yaml:"pods,omitempty" json:"pods,omitempty" default:"[]" readonly:""`
+	Retries int      `yaml:"retries,omitempty" json:"retries,omitempty" default:"5" readonly:""`
+	Direct  bool     `yaml:"direct,omitempty" json:"direct,omitempty" default:"false" readonly:""`
+	Format  string   `yaml:"format,omitempty" json:"format,omitempty" default:"pcap" readonly:""`
+}
+
+func (config *ExportConfig) Validate() error {
+	start, end, err := config.TimeRange()
+	if err != nil {
+		return err
+	}
+
+	if !start.IsZero() && !end.IsZero() && end.Before(start) {
+		return fmt.Errorf("the end of the time range (%s) is before its start (%s)", config.End, config.Start)
+	}
+
+	if config.Format != PcapExportFormat && config.Format != HarExportFormat {
+		return fmt.Errorf("unknown format %q, expected %q or %q", config.Format, PcapExportFormat, HarExportFormat)
+	}
+
+	if config.Format == HarExportFormat && config.Direct {
+		return fmt.Errorf("the %q format requires the Hub, it cannot be exported directly from the Worker pods", HarExportFormat)
+	}
+
+	if config.Retries < 0 {
+		return fmt.Errorf("retries cannot be negative: %d", config.Retries)
+	}
+
+	return nil
+}
+
+func (config *ExportConfig) IsStdout() bool {
+	return config.Output == StdoutExport
+}
+
+func (config *ExportConfig) OutputPath() string {
+	if config.Output == "" {
+		pwd, _ := os.Getwd()
+		if config.Format == HarExportFormat {
+			return path.Join(pwd, fmt.Sprintf("%d_har", time.Now().Unix()))
+		}
+		return path.Join(pwd, fmt.Sprintf("%d.tar.gz", time.Now().Unix()))
+	}
+
+	return config.Output
+}
+
+// TimeRange parses the start and the end of the export window. Both accept either
+// an RFC3339 timestamp or a duration that is relative to now (e.g. `15m` means 15 minutes ago).
+// A zero time means the window is open on that side.
+func (config *ExportConfig) TimeRange() (start time.Time, end time.Time, err error) {
+	now := time.Now()
+
+	start, err = parseTimeOrDuration(config.Start, now)
+	if err != nil {
+		err = fmt.Errorf("invalid start time %q, %w", config.Start, err)
+		return
+	}
+
+	end, err = parseTimeOrDuration(config.End, now)
+	if err != nil {
+		err = fmt.Errorf("invalid end time %q, %w", config.End, err)
+		return
+	}
+
+	return
+}
+
+func parseTimeOrDuration(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected an RFC3339 timestamp or a duration")
+	}
+
+	return now.Add(-d.Abs()), nil
+}
@@ -0,0 +1,29 @@
+package configStructs
+
+import "fmt"
+
+const (
+	DumpManifestsName          = "dump"
+	DirectoryManifestsName     = "directory"
+	SplitManifestsName         = "split"
+	KustomizationManifestsName = "kustomization"
+)
+
+type ManifestsConfig struct {
+	Dump          bool   `yaml:"dump,omitempty" json:"dump,omitempty" default:"false" readonly:""`
+	Directory     string `yaml:"directory,omitempty" json:"directory,omitempty" default:"manifests" readonly:""`
+	Split         bool   `yaml:"split,omitempty" json:"split,omitempty" default:"false" readonly:""`
+	Kustomization bool   `yaml:"kustomization,omitempty" json:"kustomization,omitempty" default:"false" readonly:""`
+}
+
+func (config *ManifestsConfig) Validate() error {
+	if (config.Split || config.Kustomization) && !config.Dump {
+		return fmt.Errorf("--%s and --%s require --%s", SplitManifestsName, KustomizationManifestsName, DumpManifestsName)
+	}
+
+	if config.Dump && config.Directory == "" {
+		return fmt.Errorf("--%s requires a --%s", DumpManifestsName, DirectoryManifestsName)
+	}
+
+	return nil
+}
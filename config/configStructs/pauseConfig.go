@@ -0,0 +1,33 @@
+package configStructs
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	ForPauseName = "for"
+)
+
+type PauseConfig struct {
+	For string `yaml:"for,omitempty" json:"for,omitempty" default:"10m" readonly:""`
+}
+
+func (config *PauseConfig) Validate() error {
+	_, err := config.Duration()
+	return err
+}
+
+// Duration returns the time that the capture is paused for.
+func (config *PauseConfig) Duration() (time.Duration, error) {
+	duration, err := time.ParseDuration(config.For)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q, %w", config.For, err)
+	}
+
+	if duration <= 0 {
+		return 0, fmt.Errorf("invalid duration %q, expected a positive duration, e.g. 10m", config.For)
+	}
+
+	return duration, nil
+}
@@ -0,0 +1,51 @@
+package configStructs
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	LimitQueryName   = "limit"
+	FollowQueryName  = "follow"
+	SinceQueryName   = "since"
+	FormatQueryName  = "format"
+	JsonQueryFormat  = "json"
+	TableQueryFormat = "table"
+)
+
+type QueryConfig struct {
+	Filter string `yaml:"filter,omitempty" json:"filter,omitempty" default:"" readonly:""`
+	Limit  int    `yaml:"limit,omitempty" json:"limit,omitempty" default:"0" readonly:""`
+	Follow bool   `yaml:"follow,omitempty" json:"follow,omitempty" default:"false" readonly:""`
+	Since  string `yaml:"since,omitempty" json:"since,omitempty" default:"" readonly:""`
+	Format string `yaml:"format,omitempty" json:"format,omitempty" default:"json" readonly:""`
+}
+
+func (config *QueryConfig) Validate() error {
+	if config.Limit < 0 {
+		return fmt.Errorf("limit cannot be negative: %d", config.Limit)
+	}
+
+	if config.Format != JsonQueryFormat && config.Format != TableQueryFormat {
+		return fmt.Errorf("unknown format %q, expected %q or %q", config.Format, JsonQueryFormat, TableQueryFormat)
+	}
+
+	if _, err := config.SinceTime(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SinceTime parses the start of the query. It accepts either an RFC3339 timestamp or a duration
+// that is relative to now. A zero time means the entries are streamed from the start of the capture, or only the
+// new ones with --follow.
+func (config *QueryConfig) SinceTime() (time.Time, error) {
+	since, err := parseTimeOrDuration(config.Since, time.Now())
+	if err != nil {
+		return since, fmt.Errorf("invalid since %q, %w", config.Since, err)
+	}
+
+	return since, nil
+}
@@ -1,45 +1,48 @@
 package configStructs
 
 import (
-	"io/fs"
-	"os"
-	"path/filepath"
-
 	"github.com/kubeshark/kubeshark/misc"
 	"github.com/rs/zerolog/log"
 )
 
+const (
+	PlanScriptingSyncName    = "plan"
+	YesScriptingSyncName     = "yes"
+	FixtureScriptingTestName = "fixture"
+	HookScriptingTestName    = "hook"
+)
+
+type ScriptingSyncConfig struct {
+	Plan bool `yaml:"plan,omitempty" json:"plan,omitempty" default:"false" readonly:""`
+	Yes  bool `yaml:"yes,omitempty" json:"yes,omitempty" default:"false" readonly:""`
+}
+
+type ScriptingTestConfig struct {
+	Fixture string `yaml:"fixture,omitempty" json:"fixture,omitempty" default:"" readonly:""`
+	Hook    string `yaml:"hook,omitempty" json:"hook,omitempty" default:"onItemCaptured" readonly:""`
+}
+
 type ScriptingConfig struct {
 	Env          map[string]interface{} `yaml:"env" json:"env" default:"{}"`
 	Source       string                 `yaml:"source" json:"source" default:""`
 	WatchScripts bool                   `yaml:"watchScripts" json:"watchScripts" default:"true"`
+	Sync         ScriptingSyncConfig    `yaml:"sync,omitempty" json:"-"`
+	Test         ScriptingTestConfig    `yaml:"test,omitempty" json:"-"`
 }
 
+// GetScripts reads the scripts in the source directory and in its subdirectories.
 func (config *ScriptingConfig) GetScripts() (scripts []*misc.Script, err error) {
 	if config.Source == "" {
 		return
 	}
 
-	var files []fs.DirEntry
-	files, err = os.ReadDir(config.Source)
+	scripts, err = misc.ReadScriptTree(config.Source)
 	if err != nil {
 		return
 	}
 
-	for _, f := range files {
-		if f.IsDir() {
-			continue
-		}
-
-		var script *misc.Script
-		path := filepath.Join(config.Source, f.Name())
-		script, err = misc.ReadScriptFile(path)
-		if err != nil {
-			return
-		}
-		scripts = append(scripts, script)
-
-		log.Debug().Str("path", path).Msg("Found script:")
+	for _, script := range scripts {
+		log.Debug().Str("path", script.Path).Msg("Found script:")
 	}
 
 	return
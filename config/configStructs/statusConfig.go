@@ -0,0 +1,21 @@
+package configStructs
+
+import "fmt"
+
+const (
+	FormatStatusName  = "format"
+	TableStatusFormat = "table"
+	JsonStatusFormat  = "json"
+)
+
+type StatusConfig struct {
+	Format string `yaml:"format,omitempty" json:"format,omitempty" default:"table" readonly:""`
+}
+
+func (config *StatusConfig) Validate() error {
+	if config.Format != TableStatusFormat && config.Format != JsonStatusFormat {
+		return fmt.Errorf("unknown format %q, expected %q or %q", config.Format, TableStatusFormat, JsonStatusFormat)
+	}
+
+	return nil
+}
@@ -1,11 +1,18 @@
 package configStructs
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
+	"reflect"
 	"regexp"
+	"strings"
+	"time"
 
+	"github.com/kubeshark/kubeshark/utils"
 	v1 "k8s.io/api/core/v1"
 	networking "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 const (
@@ -18,10 +25,12 @@ const (
 	ProxyHostLabel               = "proxy-host"
 	NamespacesLabel              = "namespaces"
 	ExcludedNamespacesLabel      = "excludedNamespaces"
+	LabelSelectorLabel           = "labelSelector"
+	NamespaceSelectorLabel       = "namespaceSelector"
 	ReleaseNamespaceLabel        = "release-namespace"
 	PersistentStorageLabel       = "persistentStorage"
 	PersistentStorageStaticLabel = "persistentStorageStatic"
-	EfsFileSytemIdAndPathLabel   = "efsFileSytemIdAndPath"
+	EfsFileSystemIdAndPathLabel  = "efsFileSystemIdAndPath"
 	StorageLimitLabel            = "storageLimit"
 	StorageClassLabel            = "storageClass"
 	DryRunLabel                  = "dryRun"
@@ -32,6 +41,15 @@ const (
 	IngressEnabledLabel          = "ingress-enabled"
 	TelemetryEnabledLabel        = "telemetry-enabled"
 	DebugLabel                   = "debug"
+	ContextsLabel                = "contexts"
+	ReuseLabel                   = "reuse"
+	UpgradeLabel                 = "upgrade"
+	DurationLabel                = "duration"
+	MaxEntriesLabel              = "maxEntries"
+	MaxBytesLabel                = "maxBytes"
+	ExportDirLabel               = "export-dir"
+	ExportFormatsLabel           = "export-formats"
+	EphemeralLabel               = "ephemeral"
 	ContainerPort                = 80
 	ContainerPortStr             = "80"
 )
@@ -63,6 +81,12 @@ type FrontConfig struct {
 	Port uint16 `yaml:"port" json:"port" default:"8899"`
 }
 
+// TapExportConfig is where and how a time-boxed capture session is exported, see TapConfig.IsSession.
+type TapExportConfig struct {
+	Dir     string   `yaml:"dir,omitempty" json:"-" default:"" readonly:""`
+	Formats []string `yaml:"formats,omitempty" json:"-" default:"[\"pcap\",\"har\"]" readonly:"" enum:"pcap,har"`
+}
+
 type ProxyConfig struct {
 	Worker WorkerConfig `yaml:"worker" json:"worker"`
 	Hub    HubConfig    `yaml:"hub" json:"hub"`
@@ -79,7 +103,7 @@ type OverrideTagConfig struct {
 type DockerConfig struct {
 	Registry         string            `yaml:"registry" json:"registry" default:"docker.io/kubeshark"`
 	Tag              string            `yaml:"tag" json:"tag" default:""`
-	ImagePullPolicy  string            `yaml:"imagePullPolicy" json:"imagePullPolicy" default:"Always"`
+	ImagePullPolicy  string            `yaml:"imagePullPolicy" json:"imagePullPolicy" default:"Always" enum:"Always,IfNotPresent,Never"`
 	ImagePullSecrets []string          `yaml:"imagePullSecrets" json:"imagePullSecrets"`
 	OverrideTag      OverrideTagConfig `yaml:"overrideTag" json:"overrideTag"`
 }
@@ -90,6 +114,29 @@ type ResourcesConfig struct {
 	Tracer  ResourceRequirements `yaml:"tracer" json:"tracer"`
 }
 
+// validate checks the quantities of the resources. An empty quantity is not set, so it's valid.
+func (config *ResourcesConfig) validate(path string) (errs []error) {
+	for name, requirements := range map[string]ResourceRequirements{
+		"hub":     config.Hub,
+		"sniffer": config.Sniffer,
+		"tracer":  config.Tracer,
+	} {
+		for quantityPath, quantity := range map[string]string{
+			"limits.cpu":      requirements.Limits.CPU,
+			"limits.memory":   requirements.Limits.Memory,
+			"requests.cpu":    requirements.Requests.CPU,
+			"requests.memory": requirements.Requests.Memory,
+		} {
+			if quantity == "" {
+				continue
+			}
+			errs = append(errs, validateQuantity(fmt.Sprintf("%s.%s.%s", path, name, quantityPath), quantity))
+		}
+	}
+
+	return
+}
+
 type Role struct {
 	Filter                  string `yaml:"filter" json:"filter" default:""`
 	CanDownloadPCAP         bool   `yaml:"canDownloadPCAP" json:"canDownloadPCAP" default:"false"`
@@ -102,15 +149,93 @@ type Role struct {
 type SamlConfig struct {
 	IdpMetadataUrl string          `yaml:"idpMetadataUrl" json:"idpMetadataUrl"`
 	X509crt        string          `yaml:"x509crt" json:"x509crt"`
-	X509key        string          `yaml:"x509key" json:"x509key"`
+	X509key        string          `yaml:"x509key" json:"x509key" secret:""`
 	RoleAttribute  string          `yaml:"roleAttribute" json:"roleAttribute"`
 	Roles          map[string]Role `yaml:"roles" json:"roles"`
 }
 
+// SecretKeyRef refers to a key of a secret in the release namespace.
+type SecretKeyRef struct {
+	Name string `yaml:"name" json:"name"`
+	Key  string `yaml:"key" json:"key"`
+}
+
+type OidcConfig struct {
+	Issuer          string          `yaml:"issuer" json:"issuer"`
+	ClientId        string          `yaml:"clientId" json:"clientId"`
+	ClientSecretRef SecretKeyRef    `yaml:"clientSecretRef" json:"clientSecretRef"`
+	Scopes          []string        `yaml:"scopes" json:"scopes"`
+	RolesClaim      string          `yaml:"rolesClaim" json:"rolesClaim"`
+	Roles           map[string]Role `yaml:"roles" json:"roles"`
+}
+
+const (
+	AuthTypeSaml = "saml"
+	AuthTypeOidc = "oidc"
+)
+
 type AuthConfig struct {
 	Enabled bool       `yaml:"enabled" json:"enabled" default:"false"`
-	Type    string     `yaml:"type" json:"type" default:"saml"`
+	Type    string     `yaml:"type" json:"type" default:"saml" enum:"saml,oidc"`
 	Saml    SamlConfig `yaml:"saml" json:"saml"`
+	Oidc    OidcConfig `yaml:"oidc" json:"oidc"`
+}
+
+func (config *AuthConfig) validate(path string) (errs []error) {
+	if !config.Enabled {
+		return
+	}
+
+	switch config.Type {
+	case AuthTypeSaml:
+		return config.validateSaml(path)
+	case AuthTypeOidc:
+		return config.validateOidc(path)
+	}
+
+	return
+}
+
+func (config *AuthConfig) validateSaml(path string) (errs []error) {
+	if idpMetadataUrl, err := url.Parse(config.Saml.IdpMetadataUrl); err != nil || (idpMetadataUrl.Scheme != "http" && idpMetadataUrl.Scheme != "https") || idpMetadataUrl.Host == "" {
+		errs = append(errs, fmt.Errorf("%s.saml.idpMetadataUrl: an http(s) URL is required when the SAML authentication is enabled, got %q", path, config.Saml.IdpMetadataUrl))
+	}
+
+	if config.Saml.RoleAttribute == "" {
+		errs = append(errs, fmt.Errorf("%s.saml.roleAttribute: required when the SAML authentication is enabled", path))
+	}
+
+	if (config.Saml.X509crt == "") != (config.Saml.X509key == "") {
+		errs = append(errs, fmt.Errorf("%s.saml.x509crt and %s.saml.x509key must be set together", path, path))
+	}
+
+	return
+}
+
+func (config *AuthConfig) validateOidc(path string) (errs []error) {
+	if issuer, err := url.Parse(config.Oidc.Issuer); err != nil || (issuer.Scheme != "http" && issuer.Scheme != "https") || issuer.Host == "" {
+		errs = append(errs, fmt.Errorf("%s.oidc.issuer: an http(s) URL is required when the OIDC authentication is enabled, got %q", path, config.Oidc.Issuer))
+	}
+
+	for _, required := range []struct {
+		name  string
+		value string
+	}{
+		{name: "clientId", value: config.Oidc.ClientId},
+		{name: "clientSecretRef.name", value: config.Oidc.ClientSecretRef.Name},
+		{name: "clientSecretRef.key", value: config.Oidc.ClientSecretRef.Key},
+		{name: "rolesClaim", value: config.Oidc.RolesClaim},
+	} {
+		if required.value == "" {
+			errs = append(errs, fmt.Errorf("%s.oidc.%s: required when the OIDC authentication is enabled", path, required.name))
+		}
+	}
+
+	if !utils.Contains(config.Oidc.Scopes, "openid") {
+		errs = append(errs, fmt.Errorf("%s.oidc.scopes: the openid scope is required, got %v", path, config.Oidc.Scopes))
+	}
+
+	return
 }
 
 type IngressConfig struct {
@@ -121,10 +246,49 @@ type IngressConfig struct {
 	Annotations map[string]string       `yaml:"annotations" json:"annotations" default:"{}"`
 }
 
+const (
+	ReleaseSourceEmbedded = "embedded"
+	ReleaseSourceRepo     = "repo"
+	ReleaseSourcePath     = "path"
+	ReleaseSourceOci      = "oci"
+)
+
+var ReleaseSources = []string{
+	ReleaseSourceEmbedded,
+	ReleaseSourceRepo,
+	ReleaseSourcePath,
+	ReleaseSourceOci,
+}
+
 type ReleaseConfig struct {
-	Repo      string `yaml:"repo" json:"repo" default:"https://helm.kubeshark.co"`
-	Name      string `yaml:"name" json:"name" default:"kubeshark"`
-	Namespace string `yaml:"namespace" json:"namespace" default:"default"`
+	Repo          string `yaml:"repo" json:"repo" default:"https://helm.kubeshark.co"`
+	Name          string `yaml:"name" json:"name" default:"kubeshark"`
+	Namespace     string `yaml:"namespace" json:"namespace" default:"default"`
+	Source        string `yaml:"source" json:"source" default:"repo"`
+	Version       string `yaml:"version" json:"version" default:""`
+	Path          string `yaml:"path" json:"path" default:""`
+	ImageRegistry string `yaml:"imageRegistry" json:"imageRegistry" default:""`
+}
+
+func (config *ReleaseConfig) Validate() error {
+	switch config.Source {
+	case ReleaseSourceEmbedded, ReleaseSourceRepo:
+	case ReleaseSourcePath:
+		if config.Path == "" {
+			return fmt.Errorf("release.path is required when the release.source is %q", ReleaseSourcePath)
+		}
+	case ReleaseSourceOci:
+		if !strings.HasPrefix(config.Repo, "oci://") {
+			return fmt.Errorf("release.repo must be an oci:// reference when the release.source is %q", ReleaseSourceOci)
+		}
+		if config.Version == "" {
+			return fmt.Errorf("release.version is required when the release.source is %q", ReleaseSourceOci)
+		}
+	default:
+		return fmt.Errorf("release.source must be one of %s, got %q", strings.Join(ReleaseSources, ", "), config.Source)
+	}
+
+	return nil
 }
 
 type TelemetryConfig struct {
@@ -160,25 +324,55 @@ type MiscConfig struct {
 	DuplicateTimeframe          string `yaml:"duplicateTimeframe" json:"duplicateTimeframe" default:"200ms"`
 }
 
+func (config *MiscConfig) validate(path string) (errs []error) {
+	errs = append(errs,
+		validateDuration(path+".jsonTTL", config.JsonTTL),
+		validateDuration(path+".pcapTTL", config.PcapTTL),
+		validateDuration(path+".pcapErrorTTL", config.PcapErrorTTL),
+		validateDuration(path+".duplicateTimeframe", config.DuplicateTimeframe),
+	)
+
+	if config.TrafficSampleRate < 0 || config.TrafficSampleRate > 100 {
+		errs = append(errs, fmt.Errorf("%s.trafficSampleRate: a percentage between 0 and 100 is expected, got %d", path, config.TrafficSampleRate))
+	}
+
+	if config.TcpStreamChannelTimeoutMs < 0 {
+		errs = append(errs, fmt.Errorf("%s.tcpStreamChannelTimeoutMs: must not be negative, got %d", path, config.TcpStreamChannelTimeoutMs))
+	}
+
+	return
+}
+
 type TapConfig struct {
 	Docker                       DockerConfig          `yaml:"docker" json:"docker"`
 	Proxy                        ProxyConfig           `yaml:"proxy" json:"proxy"`
 	PodRegexStr                  string                `yaml:"regex" json:"regex" default:".*"`
 	Namespaces                   []string              `yaml:"namespaces" json:"namespaces" default:"[]"`
 	ExcludedNamespaces           []string              `yaml:"excludedNamespaces" json:"excludedNamespaces" default:"[]"`
+	LabelSelector                string                `yaml:"labelSelector" json:"labelSelector" default:""`
+	Workloads                    []string              `yaml:"workloads" json:"workloads" default:"[]"`
+	NamespaceSelector            string                `yaml:"namespaceSelector" json:"namespaceSelector" default:""`
 	BpfOverride                  string                `yaml:"bpfOverride" json:"bpfOverride" default:""`
 	Stopped                      bool                  `yaml:"stopped" json:"stopped" default:"true"`
 	Release                      ReleaseConfig         `yaml:"release" json:"release"`
+	Contexts                     []string              `yaml:"contexts,omitempty" json:"contexts,omitempty" default:"[]"`
+	Reuse                        bool                  `yaml:"reuse,omitempty" json:"-" default:"false" readonly:""`
+	Upgrade                      bool                  `yaml:"upgrade,omitempty" json:"-" default:"false" readonly:""`
+	Duration                     string                `yaml:"duration,omitempty" json:"-" default:"" readonly:""`
+	MaxEntries                   int                   `yaml:"maxEntries,omitempty" json:"-" default:"0" readonly:""`
+	MaxBytes                     string                `yaml:"maxBytes,omitempty" json:"-" default:"" readonly:""`
+	Export                       TapExportConfig       `yaml:"export,omitempty" json:"-"`
+	Ephemeral                    bool                  `yaml:"ephemeral,omitempty" json:"-" default:"false" readonly:""`
 	PersistentStorage            bool                  `yaml:"persistentStorage" json:"persistentStorage" default:"false"`
 	PersistentStorageStatic      bool                  `yaml:"persistentStorageStatic" json:"persistentStorageStatic" default:"false"`
-	EfsFileSytemIdAndPath        string                `yaml:"efsFileSytemIdAndPath" json:"efsFileSytemIdAndPath" default:""`
+	EfsFileSystemIdAndPath       string                `yaml:"efsFileSystemIdAndPath" json:"efsFileSystemIdAndPath" default:""`
 	StorageLimit                 string                `yaml:"storageLimit" json:"storageLimit" default:"500Mi"`
 	StorageClass                 string                `yaml:"storageClass" json:"storageClass" default:"standard"`
 	DryRun                       bool                  `yaml:"dryRun" json:"dryRun" default:"false"`
 	Resources                    ResourcesConfig       `yaml:"resources" json:"resources"`
 	ServiceMesh                  bool                  `yaml:"serviceMesh" json:"serviceMesh" default:"true"`
 	Tls                          bool                  `yaml:"tls" json:"tls" default:"true"`
-	PacketCapture                string                `yaml:"packetCapture" json:"packetCapture" default:"best"`
+	PacketCapture                string                `yaml:"packetCapture" json:"packetCapture" default:"best" enum:"best,af_packet,pf_ring,ebpf"`
 	IgnoreTainted                bool                  `yaml:"ignoreTainted" json:"ignoreTainted" default:"false"`
 	Labels                       map[string]string     `yaml:"labels" json:"labels" default:"{}"`
 	Annotations                  map[string]string     `yaml:"annotations" json:"annotations" default:"{}"`
@@ -196,7 +390,7 @@ type TapConfig struct {
 	StopTrafficCapturingDisabled bool                  `yaml:"stopTrafficCapturingDisabled" json:"stopTrafficCapturingDisabled" default:"false"`
 	Capabilities                 CapabilitiesConfig    `yaml:"capabilities" json:"capabilities"`
 	GlobalFilter                 string                `yaml:"globalFilter" json:"globalFilter"`
-	EnabledDissectors            []string              `yaml:"enabledDissectors" json:"enabledDissectors"`
+	EnabledDissectors            []string              `yaml:"enabledDissectors" json:"enabledDissectors" examples:"amqp,dns,http,icmp,kafka,redis,sctp,syscall,tcp,udp,ws,tlsx,ldap,radius,diameter"`
 	Metrics                      MetricsConfig         `yaml:"metrics" json:"metrics"`
 	Misc                         MiscConfig            `yaml:"misc" json:"misc"`
 }
@@ -206,11 +400,136 @@ func (config *TapConfig) PodRegex() *regexp.Regexp {
 	return podRegex
 }
 
+// Validate reports all the invalid settings, so these don't fail later inside the cluster.
 func (config *TapConfig) Validate() error {
+	var errs []error
+
+	errs = append(errs, config.ValidateTargeting())
+
+	errs = append(errs, config.Release.Validate())
+
+	if config.Reuse && config.Upgrade {
+		errs = append(errs, fmt.Errorf("--%s and --%s are mutually exclusive", ReuseLabel, UpgradeLabel))
+	}
+
+	if config.Ephemeral && len(config.Contexts) > 0 {
+		errs = append(errs, fmt.Errorf("--%s and --%s are mutually exclusive", EphemeralLabel, ContextsLabel))
+	}
+
+	errs = append(errs, validateEnums(reflect.ValueOf(config).Elem(), "tap")...)
+	warnExamples(reflect.ValueOf(config).Elem(), "tap")
+	errs = append(errs, validateQuantity("tap.storageLimit", config.StorageLimit))
+	errs = append(errs, config.Resources.validate("tap.resources")...)
+	errs = append(errs, config.Misc.validate("tap.misc")...)
+	errs = append(errs, config.validatePorts()...)
+	errs = append(errs, config.Auth.validate("tap.auth")...)
+	errs = append(errs, config.validateSession()...)
+
+	return errors.Join(errs...)
+}
+
+// IsSession tells whether the capture is time-boxed, i.e. it's exported and uninstalled once the duration elapses
+// or the limits are reached.
+func (config *TapConfig) IsSession() bool {
+	return config.Duration != "" || config.MaxEntries > 0 || config.MaxBytes != ""
+}
+
+// SessionDuration returns the duration of the capture session, or zero if the session is not limited by time.
+func (config *TapConfig) SessionDuration() time.Duration {
+	duration, _ := time.ParseDuration(config.Duration)
+	return duration
+}
+
+// SessionMaxBytes returns the limit of the captured bytes of the session, or zero if the session is not limited by size.
+func (config *TapConfig) SessionMaxBytes() int64 {
+	quantity, err := resource.ParseQuantity(config.MaxBytes)
+	if err != nil {
+		return 0
+	}
+
+	return quantity.Value()
+}
+
+func (config *TapConfig) validateSession() (errs []error) {
+	if !config.IsSession() {
+		return
+	}
+
+	if config.Duration != "" {
+		if err := validateDuration("tap.duration", config.Duration); err != nil {
+			errs = append(errs, err)
+		} else if config.SessionDuration() <= 0 {
+			errs = append(errs, fmt.Errorf("tap.duration: the duration must be positive"))
+		}
+	}
+
+	if config.MaxEntries < 0 {
+		errs = append(errs, fmt.Errorf("tap.maxEntries: the limit must not be negative"))
+	}
+
+	if config.MaxBytes != "" {
+		if err := validateQuantity("tap.maxBytes", config.MaxBytes); err != nil {
+			errs = append(errs, err)
+		} else if config.SessionMaxBytes() <= 0 {
+			errs = append(errs, fmt.Errorf("tap.maxBytes: the limit must be positive"))
+		}
+	}
+
+	if len(config.Contexts) > 0 {
+		errs = append(errs, fmt.Errorf("a time-boxed capture cannot be combined with --%s", ContextsLabel))
+	}
+
+	if config.DryRun {
+		errs = append(errs, fmt.Errorf("a time-boxed capture cannot be combined with --%s", DryRunLabel))
+	}
+
+	return
+}
+
+// ValidateTargeting reports the invalid rules of the targeted pods and namespaces.
+func (config *TapConfig) ValidateTargeting() error {
+	var errs []error
+
 	_, compileErr := regexp.Compile(config.PodRegexStr)
 	if compileErr != nil {
-		return fmt.Errorf("%s is not a valid regex %s", config.PodRegexStr, compileErr)
+		errs = append(errs, fmt.Errorf("%s is not a valid regex %s", config.PodRegexStr, compileErr))
 	}
 
-	return nil
+	errs = append(errs, validateLabelSelector("tap.labelSelector", config.LabelSelector))
+	errs = append(errs, validateLabelSelector("tap.namespaceSelector", config.NamespaceSelector))
+	for i, ref := range config.Workloads {
+		if _, err := ParseWorkload(ref); err != nil {
+			errs = append(errs, fmt.Errorf("tap.workloads[%d]: %w", i, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validatePorts checks the ports that are bound together, locally by the proxy and on the nodes by the Workers.
+func (config *TapConfig) validatePorts() (errs []error) {
+	ports := []struct {
+		path string
+		port uint16
+	}{
+		{"tap.proxy.front.port", config.Proxy.Front.Port},
+		{"tap.proxy.hub.srvPort", config.Proxy.Hub.SrvPort},
+		{"tap.proxy.worker.srvPort", config.Proxy.Worker.SrvPort},
+		{"tap.metrics.port", config.Metrics.Port},
+	}
+	for _, p := range ports {
+		if p.port == 0 {
+			errs = append(errs, fmt.Errorf("%s: the port must not be 0", p.path))
+		}
+	}
+
+	if config.Proxy.Front.Port == config.Proxy.Hub.SrvPort {
+		errs = append(errs, fmt.Errorf("tap.proxy.front.port and tap.proxy.hub.srvPort collide on the port %d", config.Proxy.Front.Port))
+	}
+
+	if config.Proxy.Worker.SrvPort == config.Metrics.Port {
+		errs = append(errs, fmt.Errorf("tap.proxy.worker.srvPort and tap.metrics.port collide on the port %d", config.Metrics.Port))
+	}
+
+	return
 }
@@ -0,0 +1,8 @@
+package configStructs
+
+type TargetConfig struct {
+	Namespaces         []string `yaml:"namespaces,omitempty" json:"namespaces,omitempty" default:"[]" readonly:""`
+	ExcludedNamespaces []string `yaml:"excludedNamespaces,omitempty" json:"excludedNamespaces,omitempty" default:"[]" readonly:""`
+	LabelSelector      string   `yaml:"labelSelector,omitempty" json:"labelSelector,omitempty" default:"" readonly:""`
+	NamespaceSelector  string   `yaml:"namespaceSelector,omitempty" json:"namespaceSelector,omitempty" default:"" readonly:""`
+}
@@ -0,0 +1,93 @@
+package configStructs
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/kubeshark/kubeshark/utils"
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// EnumTag lists the allowed values of a string field, or of the items of a string slice field, comma-separated.
+const EnumTag = "enum"
+
+// ExamplesTag lists the known values of a field, like EnumTag does. The other values are only warned about, as the
+// chart may support more of these than the CLI knows of, e.g. the dissectors.
+const ExamplesTag = "examples"
+
+// validateEnums checks the fields of the struct, and of its nested structs, against their enum tags.
+func validateEnums(value reflect.Value, path string) []error {
+	return unknownTagValues(value, path, EnumTag)
+}
+
+// warnExamples logs the values of the fields of the struct, and of its nested structs, that their examples tags
+// don't list.
+func warnExamples(value reflect.Value, path string) {
+	for _, err := range unknownTagValues(value, path, ExamplesTag) {
+		log.Warn().Msg(fmt.Sprintf("Unknown config value, %v", err))
+	}
+}
+
+// unknownTagValues reports the values of the fields of the struct, and of its nested structs, that the tag
+// doesn't list.
+func unknownTagValues(value reflect.Value, path string, tag string) (errs []error) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		fieldValue := value.Field(i)
+		fieldPath := fmt.Sprintf("%s.%s", path, strings.Split(field.Tag.Get("yaml"), ",")[0])
+
+		if fieldValue.Kind() == reflect.Struct {
+			errs = append(errs, unknownTagValues(fieldValue, fieldPath, tag)...)
+			continue
+		}
+
+		enum, ok := field.Tag.Lookup(tag)
+		if !ok {
+			continue
+		}
+		allowed := strings.Split(enum, ",")
+
+		switch fieldValue.Kind() {
+		case reflect.String:
+			if !utils.Contains(allowed, fieldValue.String()) {
+				errs = append(errs, fmt.Errorf("%s: unknown value %q, expected one of %s", fieldPath, fieldValue.String(), enum))
+			}
+		case reflect.Slice:
+			for j := 0; j < fieldValue.Len(); j++ {
+				if item := fieldValue.Index(j).String(); !utils.Contains(allowed, item) {
+					errs = append(errs, fmt.Errorf("%s[%d]: unknown value %q, expected one of %s", fieldPath, j, item, enum))
+				}
+			}
+		}
+	}
+
+	return
+}
+
+func validateQuantity(path string, quantity string) error {
+	if _, err := resource.ParseQuantity(quantity); err != nil {
+		return fmt.Errorf("%s: invalid quantity %q, e.g. 500Mi, 1Gi or 750m are expected", path, quantity)
+	}
+
+	return nil
+}
+
+func validateDuration(path string, duration string) error {
+	if _, err := time.ParseDuration(duration); err != nil {
+		return fmt.Errorf("%s: invalid duration %q, e.g. 10s, 5m or 200ms are expected", path, duration)
+	}
+
+	return nil
+}
+
+func validateLabelSelector(path string, selector string) error {
+	if _, err := labels.Parse(selector); err != nil {
+		return fmt.Errorf("%s: invalid label selector %q, e.g. app=checkout or team in (core,payments) is expected, %w", path, selector, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package configStructs
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	WorkloadKindDeployment  = "Deployment"
+	WorkloadKindStatefulSet = "StatefulSet"
+	WorkloadKindDaemonSet   = "DaemonSet"
+	WorkloadKindReplicaSet  = "ReplicaSet"
+	WorkloadKindService     = "Service"
+)
+
+// workloadKinds maps the names of the kinds, like kubectl accepts these, to the kinds.
+var workloadKinds = map[string]string{
+	"deploy":       WorkloadKindDeployment,
+	"deployment":   WorkloadKindDeployment,
+	"deployments":  WorkloadKindDeployment,
+	"sts":          WorkloadKindStatefulSet,
+	"statefulset":  WorkloadKindStatefulSet,
+	"statefulsets": WorkloadKindStatefulSet,
+	"ds":           WorkloadKindDaemonSet,
+	"daemonset":    WorkloadKindDaemonSet,
+	"daemonsets":   WorkloadKindDaemonSet,
+	"rs":           WorkloadKindReplicaSet,
+	"replicaset":   WorkloadKindReplicaSet,
+	"replicasets":  WorkloadKindReplicaSet,
+	"svc":          WorkloadKindService,
+	"service":      WorkloadKindService,
+	"services":     WorkloadKindService,
+}
+
+// Workload is a targeted workload, given like kubectl does, e.g. deploy/checkout or svc/payments. The pods of a
+// workload are the ones that it owns, and for a Service the ones of its endpoints, in any of the targeted namespaces.
+type Workload struct {
+	Ref  string
+	Kind string
+	Name string
+}
+
+func (workload *Workload) String() string {
+	return workload.Ref
+}
+
+// IsWorkloadRef tells whether the argument names a workload, rather than being a pod regex.
+func IsWorkloadRef(ref string) bool {
+	kind, _, ok := strings.Cut(ref, "/")
+	if !ok {
+		return false
+	}
+
+	_, ok = workloadKinds[strings.ToLower(kind)]
+	return ok
+}
+
+func ParseWorkload(ref string) (*Workload, error) {
+	kindName, name, ok := strings.Cut(ref, "/")
+	kind, known := workloadKinds[strings.ToLower(kindName)]
+	if !ok || !known || name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("invalid workload %q, KIND/NAME is expected, e.g. deploy/checkout, sts/db, ds/agent, rs/checkout-7d4f9 or svc/payments", ref)
+	}
+
+	return &Workload{Ref: ref, Kind: kind, Name: name}, nil
+}
+
+// ParseWorkloads parses the workloads, the invalid ones are skipped as these are reported by TapConfig.Validate.
+func ParseWorkloads(refs []string) (workloads []*Workload) {
+	for _, ref := range refs {
+		if workload, err := ParseWorkload(ref); err == nil {
+			workloads = append(workloads, workload)
+		}
+	}
+
+	return
+}
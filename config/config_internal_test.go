@@ -1,26 +1,43 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/creasty/defaults"
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/kubeshark/kubeshark/config/configStructs"
+	"github.com/spf13/cobra"
 )
 
 type ConfigMock struct {
-	SectionMock      SectionMock `yaml:"section"`
-	Test             string      `yaml:"test"`
-	StringField      string      `yaml:"string-field"`
-	IntField         int         `yaml:"int-field"`
-	BoolField        bool        `yaml:"bool-field"`
-	UintField        uint        `yaml:"uint-field"`
-	StringSliceField []string    `yaml:"string-slice-field"`
-	IntSliceField    []int       `yaml:"int-slice-field"`
-	BoolSliceField   []bool      `yaml:"bool-slice-field"`
-	UintSliceField   []uint      `yaml:"uint-slice-field"`
+	SectionMock      SectionMock            `yaml:"section"`
+	Test             string                 `yaml:"test"`
+	StringField      string                 `yaml:"string-field"`
+	IntField         int                    `yaml:"int-field"`
+	BoolField        bool                   `yaml:"bool-field"`
+	UintField        uint                   `yaml:"uint-field"`
+	StringSliceField []string               `yaml:"string-slice-field"`
+	IntSliceField    []int                  `yaml:"int-slice-field"`
+	BoolSliceField   []bool                 `yaml:"bool-slice-field"`
+	UintSliceField   []uint                 `yaml:"uint-slice-field"`
+	MapField         map[string]string      `yaml:"map-field"`
+	StructMapField   map[string]SectionMock `yaml:"struct-map-field"`
+	StructSliceField []SectionMock          `yaml:"struct-slice-field"`
+	PointerField     *SectionMock           `yaml:"pointer-field"`
+	AnyMapField      map[string]interface{} `yaml:"any-map-field"`
 }
 
 type SectionMock struct {
-	Test string `yaml:"test"`
+	Test      string   `yaml:"test" json:"test"`
+	IntField  int      `yaml:"int-field" json:"int-field"`
+	SliceTest []string `yaml:"slice-test" json:"slice-test"`
 }
 
 type FieldSetValues struct {
@@ -148,7 +165,7 @@ func TestMergeSetFlagNotSliceValues(t *testing.T) {
 		{Name: "int field", FieldsSetValues: []FieldSetValues{{SetValues: []string{"int-field=6"}, FieldName: "IntField", FieldValue: 6}}},
 		{Name: "bool field", FieldsSetValues: []FieldSetValues{{SetValues: []string{"bool-field=true"}, FieldName: "BoolField", FieldValue: true}}},
 		{Name: "uint field", FieldsSetValues: []FieldSetValues{{SetValues: []string{"uint-field=6"}, FieldName: "UintField", FieldValue: uint(6)}}},
-		{Name: "four fields combined", FieldsSetValues: []FieldSetValues {
+		{Name: "four fields combined", FieldsSetValues: []FieldSetValues{
 			{SetValues: []string{"string-field=test"}, FieldName: "StringField", FieldValue: "test"},
 			{SetValues: []string{"int-field=6"}, FieldName: "IntField", FieldValue: 6},
 			{SetValues: []string{"bool-field=true"}, FieldName: "BoolField", FieldValue: true},
@@ -321,6 +338,8 @@ func TestGetParsedValueValidValue(t *testing.T) {
 		{StringValue: "66", Kind: reflect.Uint32, ActualValue: uint32(66)},
 		{StringValue: "6", Kind: reflect.Uint64, ActualValue: uint64(6)},
 		{StringValue: "66", Kind: reflect.Uint64, ActualValue: uint64(66)},
+		{StringValue: "1.5", Kind: reflect.Float64, ActualValue: 1.5},
+		{StringValue: "-6", Kind: reflect.Float32, ActualValue: float32(-6)},
 	}
 
 	for _, test := range tests {
@@ -366,6 +385,8 @@ func TestGetParsedValueInvalidValue(t *testing.T) {
 		{StringValue: "-6", Kind: reflect.Uint32},
 		{StringValue: "test", Kind: reflect.Uint64},
 		{StringValue: "-6", Kind: reflect.Uint64},
+		{StringValue: "test", Kind: reflect.Float64},
+		{StringValue: "true", Kind: reflect.Float32},
 	}
 
 	for _, test := range tests {
@@ -383,3 +404,1036 @@ func TestGetParsedValueInvalidValue(t *testing.T) {
 		})
 	}
 }
+
+func TestMergeSetFlagPathValues(t *testing.T) {
+	tests := []struct {
+		Name            string
+		FieldsSetValues []FieldSetValues
+	}{
+		{Name: "map key", FieldsSetValues: []FieldSetValues{{SetValues: []string{"map-field.team=test"}, FieldName: "MapField", FieldValue: map[string]string{"team": "test"}}}},
+		{Name: "escaped map key", FieldsSetValues: []FieldSetValues{{SetValues: []string{"map-field.example\\.com/team=test"}, FieldName: "MapField", FieldValue: map[string]string{"example.com/team": "test"}}}},
+		{Name: "two map keys", FieldsSetValues: []FieldSetValues{{SetValues: []string{"map-field.a=1", "map-field.b=2"}, FieldName: "MapField", FieldValue: map[string]string{"a": "1", "b": "2"}}}},
+		{Name: "struct map value fields", FieldsSetValues: []FieldSetValues{{SetValues: []string{"struct-map-field.viewer.test=test", "struct-map-field.viewer.int-field=6"}, FieldName: "StructMapField", FieldValue: map[string]SectionMock{"viewer": {Test: "test", IntField: 6}}}}},
+		{Name: "struct slice element field", FieldsSetValues: []FieldSetValues{{SetValues: []string{"struct-slice-field[0].test=test"}, FieldName: "StructSliceField", FieldValue: []SectionMock{{Test: "test"}}}}},
+		{Name: "struct slice extended up to the index", FieldsSetValues: []FieldSetValues{{SetValues: []string{"struct-slice-field[1].int-field=6"}, FieldName: "StructSliceField", FieldValue: []SectionMock{{}, {IntField: 6}}}}},
+		{Name: "struct slice element slice field", FieldsSetValues: []FieldSetValues{{SetValues: []string{"struct-slice-field[0].slice-test=a", "struct-slice-field[0].slice-test=b"}, FieldName: "StructSliceField", FieldValue: []SectionMock{{SliceTest: []string{"a", "b"}}}}}},
+		{Name: "nested slice element", FieldsSetValues: []FieldSetValues{{SetValues: []string{"struct-slice-field[0].slice-test[1]=b"}, FieldName: "StructSliceField", FieldValue: []SectionMock{{SliceTest: []string{"", "b"}}}}}},
+		{Name: "scalar slice element", FieldsSetValues: []FieldSetValues{{SetValues: []string{"int-slice-field[0]=6"}, FieldName: "IntSliceField", FieldValue: []int{6}}}},
+		{Name: "pointer field", FieldsSetValues: []FieldSetValues{{SetValues: []string{"pointer-field.test=test"}, FieldName: "PointerField", FieldValue: &SectionMock{Test: "test"}}}},
+		{Name: "interface map values", FieldsSetValues: []FieldSetValues{{SetValues: []string{"any-map-field.a=test", "any-map-field.b=6", "any-map-field.c=true", "any-map-field.d.e=test"}, FieldName: "AnyMapField", FieldValue: map[string]interface{}{"a": "test", "b": int64(6), "c": true, "d": map[string]interface{}{"e": "test"}}}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			configMock := ConfigMock{}
+			configMockElemValue := reflect.ValueOf(&configMock).Elem()
+
+			var setValues []string
+			for _, fieldSetValues := range test.FieldsSetValues {
+				setValues = append(setValues, fieldSetValues.SetValues...)
+			}
+
+			err := mergeSetFlag(configMockElemValue, setValues)
+
+			if err != nil {
+				t.Errorf("unexpected error result - err: %v", err)
+				return
+			}
+
+			for _, fieldSetValues := range test.FieldsSetValues {
+				fieldValue := configMockElemValue.FieldByName(fieldSetValues.FieldName).Interface()
+				if !reflect.DeepEqual(fieldValue, fieldSetValues.FieldValue) {
+					t.Errorf("unexpected result - expected: %v, actual: %v", fieldSetValues.FieldValue, fieldValue)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeSetFlagInvalidPath(t *testing.T) {
+	tests := []struct {
+		Name      string
+		SetValues []string
+	}{
+		{Name: "slice field without index", SetValues: []string{"struct-slice-field.test=test"}},
+		{Name: "negative index", SetValues: []string{"struct-slice-field[-1].test=test"}},
+		{Name: "invalid index", SetValues: []string{"struct-slice-field[a].test=test"}},
+		{Name: "unclosed index", SetValues: []string{"struct-slice-field[0.test=test"}},
+		{Name: "name after index", SetValues: []string{"struct-slice-field[0]test=test"}},
+		{Name: "index of a struct", SetValues: []string{"section[0]=test"}},
+		{Name: "empty name", SetValues: []string{"section..test=test"}},
+		{Name: "invalid field of map value", SetValues: []string{"struct-map-field.viewer.invalid_flag=test"}},
+		{Name: "invalid value of map value", SetValues: []string{"struct-map-field.viewer.int-field=test"}},
+		{Name: "invalid value of slice element", SetValues: []string{"struct-slice-field[2].int-field=test"}},
+		{Name: "invalid value of pointer field", SetValues: []string{"pointer-field.int-field=test"}},
+		{Name: "map value is a struct", SetValues: []string{"struct-map-field.viewer=test"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			configMock := ConfigMock{}
+			configMockElemValue := reflect.ValueOf(&configMock).Elem()
+
+			err := mergeSetFlag(configMockElemValue, test.SetValues)
+
+			if err == nil {
+				t.Errorf("unexpected unhandled error - SetValues: %v", test.SetValues)
+				return
+			}
+
+			for i := 0; i < configMockElemValue.NumField(); i++ {
+				currentField := configMockElemValue.Type().Field(i)
+				currentFieldByName := configMockElemValue.FieldByName(currentField.Name)
+
+				if !currentFieldByName.IsZero() {
+					t.Errorf("unexpected case - SetValues: %v", test.SetValues)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeSetJsonFlag(t *testing.T) {
+	tests := []struct {
+		Name            string
+		FieldsSetValues []FieldSetValues
+	}{
+		{Name: "map", FieldsSetValues: []FieldSetValues{{SetValues: []string{`map-field={"a":"1","b":"2"}`}, FieldName: "MapField", FieldValue: map[string]string{"a": "1", "b": "2"}}}},
+		{Name: "map keys are merged", FieldsSetValues: []FieldSetValues{{SetValues: []string{`map-field={"a":"1"}`, `map-field={"b":"2"}`}, FieldName: "MapField", FieldValue: map[string]string{"a": "1", "b": "2"}}}},
+		{Name: "struct slice", FieldsSetValues: []FieldSetValues{{SetValues: []string{`struct-slice-field=[{"test":"test","slice-test":["a","b"]}]`}, FieldName: "StructSliceField", FieldValue: []SectionMock{{Test: "test", SliceTest: []string{"a", "b"}}}}}},
+		{Name: "struct fields are merged", FieldsSetValues: []FieldSetValues{{SetValues: []string{`section={"test":"test"}`, `section={"int-field":6}`}, FieldName: "SectionMock", FieldValue: SectionMock{Test: "test", IntField: 6}}}},
+		{Name: "slice element", FieldsSetValues: []FieldSetValues{{SetValues: []string{`struct-slice-field[1]={"int-field":6}`}, FieldName: "StructSliceField", FieldValue: []SectionMock{{}, {IntField: 6}}}}},
+		{Name: "scalar", FieldsSetValues: []FieldSetValues{{SetValues: []string{`int-field=6`, `string-field="test"`}, FieldName: "IntField", FieldValue: 6}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			configMock := ConfigMock{}
+			configMockElemValue := reflect.ValueOf(&configMock).Elem()
+
+			var setValues []string
+			for _, fieldSetValues := range test.FieldsSetValues {
+				setValues = append(setValues, fieldSetValues.SetValues...)
+			}
+
+			err := mergeSetJsonFlag(configMockElemValue, setValues)
+
+			if err != nil {
+				t.Errorf("unexpected error result - err: %v", err)
+				return
+			}
+
+			for _, fieldSetValues := range test.FieldsSetValues {
+				fieldValue := configMockElemValue.FieldByName(fieldSetValues.FieldName).Interface()
+				if !reflect.DeepEqual(fieldValue, fieldSetValues.FieldValue) {
+					t.Errorf("unexpected result - expected: %v, actual: %v", fieldSetValues.FieldValue, fieldValue)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeSetJsonFlagInvalidValue(t *testing.T) {
+	tests := []struct {
+		Name      string
+		SetValues []string
+	}{
+		{Name: "no separator", SetValues: []string{`map-field`}},
+		{Name: "invalid JSON", SetValues: []string{`map-field={"a":`}},
+		{Name: "invalid map value type", SetValues: []string{`map-field={"a":"1","b":2}`}},
+		{Name: "invalid struct field type", SetValues: []string{`struct-slice-field=[{"int-field":"test"}]`}},
+		{Name: "invalid flag name", SetValues: []string{`invalid_flag={}`}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			configMock := ConfigMock{}
+			configMockElemValue := reflect.ValueOf(&configMock).Elem()
+
+			err := mergeSetJsonFlag(configMockElemValue, test.SetValues)
+
+			if err == nil {
+				t.Errorf("unexpected unhandled error - SetValues: %v", test.SetValues)
+				return
+			}
+
+			for i := 0; i < configMockElemValue.NumField(); i++ {
+				currentField := configMockElemValue.Type().Field(i)
+				currentFieldByName := configMockElemValue.FieldByName(currentField.Name)
+
+				if !currentFieldByName.IsZero() {
+					t.Errorf("unexpected case - SetValues: %v", test.SetValues)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeSetFileFlag(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "test.pem")
+	if err := os.WriteFile(filePath, []byte("line1\nline2\n"), 0644); err != nil {
+		t.Fatalf("failed writing file - err: %v", err)
+	}
+
+	configMock := ConfigMock{}
+	configMockElemValue := reflect.ValueOf(&configMock).Elem()
+
+	if err := mergeSetFileFlag(configMockElemValue, []string{fmt.Sprintf("map-field.cert=%s", filePath)}); err != nil {
+		t.Errorf("unexpected error result - err: %v", err)
+		return
+	}
+
+	if expected := map[string]string{"cert": "line1\nline2\n"}; !reflect.DeepEqual(configMock.MapField, expected) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, configMock.MapField)
+	}
+
+	if err := mergeSetFileFlag(configMockElemValue, []string{fmt.Sprintf("string-field=%s.missing", filePath)}); err == nil {
+		t.Errorf("unexpected unhandled error - missing file")
+	}
+}
+
+func TestParseFlagPath(t *testing.T) {
+	tests := []struct {
+		FlagName string
+		FlagPath []string
+	}{
+		{FlagName: "a", FlagPath: []string{"a"}},
+		{FlagName: "a.b.c", FlagPath: []string{"a", "b", "c"}},
+		{FlagName: "a[0].b", FlagPath: []string{"a", "[0]", "b"}},
+		{FlagName: "a[0][1]", FlagPath: []string{"a", "[0]", "[1]"}},
+		{FlagName: "a.b\\.c/d", FlagPath: []string{"a", "b.c/d"}},
+		{FlagName: "a.b\\[0]", FlagPath: []string{"a", "b[0]"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.FlagName, func(t *testing.T) {
+			flagPath, err := parseFlagPath(test.FlagName)
+
+			if err != nil {
+				t.Errorf("unexpected error result - err: %v", err)
+				return
+			}
+
+			if !reflect.DeepEqual(flagPath, test.FlagPath) {
+				t.Errorf("unexpected result - expected: %v, actual: %v", test.FlagPath, flagPath)
+			}
+		})
+	}
+}
+
+func TestResolveSecret(t *testing.T) {
+	t.Setenv("SECRET_MOCK", "env-secret")
+
+	filePath := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(filePath, []byte("file-secret\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	lookup := func(namespace string, name string, key string) (string, error) {
+		return fmt.Sprintf("%s/%s/%s", namespace, name, key), nil
+	}
+
+	tests := []struct {
+		Value         string
+		ExpectedValue string
+	}{
+		{Value: "plain", ExpectedValue: "plain"},
+		{Value: "", ExpectedValue: ""},
+		{Value: "env:SECRET_MOCK", ExpectedValue: "env-secret"},
+		{Value: fmt.Sprintf("file:%s", filePath), ExpectedValue: "file-secret"},
+		{Value: "k8s:ns/name#key", ExpectedValue: "ns/name/key"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Value, func(t *testing.T) {
+			value, err := ResolveSecret(test.Value, lookup)
+			if err != nil {
+				t.Errorf("unexpected error result - err: %v", err)
+				return
+			}
+
+			if value != test.ExpectedValue {
+				t.Errorf("unexpected result - expected: %v, actual: %v", test.ExpectedValue, value)
+			}
+		})
+	}
+}
+
+func TestResolveSecretInvalidRef(t *testing.T) {
+	tests := []string{
+		"env:SECRET_MOCK_NOT_SET",
+		"file:/not/existing/secret",
+		"k8s:name#key",
+		"k8s:ns/name",
+	}
+
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			if _, err := ResolveSecret(test, nil); err == nil {
+				t.Errorf("unexpected result - expected error for %v", test)
+			}
+		})
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	config := ConfigStruct{License: "plain"}
+	config.Tap.Auth.Saml.X509key = "env:SECRET_MOCK"
+	config.Tap.Auth.Saml.X509crt = "crt"
+	config.Profiles = map[string]interface{}{
+		"prod": map[string]interface{}{"license": "plain"},
+	}
+
+	redacted := RedactSecrets(config)
+
+	if redacted.License != RedactedValue {
+		t.Errorf("unexpected license - expected: %v, actual: %v", RedactedValue, redacted.License)
+	}
+
+	if redacted.Tap.Auth.Saml.X509key != config.Tap.Auth.Saml.X509key {
+		t.Errorf("unexpected reference - expected: %v, actual: %v", config.Tap.Auth.Saml.X509key, redacted.Tap.Auth.Saml.X509key)
+	}
+
+	if redacted.Tap.Auth.Saml.X509crt != config.Tap.Auth.Saml.X509crt {
+		t.Errorf("unexpected value - expected: %v, actual: %v", config.Tap.Auth.Saml.X509crt, redacted.Tap.Auth.Saml.X509crt)
+	}
+
+	if license := redacted.Profiles["prod"].(map[string]interface{})["license"]; license != RedactedValue {
+		t.Errorf("unexpected profile license - expected: %v, actual: %v", RedactedValue, license)
+	}
+
+	if config.License != "plain" || config.Profiles["prod"].(map[string]interface{})["license"] != "plain" {
+		t.Errorf("unexpected change of the original config")
+	}
+}
+
+func TestCheckUnknownKeys(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Data     string
+		Expected []string
+	}{
+		{Name: "known keys", Data: "test: a\nsection:\n  test: b\n", Expected: nil},
+		{Name: "unknown top level key", Data: "test: a\nunknown: b\n", Expected: []string{`[2:1] unknown field "unknown"`}},
+		{Name: "unknown nested key", Data: "section:\n  test: a\n  unknown: b\n", Expected: []string{`[3:3] unknown field "section.unknown"`}},
+		{Name: "unknown key of a struct in a map", Data: "struct-map-field:\n  a:\n    unknown: b\n", Expected: []string{`[3:5] unknown field "struct-map-field.a.unknown"`}},
+		{Name: "unknown key of a struct in a slice", Data: "struct-slice-field:\n  - test: a\n  - unknown: b\n", Expected: []string{`[3:5] unknown field "struct-slice-field[1].unknown"`}},
+		{Name: "unknown key of a pointer", Data: "pointer-field:\n  unknown: b\n", Expected: []string{`[2:3] unknown field "pointer-field.unknown"`}},
+		{Name: "any keys of an any map", Data: "any-map-field:\n  a:\n    b: c\n", Expected: nil},
+		{Name: "several unknown keys", Data: "unknown: a\nsection:\n  unknown: b\n", Expected: []string{`[1:1] unknown field "unknown"`, `[3:3] unknown field "section.unknown"`}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := checkUnknownKeys([]byte(test.Data), reflect.TypeOf(ConfigMock{}), nil)
+
+			if len(test.Expected) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error - expected: nil, actual: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("unexpected result - expected: %v, actual: nil", test.Expected)
+			}
+
+			if actual := strings.Split(err.Error(), "\n"); !reflect.DeepEqual(actual, test.Expected) {
+				t.Errorf("unexpected errors - expected: %v, actual: %v", test.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFileUnknownKeys(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(filePath, []byte("headless: true\nstale: true\ntap:\n  stale: true\n"), 0644); err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+
+	config := CreateDefaultConfig()
+	if _, err := loadConfigFile(&config, filePath, "", true); err != nil {
+		t.Fatalf("unexpected error - expected: nil, actual: %v", err)
+	}
+
+	if !config.HeadlessMode {
+		t.Errorf("unexpected headless - expected: true, actual: false")
+	}
+
+	data, _ := os.ReadFile(filePath)
+	if err := ValidateConfigData(data); err == nil || !strings.Contains(err.Error(), `[2:1] unknown field "stale"`) {
+		t.Errorf("unexpected validation error - expected: the unknown field, actual: %v", err)
+	}
+}
+
+func defaultTapConfig(t *testing.T) configStructs.TapConfig {
+	config := CreateDefaultConfig()
+	if err := defaults.Set(&config); err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+
+	return config.Tap
+}
+
+func TestTapConfigValidate(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Modify   func(config *configStructs.TapConfig)
+		Expected []string
+	}{
+		{Name: "defaults", Modify: func(config *configStructs.TapConfig) {}},
+		{Name: "known enum value", Modify: func(config *configStructs.TapConfig) { config.Docker.ImagePullPolicy = "Never" }},
+		{
+			Name:     "unknown enum value",
+			Modify:   func(config *configStructs.TapConfig) { config.Docker.ImagePullPolicy = "Sometimes" },
+			Expected: []string{`tap.docker.imagePullPolicy: unknown value "Sometimes"`},
+		},
+		{Name: "unknown dissector is only warned about", Modify: func(config *configStructs.TapConfig) { config.EnabledDissectors = []string{"http", "gopher"} }},
+		{
+			Name:     "unknown export format",
+			Modify:   func(config *configStructs.TapConfig) { config.Export.Formats = []string{"pcap", "csv"} },
+			Expected: []string{`tap.export.formats[1]: unknown value "csv"`},
+		},
+		{Name: "valid quantity", Modify: func(config *configStructs.TapConfig) { config.Resources.Hub.Limits.Memory = "1Gi" }},
+		{
+			Name:     "invalid storage limit",
+			Modify:   func(config *configStructs.TapConfig) { config.StorageLimit = "lots" },
+			Expected: []string{`tap.storageLimit: invalid quantity "lots"`},
+		},
+		{
+			Name:     "invalid resource quantity",
+			Modify:   func(config *configStructs.TapConfig) { config.Resources.Sniffer.Requests.CPU = "fast" },
+			Expected: []string{`tap.resources.sniffer.requests.cpu: invalid quantity "fast"`},
+		},
+		{
+			Name:     "zero port",
+			Modify:   func(config *configStructs.TapConfig) { config.Metrics.Port = 0 },
+			Expected: []string{"tap.metrics.port: the port must not be 0"},
+		},
+		{
+			Name: "colliding proxy ports",
+			Modify: func(config *configStructs.TapConfig) {
+				config.Proxy.Hub.SrvPort = config.Proxy.Front.Port
+			},
+			Expected: []string{"tap.proxy.front.port and tap.proxy.hub.srvPort collide"},
+		},
+		{
+			Name: "colliding worker ports",
+			Modify: func(config *configStructs.TapConfig) {
+				config.Metrics.Port = config.Proxy.Worker.SrvPort
+			},
+			Expected: []string{"tap.proxy.worker.srvPort and tap.metrics.port collide"},
+		},
+		{
+			Name: "disabled auth is not checked",
+			Modify: func(config *configStructs.TapConfig) {
+				config.Auth.Type = configStructs.AuthTypeOidc
+			},
+		},
+		{
+			Name: "valid SAML auth",
+			Modify: func(config *configStructs.TapConfig) {
+				config.Auth.Enabled = true
+				config.Auth.Saml.IdpMetadataUrl = "https://idp.example.com/metadata"
+				config.Auth.Saml.RoleAttribute = "role"
+			},
+		},
+		{
+			Name: "invalid SAML auth",
+			Modify: func(config *configStructs.TapConfig) {
+				config.Auth.Enabled = true
+				config.Auth.Saml.IdpMetadataUrl = "idp.example.com"
+				config.Auth.Saml.RoleAttribute = ""
+				config.Auth.Saml.X509crt = "crt"
+			},
+			Expected: []string{
+				"tap.auth.saml.idpMetadataUrl: an http(s) URL is required",
+				"tap.auth.saml.roleAttribute: required",
+				"tap.auth.saml.x509crt and tap.auth.saml.x509key must be set together",
+			},
+		},
+		{
+			Name: "invalid OIDC auth",
+			Modify: func(config *configStructs.TapConfig) {
+				config.Auth.Enabled = true
+				config.Auth.Type = configStructs.AuthTypeOidc
+				config.Auth.Oidc = configStructs.OidcConfig{Issuer: "https://issuer.example.com"}
+			},
+			Expected: []string{
+				"tap.auth.oidc.clientId: required",
+				"tap.auth.oidc.clientSecretRef.name: required",
+				"tap.auth.oidc.clientSecretRef.key: required",
+				"tap.auth.oidc.rolesClaim: required",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			config := defaultTapConfig(t)
+			test.Modify(&config)
+
+			err := config.Validate()
+
+			if len(test.Expected) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error - expected: nil, actual: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("unexpected result - expected: %v, actual: nil", test.Expected)
+			}
+
+			for _, expected := range test.Expected {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("unexpected error - expected to contain: %v, actual: %v", expected, err)
+				}
+			}
+		})
+	}
+}
+
+func TestSchema(t *testing.T) {
+	schema, err := Schema()
+	if err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+
+	property := func(schema map[string]interface{}, path ...string) map[string]interface{} {
+		for _, name := range path {
+			properties, ok := schema["properties"].(map[string]interface{})
+			if !ok {
+				t.Fatalf("unexpected schema - no properties at %v", name)
+			}
+			if schema, ok = properties[name].(map[string]interface{}); !ok {
+				t.Fatalf("unexpected schema - no property %v", name)
+			}
+		}
+		return schema
+	}
+
+	tests := []struct {
+		Name     string
+		Path     []string
+		Key      string
+		Expected interface{}
+	}{
+		{Name: "draft", Key: "$schema", Expected: schemaDraft},
+		{Name: "closed objects", Path: []string{"tap", "docker"}, Key: "additionalProperties", Expected: false},
+		{Name: "string type", Path: []string{"tap", "docker", "registry"}, Key: "type", Expected: "string"},
+		{Name: "string default", Path: []string{"tap", "docker", "registry"}, Key: "default", Expected: "docker.io/kubeshark"},
+		{Name: "boolean default", Path: []string{"tap", "tls"}, Key: "default", Expected: true},
+		{Name: "enum", Path: []string{"tap", "docker", "imagePullPolicy"}, Key: "enum", Expected: []string{"Always", "IfNotPresent", "Never"}},
+		{Name: "port maximum", Path: []string{"tap", "metrics", "port"}, Key: "maximum", Expected: 65535},
+		{Name: "port minimum", Path: []string{"tap", "metrics", "port"}, Key: "minimum", Expected: 0},
+		{Name: "array type", Path: []string{"tap", "namespaces"}, Key: "type", Expected: "array"},
+		{Name: "map type", Path: []string{"tap", "labels"}, Key: "type", Expected: "object"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			actual := property(schema, test.Path...)[test.Key]
+			if !reflect.DeepEqual(actual, test.Expected) {
+				t.Errorf("unexpected %v - expected: %v, actual: %v", test.Key, test.Expected, actual)
+			}
+		})
+	}
+
+	dissectors := property(schema, "tap", "enabledDissectors")
+	if items, ok := dissectors["items"].(map[string]interface{}); !ok || items["enum"] != nil || !reflect.DeepEqual(items["examples"], strings.Split("amqp,dns,http,icmp,kafka,redis,sctp,syscall,tcp,udp,ws,tlsx,ldap,radius,diameter", ",")) {
+		t.Errorf("unexpected enabledDissectors items - expected: the examples, actual: %v", dissectors["items"])
+	}
+}
+
+// initTestConfig initializes the config of the console command, in a home and a working directory that have the
+// given global and project files, and with the given --config file, if these are not empty.
+func initTestConfig(t *testing.T, globalFile string, projectFile string, configFile string, args ...string) error {
+	home, cwd := t.TempDir(), t.TempDir()
+	t.Setenv("HOME", home)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+	if err := os.Chdir(cwd); err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	for filePath, data := range map[string]string{
+		filepath.Join(home, ".kubeshark", "config.yaml"): globalFile,
+		filepath.Join(cwd, "kubeshark.yaml"):             projectFile,
+		filepath.Join(cwd, "custom.yaml"):                configFile,
+	} {
+		if data == "" {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(filePath), 0700); err != nil {
+			t.Fatalf("unexpected error - %v", err)
+		}
+		if err := os.WriteFile(filePath, []byte(data), 0644); err != nil {
+			t.Fatalf("unexpected error - %v", err)
+		}
+	}
+
+	cmd := &cobra.Command{Use: "console"}
+	cmd.Flags().Bool(DebugFlag, false, "")
+	cmd.Flags().StringSlice(ConfigFilesFlag, []string{}, "")
+	cmd.Flags().String(ProfileFlag, "", "")
+	cmd.Flags().StringSlice(SetCommandName, []string{}, "")
+	cmd.Flags().String("docker-tag", "", "")
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+
+	return InitConfig(cmd)
+}
+
+func TestInitConfigPrecedence(t *testing.T) {
+	t.Setenv("KUBESHARK_TAP_GLOBALFILTER", "env")
+	t.Setenv("KUBESHARK_TAP_BPFOVERRIDE", "env")
+	t.Setenv("KUBESHARK_TAP_DOCKER_TAG", "env")
+
+	err := initTestConfig(t,
+		"tap:\n  docker:\n    registry: global\n    tag: global\n  storageClass: global\n  defaultFilter: global\n  globalFilter: global\n  bpfOverride: global\n",
+		"tap:\n  docker:\n    tag: project\n  storageClass: project\n  defaultFilter: project\n  globalFilter: project\n  bpfOverride: project\n",
+		"tap:\n  docker:\n    tag: config\n  defaultFilter: config\n  globalFilter: config\n  bpfOverride: config\n",
+		"--config", "custom.yaml", "--set", "tap.bpfOverride=set", "--set", "tap.docker.tag=set", "--docker-tag", "flag",
+	)
+	if err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+
+	cwd, _ := os.Getwd()
+	loaded := []string{filepath.Join(os.Getenv("HOME"), ".kubeshark", "config.yaml"), filepath.Join(cwd, "kubeshark.yaml"), "custom.yaml"}
+	if !reflect.DeepEqual(LoadedConfigFiles, loaded) {
+		t.Errorf("unexpected loaded config files - expected: %v, actual: %v", loaded, LoadedConfigFiles)
+	}
+
+	tests := []struct {
+		Path   string
+		Value  string
+		Source string
+	}{
+		{Path: "tap.docker.registry", Value: "global", Source: filepath.Join(os.Getenv("HOME"), ".kubeshark", "config.yaml") + ":3"},
+		{Path: "tap.storageClass", Value: "project", Source: filepath.Join(cwd, "kubeshark.yaml") + ":4"},
+		{Path: "tap.defaultFilter", Value: "config", Source: "custom.yaml:4"},
+		{Path: "tap.globalFilter", Value: "env", Source: "env KUBESHARK_TAP_GLOBALFILTER"},
+		{Path: "tap.bpfOverride", Value: "set", Source: "--set"},
+		{Path: "tap.docker.tag", Value: "flag", Source: "--docker-tag"},
+		{Path: "tap.docker.imagePullPolicy", Value: "Always", Source: DefaultSource},
+	}
+
+	explained := make(map[string]*ExplainedValue)
+	for _, value := range ExplainConfig() {
+		explained[value.Path] = value
+	}
+
+	for _, test := range tests {
+		t.Run(test.Path, func(t *testing.T) {
+			value, ok := explained[test.Path]
+			if !ok {
+				t.Fatalf("unexpected explanation - %v is not explained", test.Path)
+			}
+
+			if expected := fmt.Sprintf("%q", test.Value); value.Value != expected {
+				t.Errorf("unexpected value - expected: %v, actual: %v", expected, value.Value)
+			}
+
+			if value.Source != test.Source {
+				t.Errorf("unexpected source - expected: %v, actual: %v", test.Source, value.Source)
+			}
+		})
+	}
+}
+
+func TestInitConfigProfile(t *testing.T) {
+	err := initTestConfig(t,
+		"tap:\n  docker:\n    tag: global\nprofiles:\n  prod:\n    tap:\n      docker:\n        tag: prod\n",
+		"tap:\n  docker:\n    tag: project\n    registry: project\n",
+		"",
+		"--profile", "prod",
+	)
+	if err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+
+	// The profile is applied on top of all the files, even the ones after the file that defines it.
+	if Config.Tap.Docker.Tag != "prod" {
+		t.Errorf("unexpected tag - expected: prod, actual: %v", Config.Tap.Docker.Tag)
+	}
+
+	if Config.Tap.Docker.Registry != "project" {
+		t.Errorf("unexpected registry - expected: project, actual: %v", Config.Tap.Docker.Registry)
+	}
+}
+
+func TestInitConfigMissingProfile(t *testing.T) {
+	err := initTestConfig(t,
+		"profiles:\n  prod:\n    tap:\n      docker:\n        tag: prod\n",
+		"",
+		"",
+		"--profile", "staging",
+	)
+
+	if err == nil || !strings.Contains(err.Error(), `the profile "staging" is not defined`) {
+		t.Errorf("unexpected error - expected: the profile is not defined, actual: %v", err)
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Env      map[string]string
+		Expected []string
+		Err      string
+	}{
+		{Name: "list", Env: map[string]string{"KUBESHARK_TAP_NAMESPACES": "a,b"}, Expected: []string{"a", "b"}},
+		{Name: "single item list", Env: map[string]string{"KUBESHARK_TAP_NAMESPACES": "a"}, Expected: []string{"a"}},
+		{Name: "empty list", Env: map[string]string{"KUBESHARK_TAP_NAMESPACES": ""}, Expected: []string{}},
+		{Name: "map", Env: map[string]string{"KUBESHARK_TAP_LABELS": "a=b"}, Err: "tap.labels cannot be set by an environment variable"},
+		{Name: "invalid scalar", Env: map[string]string{"KUBESHARK_TAP_TLS": "maybe"}, Err: "KUBESHARK_TAP_TLS"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			for name, value := range test.Env {
+				t.Setenv(name, value)
+			}
+
+			config := CreateDefaultConfig()
+			config.Tap.Namespaces = []string{"default"}
+			err := loadEnv(&config)
+
+			if test.Err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.Err) {
+					t.Errorf("unexpected error - expected: %v, actual: %v", test.Err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error - %v", err)
+			}
+
+			if !reflect.DeepEqual(config.Tap.Namespaces, test.Expected) {
+				t.Errorf("unexpected namespaces - expected: %v, actual: %v", test.Expected, config.Tap.Namespaces)
+			}
+		})
+	}
+}
+
+func parseTestConfigFile(t *testing.T, data string) *ConfigFile {
+	file, err := parser.ParseBytes([]byte(data), parser.ParseComments)
+	if err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+
+	return &ConfigFile{file: file}
+}
+
+func TestConfigFileSet(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Data     string
+		Path     string
+		Value    interface{}
+		Expected string
+		Err      string
+	}{
+		{Name: "existing key", Data: "tap:\n  docker:\n    tag: a # keep\n", Path: "tap.docker.tag", Value: "b", Expected: "tap:\n  docker:\n    tag: b # keep\n"},
+		{Name: "new key", Data: "tap:\n  docker:\n    tag: a\n", Path: "tap.docker.registry", Value: "r", Expected: "tap:\n  docker:\n    tag: a\n    registry: r\n"},
+		{Name: "new nested keys", Data: "headless: true\n", Path: "tap.docker.tag", Value: "b", Expected: "headless: true\ntap:\n  docker:\n    tag: b\n"},
+		{Name: "empty file", Data: "", Path: "tap.docker.tag", Value: "b", Expected: "tap:\n  docker:\n    tag: b\n"},
+		{Name: "existing key in flow style", Data: "tap: {docker: {tag: a}}\n", Path: "tap.docker.tag", Value: "b", Expected: "tap: {docker: {tag: b}}\n"},
+		{Name: "new key in flow style", Data: "tap: {docker: {tag: a}}\n", Path: "tap.docker.registry", Value: "r", Expected: "tap: {docker: {tag: a, registry: r}}\n"},
+		{Name: "list index in flow style", Data: "tap:\n  namespaces: [a, b]\n", Path: "tap.namespaces[1]", Value: "c", Expected: "tap:\n  namespaces: [a, c]\n"},
+		{Name: "list index append", Data: "tap:\n  namespaces:\n    - a\n    - b\n", Path: "tap.namespaces[2]", Value: "c", Expected: "tap:\n  namespaces:\n    - a\n    - b\n    - c\n"},
+		{Name: "list index out of range", Data: "tap:\n  namespaces:\n    - a\n", Path: "tap.namespaces[3]", Value: "c", Err: "index 3 is out of range"},
+		{Name: "whole list", Data: "tap:\n  namespaces:\n    - a\n", Path: "tap.namespaces", Value: []string{"x", "y"}, Expected: "tap:\n  namespaces:\n  - x\n  - \"y\"\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			configFile := parseTestConfigFile(t, test.Data)
+			flagPath, err := parseFlagPath(test.Path)
+			if err != nil {
+				t.Fatalf("unexpected error - %v", err)
+			}
+
+			err = configFile.Set(flagPath, test.Value)
+
+			if test.Err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.Err) {
+					t.Errorf("unexpected error - expected: %v, actual: %v", test.Err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error - %v", err)
+			}
+
+			if actual := string(configFile.Bytes()); actual != test.Expected {
+				t.Errorf("unexpected config file - expected: %q, actual: %q", test.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestConfigFileUnset(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Data     string
+		Path     string
+		Found    bool
+		Expected string
+	}{
+		{Name: "only key", Data: "tap:\n  docker:\n    tag: a\n", Path: "tap.docker.tag", Found: true, Expected: ""},
+		{Name: "sibling key is kept", Data: "tap:\n  docker:\n    tag: a\n    registry: r\n", Path: "tap.docker.tag", Found: true, Expected: "tap:\n  docker:\n    registry: r\n"},
+		{Name: "empty parents are removed", Data: "headless: true\ntap:\n  docker:\n    tag: a\n", Path: "tap.docker.tag", Found: true, Expected: "headless: true\n"},
+		{Name: "missing key", Data: "tap:\n  docker:\n    tag: a\n", Path: "tap.docker.registry", Found: false, Expected: "tap:\n  docker:\n    tag: a\n"},
+		{Name: "list index", Data: "tap:\n  namespaces: [a, b]\n", Path: "tap.namespaces[0]", Found: true, Expected: "tap:\n  namespaces: [b]\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			configFile := parseTestConfigFile(t, test.Data)
+			flagPath, err := parseFlagPath(test.Path)
+			if err != nil {
+				t.Fatalf("unexpected error - %v", err)
+			}
+
+			if found := configFile.Unset(flagPath); found != test.Found {
+				t.Errorf("unexpected found - expected: %v, actual: %v", test.Found, found)
+			}
+
+			if actual := string(configFile.Bytes()); actual != test.Expected {
+				t.Errorf("unexpected config file - expected: %q, actual: %q", test.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestConfigFileWrite(t *testing.T) {
+	tests := []struct {
+		Name string
+		Data string
+		Path string
+		Err  string
+	}{
+		{Name: "known keys", Data: "tap:\n  docker:\n    tag: a\n", Path: "tap.docker.tag"},
+		{Name: "unrelated unknown key", Data: "tap:\n  foo: 1\n", Path: "tap.docker.tag"},
+		{Name: "unknown key", Data: "tap:\n  foo: 1\n", Path: "tap.bar", Err: `unknown field "tap.bar"`},
+		{Name: "under an unknown key", Data: "foo:\n  bar: 1\n", Path: "foo.baz", Err: `unknown field "foo"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			configFile := parseTestConfigFile(t, test.Data)
+			configFile.Path = filepath.Join(t.TempDir(), "config.yaml")
+			flagPath, err := parseFlagPath(test.Path)
+			if err != nil {
+				t.Fatalf("unexpected error - %v", err)
+			}
+
+			if err := configFile.Set(flagPath, "b"); err != nil {
+				t.Fatalf("unexpected error - %v", err)
+			}
+
+			err = configFile.Write()
+
+			if test.Err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.Err) {
+					t.Errorf("unexpected error - expected: %v, actual: %v", test.Err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error - %v", err)
+			}
+		})
+	}
+}
+
+func TestConfigFileMigrate(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Data     string
+		Changes  []string
+		Expected string
+	}{
+		{
+			Name:     "renamed key",
+			Data:     "tap:\n  efsFileSytemIdAndPath: a # keep\n",
+			Changes:  []string{"renamed tap.efsFileSytemIdAndPath to tap.efsFileSystemIdAndPath"},
+			Expected: "tap:\n  efsFileSystemIdAndPath: a # keep\n",
+		},
+		{
+			Name:     "renamed key in flow style",
+			Data:     "tap: {efsFileSytemIdAndPath: a}\n",
+			Changes:  []string{"renamed tap.efsFileSytemIdAndPath to tap.efsFileSystemIdAndPath"},
+			Expected: "tap: {efsFileSystemIdAndPath: a}\n",
+		},
+		{
+			Name:     "new key is set already",
+			Data:     "tap:\n  efsFileSytemIdAndPath: a\n  efsFileSystemIdAndPath: b\n",
+			Changes:  []string{"removed tap.efsFileSytemIdAndPath, tap.efsFileSystemIdAndPath is set already"},
+			Expected: "tap:\n  efsFileSystemIdAndPath: b\n",
+		},
+		{
+			Name:     "renamed key in a profile",
+			Data:     "profiles:\n  prod:\n    tap:\n      efsFileSytemIdAndPath: a\n",
+			Changes:  []string{"renamed profiles.prod.tap.efsFileSytemIdAndPath to profiles.prod.tap.efsFileSystemIdAndPath"},
+			Expected: "profiles:\n  prod:\n    tap:\n      efsFileSystemIdAndPath: a\n",
+		},
+		{
+			Name:     "nothing to migrate",
+			Data:     "tap:\n  docker:\n    tag: a\n",
+			Expected: "tap:\n  docker:\n    tag: a\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			configFile := parseTestConfigFile(t, test.Data)
+
+			changes, err := configFile.Migrate()
+			if err != nil {
+				t.Fatalf("unexpected error - %v", err)
+			}
+
+			if !reflect.DeepEqual(changes, test.Changes) {
+				t.Errorf("unexpected changes - expected: %v, actual: %v", test.Changes, changes)
+			}
+
+			if actual := string(configFile.Bytes()); actual != test.Expected {
+				t.Errorf("unexpected config file - expected: %q, actual: %q", test.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestConfigFileRenameKey(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Data     string
+		Path     string
+		Key      string
+		Renamed  bool
+		Expected string
+	}{
+		{Name: "block style", Data: "tap:\n  # comment\n  tag: a # keep\n", Path: "tap.tag", Key: "version", Renamed: true, Expected: "tap:\n  # comment\n  version: a # keep\n"},
+		{Name: "flow style", Data: "tap: {tag: a, registry: r}\n", Path: "tap.tag", Key: "version", Renamed: true, Expected: "tap: {version: a, registry: r}\n"},
+		{Name: "top level", Data: "tag: a\n", Path: "tag", Key: "version", Renamed: true, Expected: "version: a\n"},
+		{Name: "missing key", Data: "tap:\n  tag: a\n", Path: "tap.registry", Key: "version", Renamed: false, Expected: "tap:\n  tag: a\n"},
+		{Name: "list index", Data: "tap:\n  tags: [a]\n", Path: "tap.tags[0]", Key: "version", Renamed: false, Expected: "tap:\n  tags: [a]\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			configFile := parseTestConfigFile(t, test.Data)
+			flagPath, err := parseFlagPath(test.Path)
+			if err != nil {
+				t.Fatalf("unexpected error - %v", err)
+			}
+
+			if renamed := configFile.renameKey(flagPath, test.Key); renamed != test.Renamed {
+				t.Errorf("unexpected renamed - expected: %v, actual: %v", test.Renamed, renamed)
+			}
+
+			if actual := string(configFile.Bytes()); actual != test.Expected {
+				t.Errorf("unexpected config file - expected: %q, actual: %q", test.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestConfigFileRedactSecrets(t *testing.T) {
+	configFile := parseTestConfigFile(t, "license: a\n---\nheadless: true\n---\nlicense: b\nprofiles:\n  prod:\n    license: env:SECRET_MOCK\n")
+
+	if err := configFile.RedactSecrets(); err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+
+	expected := fmt.Sprintf("license: %s\n---\nheadless: true\n---\nlicense: %s\nprofiles:\n  prod:\n    license: env:SECRET_MOCK\n", RedactedValue, RedactedValue)
+	if actual := string(configFile.Bytes()); actual != expected {
+		t.Errorf("unexpected config file - expected: %q, actual: %q", expected, actual)
+	}
+}
+
+func TestOmitSecretRefs(t *testing.T) {
+	config := ConfigStruct{License: "env:SECRET_MOCK"}
+	config.Tap.Auth.Saml.X509key = "plain"
+
+	paths := OmitSecretRefs(&config)
+
+	if !reflect.DeepEqual(paths, []string{"license"}) {
+		t.Errorf("unexpected paths - expected: %v, actual: %v", []string{"license"}, paths)
+	}
+
+	if config.License != "" {
+		t.Errorf("unexpected license - expected: empty, actual: %v", config.License)
+	}
+
+	if config.Tap.Auth.Saml.X509key != "plain" {
+		t.Errorf("unexpected value - expected: plain, actual: %v", config.Tap.Auth.Saml.X509key)
+	}
+}
+
+func TestOmitReadonly(t *testing.T) {
+	config := CreateDefaultConfig()
+	if err := defaults.Set(&config); err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+
+	data, err := yaml.Marshal(OmitReadonly(config))
+	if err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+
+	for _, key := range []string{"export", "query", "status", "pause", "target", "manifests", "config"} {
+		if _, ok := values[key]; ok {
+			t.Errorf("unexpected key %v in the values", key)
+		}
+	}
+
+	if _, ok := values["scripting"].(map[string]interface{})["test"]; ok {
+		t.Errorf("unexpected key scripting.test in the values")
+	}
+
+	// The chart values are the config in JSON, where the settings of the commands are never included.
+	data, err = json.Marshal(config)
+	if err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+
+	var chartValues map[string]interface{}
+	if err := json.Unmarshal(data, &chartValues); err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+
+	for _, key := range []string{"export", "query", "status", "pause", "target", "manifests"} {
+		if _, ok := chartValues[key]; ok {
+			t.Errorf("unexpected key %v in the chart values", key)
+		}
+	}
+
+	for _, key := range []string{"sync", "test"} {
+		if _, ok := chartValues["scripting"].(map[string]interface{})[key]; ok {
+			t.Errorf("unexpected key scripting.%v in the chart values", key)
+		}
+	}
+
+	if config.Export.Retries == 0 {
+		t.Errorf("unexpected change of the config - expected: a zeroed copy, actual: the config is zeroed")
+	}
+}
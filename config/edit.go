@@ -0,0 +1,514 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/creasty/defaults"
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// ConfigFile is a config file that is edited in place, i.e. its comments and the order of its keys are kept.
+// The paths are the same as of --set, e.g. tap.docker.tag or tap.nodeSelectorTerms[0].matchExpressions
+type ConfigFile struct {
+	Path   string
+	file   *ast.File
+	edited []string
+}
+
+// ReadConfigFile reads the config file for editing, a missing file is read as an empty one.
+func ReadConfigFile(filePath string) (*ConfigFile, error) {
+	buf, err := os.ReadFile(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	file, err := parser.ParseBytes(buf, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConfigFile{Path: filePath, file: file}, nil
+}
+
+func (f *ConfigFile) Bytes() []byte {
+	var docs []string
+	for _, doc := range f.file.Docs {
+		if doc.Body == nil {
+			continue
+		}
+		// The documents after the first one start with their separator, that's added back when they're joined.
+		docs = append(docs, strings.TrimPrefix(strings.TrimSuffix(doc.String(), "\n"), "---\n"))
+	}
+
+	if len(docs) == 0 {
+		return []byte{}
+	}
+
+	return []byte(strings.Join(docs, "\n---\n") + "\n")
+}
+
+// Write validates the config file and writes it. Only the unknown keys of the edited paths fail it, the other ones
+// were there before and are only warned about, so that e.g. a stale key doesn't block the migration of the file.
+func (f *ConfigFile) Write() error {
+	data := f.Bytes()
+	err := validateConfigData(data, func(unknownKey *unknownKeyError) bool {
+		for _, edited := range f.edited {
+			if isConfigSubpath(unknownKey.path, edited) || isConfigSubpath(edited, unknownKey.path) {
+				return true
+			}
+		}
+
+		warnUnknownKeys(f.Path, []error{unknownKey})
+		return false
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeConfigFile(f.Path, data)
+}
+
+// Get returns the node of the path in the config file, or nil if it's not set.
+func (f *ConfigFile) Get(flagPath []string) ast.Node {
+	for _, doc := range f.file.Docs {
+		if node := findNode(doc.Body, flagPath); node != nil {
+			return node
+		}
+	}
+
+	return nil
+}
+
+// Set sets the value of the path in the config file, the missing keys are added.
+func (f *ConfigFile) Set(flagPath []string, value interface{}) error {
+	if len(f.file.Docs) == 0 {
+		f.file.Docs = append(f.file.Docs, ast.Document(nil, nil))
+	}
+
+	doc := f.file.Docs[0]
+	body, err := setNode(doc.Body, flagPath, value)
+	if err != nil {
+		return err
+	}
+
+	doc.Body = body
+	f.edited = append(f.edited, configPath(flagPath))
+	return nil
+}
+
+// Unset removes the path from the config file, along with the mappings that are left empty.
+// It returns whether the path was set.
+func (f *ConfigFile) Unset(flagPath []string) bool {
+	found := false
+	for _, doc := range f.file.Docs {
+		body, ok := unsetNode(doc.Body, flagPath)
+		if ok {
+			doc.Body = body
+			found = true
+		}
+	}
+
+	return found
+}
+
+// ParseConfigValue parses the value of a config path, the same way as --set does. Multiple values are
+// parsed as a list.
+func ParseConfigValue(key string, values []string) (flagPath []string, value interface{}, err error) {
+	flagPath, err = parseFlagPath(key)
+	if err != nil {
+		return
+	}
+
+	if renamedKey, ok := renamedConfigPath(key); ok {
+		err = fmt.Errorf("%s is renamed to %s", key, renamedKey)
+		return
+	}
+
+	configElemValue := reflect.New(reflect.TypeOf(Config)).Elem()
+	if len(values) == 1 {
+		err = mergeFlagValue(configElemValue, flagPath, key, values[0])
+	} else {
+		err = mergeFlagValues(configElemValue, flagPath, key, values)
+	}
+	if err != nil {
+		return
+	}
+
+	err = mergeFlag(configElemValue, flagPath, key, func(_ string, _ reflect.Type, currentFieldElemValue reflect.Value) error {
+		value = currentFieldElemValue.Interface()
+		return nil
+	})
+
+	return
+}
+
+// GetConfigValue returns the value of a path of the effective config, the secrets redacted.
+func GetConfigValue(key string) (interface{}, error) {
+	flagPath, err := parseFlagPath(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := yaml.Marshal(RedactSecrets(Config))
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+
+	for _, name := range flagPath {
+		if index, ok := parseFlagIndex(name); ok {
+			values, ok := value.([]interface{})
+			if !ok || index >= len(values) {
+				return nil, fmt.Errorf("%s not found", key)
+			}
+			value = values[index]
+			continue
+		}
+
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s not found", key)
+		}
+
+		if value, ok = values[name]; !ok {
+			return nil, fmt.Errorf("%s not found", key)
+		}
+	}
+
+	return value, nil
+}
+
+// ValidateConfigData validates the content of a config file, the way it's loaded.
+func ValidateConfigData(data []byte) error {
+	return validateConfigData(data, nil)
+}
+
+// validateConfigData validates the content of a config file, the unknown keys fail it if isError tells so, or
+// all of these if it's not set.
+func validateConfigData(data []byte, isError func(unknownKey *unknownKeyError) bool) error {
+	config := CreateDefaultConfig()
+	if err := defaults.Set(&config); err != nil {
+		return err
+	}
+
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return err
+	}
+
+	if err := checkUnknownKeys(data, reflect.TypeOf(config), isError); err != nil {
+		return err
+	}
+
+	return config.Tap.Validate()
+}
+
+// configPath formats a parsed config path the way the unknown keys are reported, e.g. tap.nodeSelectorTerms[0].key
+func configPath(flagPath []string) string {
+	var path strings.Builder
+	for _, name := range flagPath {
+		if _, ok := parseFlagIndex(name); !ok && path.Len() > 0 {
+			path.WriteByte('.')
+		}
+		path.WriteString(name)
+	}
+
+	return path.String()
+}
+
+// isConfigSubpath tells whether the config path is the parent path or is nested in it.
+func isConfigSubpath(path string, parent string) bool {
+	return path == parent || strings.HasPrefix(path, parent+".") || strings.HasPrefix(path, parent+"[")
+}
+
+func findNode(node ast.Node, flagPath []string) ast.Node {
+	if len(flagPath) == 0 {
+		return node
+	}
+
+	if index, ok := parseFlagIndex(flagPath[0]); ok {
+		sequence, ok := node.(*ast.SequenceNode)
+		if !ok || index >= len(sequence.Values) {
+			return nil
+		}
+
+		return findNode(sequence.Values[index], flagPath[1:])
+	}
+
+	if mappingValue := findMappingValueNode(node, flagPath[0]); mappingValue != nil {
+		return findNode(mappingValue.Value, flagPath[1:])
+	}
+
+	return nil
+}
+
+func findMappingValueNode(node ast.Node, key string) *ast.MappingValueNode {
+	switch n := node.(type) {
+	case *ast.MappingNode:
+		for _, value := range n.Values {
+			if value.Key.GetToken().Value == key {
+				return value
+			}
+		}
+	case *ast.MappingValueNode:
+		if n.Key.GetToken().Value == key {
+			return n
+		}
+	}
+
+	return nil
+}
+
+// setNode sets the value of the path in the node. It returns the node, or the one that replaces it
+// when it can't hold the path, e.g. a null or an empty flow mapping.
+func setNode(node ast.Node, flagPath []string, value interface{}) (ast.Node, error) {
+	if len(flagPath) == 0 {
+		valueNode, err := yaml.ValueToNode(value)
+		if err != nil {
+			return nil, err
+		}
+
+		if node != nil && node.GetComment() != nil {
+			if err := valueNode.SetComment(node.GetComment()); err != nil {
+				return nil, err
+			}
+		}
+
+		return valueNode, nil
+	}
+
+	if index, ok := parseFlagIndex(flagPath[0]); ok {
+		sequence, ok := node.(*ast.SequenceNode)
+		if ok && index < len(sequence.Values) {
+			item, err := setNode(sequence.Values[index], flagPath[1:], value)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := sequence.Replace(index, item); err != nil {
+				return nil, err
+			}
+
+			return sequence, nil
+		}
+
+		if ok && index == len(sequence.Values) && index > 0 {
+			itemNode, err := yaml.ValueToNode([]interface{}{nestedConfigValue(flagPath[1:], value)})
+			if err != nil {
+				return nil, err
+			}
+
+			itemSequence := itemNode.(*ast.SequenceNode)
+			itemSequence.SetIsFlowStyle(sequence.IsFlowStyle)
+			sequence.Merge(itemSequence)
+			return sequence, nil
+		}
+
+		if ok && index > len(sequence.Values) {
+			return nil, fmt.Errorf("index %d is out of range, the list has %d items", index, len(sequence.Values))
+		}
+	} else {
+		if mappingValue := findMappingValueNode(node, flagPath[0]); mappingValue != nil {
+			if err := setMappingValueNode(mappingValue, flagPath[1:], value); err != nil {
+				return nil, err
+			}
+
+			return node, nil
+		}
+
+		var mapping *ast.MappingNode
+		switch n := node.(type) {
+		case *ast.MappingNode:
+			if len(n.Values) > 0 {
+				mapping = n
+			}
+		case *ast.MappingValueNode:
+			mapping = ast.Mapping(n.GetToken(), false, n)
+		}
+
+		if mapping != nil {
+			mappingNode, err := yaml.ValueToNode(nestedConfigValue(flagPath, value))
+			if err != nil {
+				return nil, err
+			}
+
+			newMapping := mappingNode.(*ast.MappingNode)
+			newMapping.SetIsFlowStyle(mapping.IsFlowStyle)
+			mapping.Merge(newMapping)
+			return mapping, nil
+		}
+	}
+
+	// There's nothing to keep, e.g. an empty file, a null or an empty flow mapping
+	return yaml.ValueToNode(nestedConfigValue(flagPath, value))
+}
+
+// setMappingValueNode sets the value of the path in the value of a key, and aligns a new block mapping
+// or list under the key, the way the config file is generated.
+func setMappingValueNode(mappingValue *ast.MappingValueNode, flagPath []string, value interface{}) error {
+	valueNode, err := setNode(mappingValue.Value, flagPath, value)
+	if err != nil {
+		return err
+	}
+
+	if valueNode == mappingValue.Value {
+		return nil
+	}
+
+	switch n := valueNode.(type) {
+	case *ast.MappingNode:
+		if !n.IsFlowStyle && len(n.Values) > 0 {
+			n.AddColumn(mappingValue.Key.GetToken().Position.Column + 2 - n.Values[0].Key.GetToken().Position.Column)
+			mappingValue.Value = n
+			return nil
+		}
+	case *ast.SequenceNode:
+		if !n.IsFlowStyle {
+			n.AddColumn(mappingValue.Key.GetToken().Position.Column - n.Start.Position.Column)
+			mappingValue.Value = n
+			return nil
+		}
+	}
+
+	return mappingValue.Replace(valueNode)
+}
+
+// unsetNode removes the path from the node. It returns the node, or nil if it's left empty, and whether the path was found.
+func unsetNode(node ast.Node, flagPath []string) (ast.Node, bool) {
+	if len(flagPath) == 0 {
+		return nil, true
+	}
+
+	if index, ok := parseFlagIndex(flagPath[0]); ok {
+		sequence, ok := node.(*ast.SequenceNode)
+		if !ok || index >= len(sequence.Values) {
+			return node, false
+		}
+
+		item, found := unsetNode(sequence.Values[index], flagPath[1:])
+		if !found {
+			return node, false
+		}
+
+		if item != nil {
+			sequence.Values[index] = item
+			return sequence, true
+		}
+
+		sequence.Values = append(sequence.Values[:index], sequence.Values[index+1:]...)
+		if len(sequence.Values) == 0 {
+			return nil, true
+		}
+
+		return sequence, true
+	}
+
+	switch n := node.(type) {
+	case *ast.MappingNode:
+		for i, mappingValue := range n.Values {
+			if mappingValue.Key.GetToken().Value != flagPath[0] {
+				continue
+			}
+
+			value, found := unsetNode(mappingValue.Value, flagPath[1:])
+			if !found {
+				return node, false
+			}
+
+			if value != nil {
+				mappingValue.Value = value
+				return n, true
+			}
+
+			n.Values = append(n.Values[:i], n.Values[i+1:]...)
+			if len(n.Values) == 0 {
+				return nil, true
+			}
+
+			return n, true
+		}
+	case *ast.MappingValueNode:
+		if n.Key.GetToken().Value != flagPath[0] {
+			return node, false
+		}
+
+		value, found := unsetNode(n.Value, flagPath[1:])
+		if !found {
+			return node, false
+		}
+
+		if value != nil {
+			n.Value = value
+			return n, true
+		}
+
+		return nil, true
+	}
+
+	return node, false
+}
+
+// nestedConfigValue nests the value in the maps and the lists of the path, e.g. a[0].b=c into {a: [{b: c}]}
+func nestedConfigValue(flagPath []string, value interface{}) interface{} {
+	for i := len(flagPath) - 1; i >= 0; i-- {
+		if index, ok := parseFlagIndex(flagPath[i]); ok {
+			values := make([]interface{}, index+1)
+			values[index] = value
+			value = values
+			continue
+		}
+
+		value = map[string]interface{}{flagPath[i]: value}
+	}
+
+	return value
+}
+
+// ParseConfigPath parses a config path, the same way as --set does.
+func ParseConfigPath(key string) ([]string, error) {
+	return parseFlagPath(key)
+}
+
+// WriteConfigData writes the content of the config file as it is, e.g. after it's edited by the user.
+func WriteConfigData(data []byte) error {
+	return writeConfigFile(ConfigFilePath, data)
+}
+
+// renameKey renames the last key of the path in place, i.e. with its comments. It returns false if the path
+// is not set.
+func (f *ConfigFile) renameKey(flagPath []string, key string) bool {
+	if _, ok := parseFlagIndex(flagPath[len(flagPath)-1]); ok {
+		return false
+	}
+
+	for _, doc := range f.file.Docs {
+		parent := doc.Body
+		if len(flagPath) > 1 {
+			parent = findNode(doc.Body, flagPath[:len(flagPath)-1])
+		}
+
+		if mappingValue := findMappingValueNode(parent, flagPath[len(flagPath)-1]); mappingValue != nil {
+			keyNode, ok := mappingValue.Key.(*ast.StringNode)
+			if !ok {
+				return false
+			}
+
+			keyNode.Value = key
+			keyToken := mappingValue.Key.GetToken()
+			keyToken.Origin = strings.Replace(keyToken.Origin, keyToken.Value, key, 1)
+			keyToken.Value = key
+			f.edited = append(f.edited, configPath(append(flagPath[:len(flagPath)-1:len(flagPath)-1], key)))
+			return true
+		}
+	}
+
+	return false
+}
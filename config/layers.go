@@ -0,0 +1,331 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/kubeshark/kubeshark/misc"
+	"github.com/rs/zerolog/log"
+)
+
+// The config is layered, each layer overrides the ones before it:
+// defaults < global file < project file < --config files < KUBESHARK_* env vars < --set < flags
+
+const (
+	ConfigFilesFlag = "config"
+	ProfileFlag     = "profile"
+	ProfilesKey     = "profiles"
+	DefaultSource   = "default"
+)
+
+type configSource struct {
+	path   string
+	source string
+}
+
+// configSources records where the values that are not the defaults came from, in the order of the layers.
+var configSources []configSource
+
+func recordSource(path string, source string) {
+	configSources = append(configSources, configSource{path: path, source: source})
+}
+
+// loadConfigFiles loads the global file, the project file and the given files in this order. Only the
+// given files are required to exist. If a profile is given, its sections are applied on top of all the
+// files, in the same order, and whether it's defined in any of them is returned.
+func loadConfigFiles(config *ConfigStruct, configFiles []string, profile string, silent bool) (profileFound bool, err error) {
+	ConfigFilePath = path.Join(misc.GetDotFolderPath(), "config.yaml")
+	filePaths := []string{ConfigFilePath}
+	LoadedConfigFiles = nil
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return
+	}
+
+	cwdConfig := filepath.Join(cwd, fmt.Sprintf("%s.yaml", misc.Program))
+	if _, err := os.Stat(cwdConfig); err == nil {
+		ConfigFilePath = cwdConfig
+		filePaths = append(filePaths, cwdConfig)
+	}
+
+	var profileSections []profileSection
+	for i, filePath := range append(filePaths, configFiles...) {
+		sections, loadErr := loadConfigFile(config, filePath, profile, silent)
+		if loadErr != nil {
+			if os.IsNotExist(loadErr) && i < len(filePaths) {
+				continue
+			}
+
+			err = fmt.Errorf("%s, %w", filePath, loadErr)
+			return
+		}
+
+		profileSections = append(profileSections, sections...)
+		LoadedConfigFiles = append(LoadedConfigFiles, filePath)
+	}
+
+	for _, section := range profileSections {
+		if err = section.apply(config, profile); err != nil {
+			err = fmt.Errorf("%s, %w", section.filePath, err)
+			return
+		}
+	}
+
+	profileFound = len(profileSections) > 0
+
+	return
+}
+
+// profileSection is the section of a profile in a config file.
+type profileSection struct {
+	filePath string
+	node     ast.Node
+}
+
+// apply loads the section onto the config.
+func (section profileSection) apply(config *ConfigStruct, profile string) error {
+	warnUnknownKeys(section.filePath, checkNodeKeys(section.node, reflect.TypeOf(config).Elem(), fmt.Sprintf("%s.%s", ProfilesKey, profile)))
+
+	if err := yaml.NodeToValue(section.node, config); err != nil {
+		return err
+	}
+
+	profileSource := fmt.Sprintf("%s (profile %s)", section.filePath, profile)
+	collectSources(section.node, reflect.TypeOf(config).Elem(), "", profileSource)
+
+	return loadRenamedFields(section.node, config, profileSource)
+}
+
+// loadConfigFile loads a config file onto the config and returns the sections of the profile that are defined in
+// the file, to be applied once all the files are loaded.
+func loadConfigFile(config *ConfigStruct, filePath string, profile string, silent bool) (profileSections []profileSection, err error) {
+	reader, err := os.Open(filePath)
+	if err != nil {
+		return
+	}
+	defer reader.Close()
+
+	buf, err := io.ReadAll(reader)
+	if err != nil {
+		return
+	}
+
+	if err = yaml.Unmarshal(buf, config); err != nil {
+		return
+	}
+
+	file, err := parser.ParseBytes(buf, 0)
+	if err != nil {
+		return
+	}
+
+	for _, doc := range file.Docs {
+		warnUnknownKeys(filePath, checkNodeKeys(doc.Body, reflect.TypeOf(config).Elem(), ""))
+		collectSources(doc.Body, reflect.TypeOf(config).Elem(), "", filePath)
+
+		if err = loadRenamedFields(doc.Body, config, filePath); err != nil {
+			return
+		}
+
+		if profile == "" {
+			continue
+		}
+
+		if profileNode := findMappingValue(findMappingValue(doc.Body, ProfilesKey), profile); profileNode != nil {
+			profileSections = append(profileSections, profileSection{filePath: filePath, node: profileNode})
+		}
+	}
+
+	if !silent {
+		log.Info().Str("path", filePath).Bool("profile", len(profileSections) > 0).Msg("Found config file!")
+	}
+
+	return
+}
+
+// findMappingValue returns the value of the key in the mapping node, or nil.
+func findMappingValue(node ast.Node, key string) ast.Node {
+	switch n := node.(type) {
+	case *ast.AnchorNode:
+		return findMappingValue(n.Value, key)
+	case *ast.TagNode:
+		return findMappingValue(n.Value, key)
+	case *ast.MappingNode:
+		for _, value := range n.Values {
+			if value.Key.GetToken().Value == key {
+				return value.Value
+			}
+		}
+	case *ast.MappingValueNode:
+		if n.Key.GetToken().Value == key {
+			return n.Value
+		}
+	}
+
+	return nil
+}
+
+// collectSources records the file, and the line, as the source of every config field that's set in the node.
+func collectSources(node ast.Node, t reflect.Type, path string, filePath string) {
+	var values []*ast.MappingValueNode
+	switch n := node.(type) {
+	case *ast.AnchorNode:
+		collectSources(n.Value, t, path, filePath)
+		return
+	case *ast.TagNode:
+		collectSources(n.Value, t, path, filePath)
+		return
+	case *ast.MappingNode:
+		values = n.Values
+	case *ast.MappingValueNode:
+		values = []*ast.MappingValueNode{n}
+	}
+
+	t = derefType(t)
+	for _, value := range values {
+		if _, ok := value.Key.(*ast.MergeKeyNode); ok {
+			collectSources(value.Value, t, path, filePath)
+			continue
+		}
+
+		if t.Kind() != reflect.Struct {
+			continue
+		}
+
+		key := value.Key.GetToken().Value
+		if path == "" && key == ProfilesKey {
+			continue
+		}
+
+		field, ok := findYamlField(t, key)
+		if !ok {
+			continue
+		}
+
+		keyPath := key
+		if path != "" {
+			keyPath = fmt.Sprintf("%s.%s", path, key)
+		}
+
+		if derefType(field.Type).Kind() == reflect.Struct {
+			collectSources(value.Value, field.Type, keyPath, filePath)
+			continue
+		}
+
+		recordSource(keyPath, fmt.Sprintf("%s:%d", filePath, value.Key.GetToken().Position.Line))
+	}
+}
+
+// fieldPaths returns the paths of the fields of the config that are not structs, i.e. the settings.
+func fieldPaths(value reflect.Value, path string, visit func(path string, field reflect.StructField, value reflect.Value)) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name := getFieldNameByTag(field)
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+
+		fieldPath := name
+		if path != "" {
+			fieldPath = fmt.Sprintf("%s.%s", path, name)
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			fieldPaths(value.Field(i), fieldPath, visit)
+			continue
+		}
+
+		visit(fieldPath, field, value.Field(i))
+	}
+}
+
+// EnvName returns the name of the environment variable that sets the config path, e.g. KUBESHARK_TAP_DOCKER_TAG for tap.docker.tag
+func EnvName(path string) string {
+	return strings.ToUpper(fmt.Sprintf("%s_%s", misc.Program, strings.ReplaceAll(path, ".", "_")))
+}
+
+// loadEnv sets the scalar and the list settings from the KUBESHARK_* environment variables. A list is comma-separated.
+func loadEnv(config *ConfigStruct) error {
+	configElemValue := reflect.ValueOf(config).Elem()
+
+	var errs []error
+	fieldPaths(configElemValue, "", func(path string, field reflect.StructField, _ reflect.Value) {
+		envName := EnvName(path)
+		envValue, ok := os.LookupEnv(envName)
+		if !ok {
+			return
+		}
+
+		var err error
+		switch field.Type.Kind() {
+		case reflect.Map, reflect.Interface:
+			err = fmt.Errorf("%s cannot be set by an environment variable", path)
+		case reflect.Slice:
+			var values []string
+			if envValue != "" {
+				values = strings.Split(envValue, ",")
+			}
+			err = mergeFlagValues(configElemValue, strings.Split(path, "."), path, values)
+		default:
+			err = mergeFlagValue(configElemValue, strings.Split(path, "."), path, envValue)
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", envName, err))
+			return
+		}
+
+		recordSource(path, fmt.Sprintf("env %s", envName))
+	})
+
+	return errors.Join(errs...)
+}
+
+type ExplainedValue struct {
+	Path   string
+	Value  string
+	Source string
+}
+
+// ExplainConfig returns every setting of the effective config along with the layer that it came from, the secrets redacted.
+func ExplainConfig() []*ExplainedValue {
+	var explained []*ExplainedValue
+	redacted := RedactSecrets(Config)
+	fieldPaths(reflect.ValueOf(&redacted).Elem(), "", func(path string, _ reflect.StructField, value reflect.Value) {
+		if path == ProfilesKey {
+			return
+		}
+
+		source := DefaultSource
+		for _, s := range configSources {
+			if s.path == path || strings.HasPrefix(s.path, path+".") || strings.HasPrefix(s.path, path+"[") || strings.HasPrefix(path, s.path+".") {
+				source = s.source
+			}
+		}
+
+		data, err := json.Marshal(value.Interface())
+		if err != nil {
+			data = []byte(fmt.Sprint(value.Interface()))
+		}
+
+		explained = append(explained, &ExplainedValue{Path: path, Value: string(data), Source: source})
+	})
+
+	sort.SliceStable(explained, func(i, j int) bool {
+		return explained[i].Path < explained[j].Path
+	})
+
+	return explained
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/kubeshark/kubeshark/misc"
+	"github.com/rs/zerolog/log"
+)
+
+// configRenames are the config paths that were renamed. The old paths are still accepted by the flags and
+// by --set, and `config migrate` renames them in the config files.
+var configRenames = []struct {
+	from string
+	to   string
+}{
+	{from: "tap.efsFileSytemIdAndPath", to: "tap.efsFileSystemIdAndPath"},
+}
+
+// renamedConfigPath returns the new path of a path that was renamed, or of a path in a section that was renamed.
+func renamedConfigPath(path string) (string, bool) {
+	for _, rename := range configRenames {
+		if path == rename.from {
+			return rename.to, true
+		}
+
+		for _, separator := range []string{".", "["} {
+			if strings.HasPrefix(path, rename.from+separator) {
+				return rename.to + strings.TrimPrefix(path, rename.from), true
+			}
+		}
+	}
+
+	return path, false
+}
+
+// migrateConfigPath returns the new path of a renamed path, with a warning.
+func migrateConfigPath(path string, source string) string {
+	renamedPath, ok := renamedConfigPath(path)
+	if ok {
+		log.Warn().Str("path", path).Str("source", source).Msg(fmt.Sprintf("Deprecated, use %s instead.", renamedPath))
+	}
+
+	return renamedPath
+}
+
+// loadRenamedFields loads the values of the renamed paths that are set in the node onto the new paths,
+// unless these are set too.
+func loadRenamedFields(node ast.Node, config *ConfigStruct, source string) error {
+	for _, rename := range configRenames {
+		fromPath, err := parseFlagPath(rename.from)
+		if err != nil {
+			return err
+		}
+
+		valueNode := findNode(node, fromPath)
+		if valueNode == nil {
+			continue
+		}
+
+		toPath, err := parseFlagPath(rename.to)
+		if err != nil {
+			return err
+		}
+
+		if findNode(node, toPath) != nil {
+			continue
+		}
+
+		if err := mergeFlag(reflect.ValueOf(config).Elem(), toPath, rename.to, func(_ string, currentFieldType reflect.Type, currentFieldElemValue reflect.Value) error {
+			decodedValue := reflect.New(currentFieldType)
+			if err := yaml.NodeToValue(valueNode, decodedValue.Interface()); err != nil {
+				return err
+			}
+
+			currentFieldElemValue.Set(decodedValue.Elem())
+			return nil
+		}); err != nil {
+			return fmt.Errorf("%s, %w", rename.from, err)
+		}
+
+		log.Warn().Str("path", rename.from).Str("source", source).Msg(fmt.Sprintf("Deprecated, use %s instead, or migrate the config file by `%s config migrate`.", rename.to, misc.Program))
+		recordSource(rename.to, source)
+	}
+
+	return nil
+}
+
+// Migrate renames the renamed paths in the config file, the profiles included. An old path is just removed
+// if the new one is set already. It returns the descriptions of the changes.
+func (f *ConfigFile) Migrate() ([]string, error) {
+	var changes []string
+	for _, prefix := range f.profilePrefixes() {
+		for _, rename := range configRenames {
+			from, to := prefix+rename.from, prefix+rename.to
+			fromPath, err := parseFlagPath(from)
+			if err != nil {
+				return nil, err
+			}
+
+			node := f.Get(fromPath)
+			if node == nil {
+				continue
+			}
+
+			toPath, err := parseFlagPath(to)
+			if err != nil {
+				return nil, err
+			}
+
+			if f.Get(toPath) != nil {
+				f.Unset(fromPath)
+				changes = append(changes, fmt.Sprintf("removed %s, %s is set already", from, to))
+				continue
+			}
+
+			changes = append(changes, fmt.Sprintf("renamed %s to %s", from, to))
+
+			if len(fromPath) == len(toPath) && reflect.DeepEqual(fromPath[:len(fromPath)-1], toPath[:len(toPath)-1]) && f.renameKey(fromPath, toPath[len(toPath)-1]) {
+				continue
+			}
+
+			var value interface{}
+			if err := yaml.NodeToValue(node, &value); err != nil {
+				return nil, err
+			}
+
+			f.Unset(fromPath)
+			if err := f.Set(toPath, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+// profilePrefixes returns the path prefixes of the config file sections, the top level and then each of the profiles.
+func (f *ConfigFile) profilePrefixes() []string {
+	prefixes := []string{""}
+	for _, doc := range f.file.Docs {
+		profiles := findMappingValue(doc.Body, ProfilesKey)
+		for _, name := range mappingKeys(profiles) {
+			prefixes = append(prefixes, fmt.Sprintf("%s.%s.", ProfilesKey, name))
+		}
+	}
+
+	return prefixes
+}
+
+func mappingKeys(node ast.Node) (keys []string) {
+	switch n := node.(type) {
+	case *ast.MappingNode:
+		for _, value := range n.Values {
+			keys = append(keys, value.Key.GetToken().Value)
+		}
+	case *ast.MappingValueNode:
+		keys = append(keys, n.Key.GetToken().Value)
+	}
+
+	return
+}
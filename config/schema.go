@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/creasty/defaults"
+	"github.com/kubeshark/kubeshark/config/configStructs"
+	"github.com/kubeshark/kubeshark/misc"
+)
+
+const schemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// Schema generates the JSON Schema of the config file from the config structs, for the autocompletion
+// and the validation in the editors. The defaults are the values of the default config.
+func Schema() (map[string]interface{}, error) {
+	defaultConfig := CreateDefaultConfig()
+	if err := defaults.Set(&defaultConfig); err != nil {
+		return nil, err
+	}
+
+	schema := valueSchema(reflect.ValueOf(defaultConfig), true)
+	schema["$schema"] = schemaDraft
+	schema["title"] = fmt.Sprintf("%s config", misc.Software)
+
+	return schema, nil
+}
+
+// valueSchema returns the schema of the value's type. The value is only used for the defaults of the leaves,
+// if these are wanted.
+func valueSchema(value reflect.Value, withDefaults bool) map[string]interface{} {
+	t := value.Type()
+	if t.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return typeSchema(t.Elem())
+		}
+		return valueSchema(value.Elem(), withDefaults)
+	}
+
+	if t.Kind() != reflect.Struct {
+		schema := typeSchema(t)
+		if withDefaults && (value.Kind() != reflect.Slice && value.Kind() != reflect.Map || !value.IsNil()) {
+			schema["default"] = value.Interface()
+		}
+		return schema
+	}
+
+	properties := make(map[string]interface{})
+	addStructProperties(properties, value, withDefaults)
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+func addStructProperties(properties map[string]interface{}, value reflect.Value, withDefaults bool) {
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, inline, ok := yamlFieldName(field)
+		if !ok {
+			continue
+		}
+
+		fieldValue := value.Field(i)
+		if inline {
+			for fieldValue.Kind() == reflect.Pointer {
+				if fieldValue.IsNil() {
+					fieldValue = reflect.Zero(fieldValue.Type().Elem())
+					continue
+				}
+				fieldValue = fieldValue.Elem()
+			}
+			addStructProperties(properties, fieldValue, withDefaults)
+			continue
+		}
+
+		schema := valueSchema(fieldValue, withDefaults)
+		// The examples only help the editors to complete the values, unlike the enum these aren't enforced.
+		for keyword, tag := range map[string]string{"enum": configStructs.EnumTag, "examples": configStructs.ExamplesTag} {
+			if list, ok := field.Tag.Lookup(tag); ok {
+				values := strings.Split(list, ",")
+				if items, ok := schema["items"].(map[string]interface{}); ok {
+					items[keyword] = values
+				} else {
+					schema[keyword] = values
+				}
+			}
+		}
+		properties[name] = schema
+	}
+}
+
+// typeSchema returns the schema of a type, that has no value to take the defaults from.
+func typeSchema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem())
+	case reflect.Struct:
+		return valueSchema(reflect.Zero(t), false)
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		schema := map[string]interface{}{"type": "integer", "minimum": 0}
+		if t.Kind() == reflect.Uint16 {
+			schema["maximum"] = 65535
+		}
+		return schema
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	}
+
+	// Any value, e.g. of an interface{}
+	return map[string]interface{}{}
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// The fields tagged as secret accept a reference instead of the value, which is resolved at install time:
+//
+//	env:NAME                       the environment variable NAME
+//	file:/path                     the content of the file, without the trailing newline
+//	k8s:namespace/secret#key       the key of the Kubernetes secret
+//
+// The values of these fields are never logged and they're redacted in the printed config, the references are not.
+const (
+	SecretEnvPrefix  = "env:"
+	SecretFilePrefix = "file:"
+	SecretK8sPrefix  = "k8s:"
+	RedactedValue    = "REDACTED"
+)
+
+// SecretLookup returns the value of the key of a Kubernetes secret.
+type SecretLookup func(namespace string, name string, key string) (string, error)
+
+// IsSecretRef tells whether the value is a reference to a secret rather than the secret itself.
+func IsSecretRef(value string) bool {
+	for _, prefix := range []string{SecretEnvPrefix, SecretFilePrefix, SecretK8sPrefix} {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ResolveSecret returns the value that the reference points to, or the value itself if it's not a reference.
+func ResolveSecret(value string, lookup SecretLookup) (string, error) {
+	switch {
+	case strings.HasPrefix(value, SecretEnvPrefix):
+		name := strings.TrimPrefix(value, SecretEnvPrefix)
+		envValue, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("the environment variable %s is not set", name)
+		}
+		return envValue, nil
+	case strings.HasPrefix(value, SecretFilePrefix):
+		data, err := os.ReadFile(strings.TrimPrefix(value, SecretFilePrefix))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case strings.HasPrefix(value, SecretK8sPrefix):
+		namespace, name, key, err := parseK8sSecretRef(strings.TrimPrefix(value, SecretK8sPrefix))
+		if err != nil {
+			return "", err
+		}
+		if lookup == nil {
+			return "", fmt.Errorf("cannot read the Kubernetes secret %s/%s", namespace, name)
+		}
+		return lookup(namespace, name, key)
+	default:
+		return value, nil
+	}
+}
+
+func parseK8sSecretRef(ref string) (namespace string, name string, key string, err error) {
+	secret, key, _ := strings.Cut(ref, "#")
+	namespace, name, _ = strings.Cut(secret, "/")
+	if namespace == "" || name == "" || key == "" {
+		err = fmt.Errorf("invalid reference %q, expected %snamespace/secret#key", SecretK8sPrefix+ref, SecretK8sPrefix)
+	}
+
+	return
+}
+
+// secretFields visits the fields of the config that are tagged as secret.
+func secretFields(value reflect.Value, visit func(path string, value reflect.Value)) {
+	fieldPaths(value, "", func(path string, field reflect.StructField, value reflect.Value) {
+		if _, ok := field.Tag.Lookup(SecretTag); ok && field.Type.Kind() == reflect.String {
+			visit(path, value)
+		}
+	})
+}
+
+// SecretPaths returns the paths of the config fields that are tagged as secret, e.g. license.
+func SecretPaths() (paths []string) {
+	secretFields(reflect.ValueOf(&ConfigStruct{}).Elem(), func(path string, _ reflect.Value) {
+		paths = append(paths, path)
+	})
+
+	return
+}
+
+// ResolveSecrets replaces the secret references of the config with the values that they point to.
+func ResolveSecrets(config *ConfigStruct, lookup SecretLookup) error {
+	var errs []error
+	secretFields(reflect.ValueOf(config).Elem(), func(path string, value reflect.Value) {
+		resolved, err := ResolveSecret(value.String(), lookup)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			return
+		}
+
+		value.SetString(resolved)
+	})
+
+	return errors.Join(errs...)
+}
+
+// OmitSecretRefs empties the secret fields of the config that hold references, rather than resolving these, and
+// returns their paths. It's for the output that may be committed, e.g. the manifests.
+func OmitSecretRefs(config *ConfigStruct) (paths []string) {
+	secretFields(reflect.ValueOf(config).Elem(), func(path string, value reflect.Value) {
+		if IsSecretRef(value.String()) {
+			value.SetString("")
+			paths = append(paths, path)
+		}
+	})
+
+	return
+}
+
+// RedactSecret hides the value of a secret. An empty value and a reference are kept, as they disclose nothing.
+func RedactSecret(value string) string {
+	if value == "" || IsSecretRef(value) {
+		return value
+	}
+
+	return RedactedValue
+}
+
+// RedactSecrets returns a copy of the config with the secrets redacted, the ones in the profiles included.
+func RedactSecrets(config ConfigStruct) ConfigStruct {
+	secretFields(reflect.ValueOf(&config).Elem(), func(_ string, value reflect.Value) {
+		value.SetString(RedactSecret(value.String()))
+	})
+
+	if config.Profiles != nil {
+		profiles := make(map[string]interface{}, len(config.Profiles))
+		for name, profile := range config.Profiles {
+			profiles[name] = redactSecretValues(profile, "")
+		}
+		config.Profiles = profiles
+	}
+
+	return config
+}
+
+// redactSecretValues returns a copy of the decoded YAML value at the path, with the values of the secret paths redacted.
+func redactSecretValues(value interface{}, path string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			redacted[key] = redactSecretValues(item, joinConfigPath(path, key))
+		}
+		return redacted
+	case string:
+		for _, secretPath := range SecretPaths() {
+			if path == secretPath {
+				return RedactSecret(v)
+			}
+		}
+	}
+
+	return value
+}
+
+func joinConfigPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return fmt.Sprintf("%s.%s", path, key)
+}
+
+// RedactSecrets redacts the secrets that are set in the config file, the ones in the profiles included. A secret is
+// redacted in every document of the file that sets it.
+func (f *ConfigFile) RedactSecrets() error {
+	for _, prefix := range f.profilePrefixes() {
+		for _, secretPath := range SecretPaths() {
+			flagPath, err := parseFlagPath(prefix + secretPath)
+			if err != nil {
+				return err
+			}
+
+			for _, doc := range f.file.Docs {
+				node := findNode(doc.Body, flagPath)
+				if node == nil {
+					continue
+				}
+
+				var value string
+				if err := yaml.NodeToValue(node, &value); err != nil {
+					continue
+				}
+
+				if redacted := RedactSecret(value); redacted != value {
+					if doc.Body, err = setNode(doc.Body, flagPath, redacted); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
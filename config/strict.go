@@ -0,0 +1,187 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/kubeshark/kubeshark/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// checkUnknownKeys reports every key of the YAML document that has no matching field in the type,
+// with the line and the column of the key. It complements the lenient unmarshalling, that ignores these.
+// If isError is set, only the keys that it tells are reported.
+func checkUnknownKeys(buf []byte, t reflect.Type, isError func(unknownKey *unknownKeyError) bool) error {
+	file, err := parser.ParseBytes(buf, 0)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, doc := range file.Docs {
+		for _, err := range checkNodeKeys(doc.Body, t, "") {
+			if unknownKey, ok := err.(*unknownKeyError); ok && isError != nil && !isError(unknownKey) {
+				continue
+			}
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// unknownKeyError is a key of a config file that has no matching field, along with its line and column.
+type unknownKeyError struct {
+	line   int
+	column int
+	path   string
+}
+
+func (e *unknownKeyError) Error() string {
+	return fmt.Sprintf("[%d:%d] unknown field %q", e.line, e.column, e.path)
+}
+
+// warnUnknownKeys logs the unknown keys of a loaded config file. A stale key shouldn't break the commands,
+// including the ones that fix the file, so only ValidateConfigData fails on these.
+func warnUnknownKeys(filePath string, errs []error) {
+	for _, err := range errs {
+		log.Warn().Str("path", filePath).Msg(fmt.Sprintf("Ignoring the config key, %v", err))
+	}
+}
+
+func checkNodeKeys(node ast.Node, t reflect.Type, path string) (errs []error) {
+	t = derefType(t)
+
+	switch n := node.(type) {
+	case *ast.AnchorNode:
+		return checkNodeKeys(n.Value, t, path)
+	case *ast.TagNode:
+		return checkNodeKeys(n.Value, t, path)
+	case *ast.MappingNode:
+		for _, value := range n.Values {
+			errs = append(errs, checkMappingValueKeys(value, t, path)...)
+		}
+	case *ast.MappingValueNode:
+		errs = append(errs, checkMappingValueKeys(n, t, path)...)
+	case *ast.SequenceNode:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return
+		}
+		for i, value := range n.Values {
+			errs = append(errs, checkNodeKeys(value, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+
+	return
+}
+
+func checkMappingValueKeys(node *ast.MappingValueNode, t reflect.Type, path string) []error {
+	if _, ok := node.Key.(*ast.MergeKeyNode); ok {
+		return checkNodeKeys(node.Value, t, path)
+	}
+
+	key := node.Key.GetToken().Value
+	keyPath := key
+	if path != "" {
+		keyPath = fmt.Sprintf("%s.%s", path, key)
+	}
+
+	switch t.Kind() {
+	case reflect.Map:
+		return checkNodeKeys(node.Value, t.Elem(), keyPath)
+	case reflect.Struct:
+		field, ok := findYamlField(t, key)
+		if !ok {
+			// The renamed fields are loaded onto the new ones
+			if _, renamed := renamedConfigPath(withoutProfilePrefix(keyPath)); renamed {
+				return nil
+			}
+
+			position := node.Key.GetToken().Position
+			return []error{&unknownKeyError{line: position.Line, column: position.Column, path: keyPath}}
+		}
+
+		return checkNodeKeys(node.Value, field.Type, keyPath)
+	}
+
+	return nil
+}
+
+// withoutProfilePrefix returns the config path of a path in a profile, e.g. tap.docker.tag of profiles.prod.tap.docker.tag
+func withoutProfilePrefix(path string) string {
+	if !strings.HasPrefix(path, ProfilesKey+".") {
+		return path
+	}
+
+	split := strings.SplitN(path, ".", 3)
+	if len(split) < 3 {
+		return path
+	}
+
+	return split[2]
+}
+
+// findYamlField finds the field of the struct by its name in YAML, the same way as the YAML decoder does.
+func findYamlField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldName, inline, ok := yamlFieldName(field)
+		if !ok {
+			continue
+		}
+
+		if inline {
+			if inlineField, ok := findYamlField(derefType(field.Type), name); ok {
+				return inlineField, true
+			}
+			continue
+		}
+
+		if fieldName == name {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// yamlFieldName returns the name of the field in YAML, or whether it's inlined. It's not ok if the field
+// is not decoded at all.
+func yamlFieldName(field reflect.StructField) (name string, inline bool, ok bool) {
+	if !field.IsExported() {
+		return
+	}
+
+	tag := field.Tag.Get(FieldNameTag)
+	if tag == "" {
+		tag = field.Tag.Get("json")
+	}
+	options := strings.Split(tag, ",")
+
+	if options[0] == "-" {
+		return
+	}
+
+	if (field.Anonymous || utils.Contains(options[1:], "inline")) && derefType(field.Type).Kind() == reflect.Struct {
+		return "", true, true
+	}
+
+	name = options[0]
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+
+	return name, false, true
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t
+}
@@ -0,0 +1,7 @@
+// Package helmchart embeds the Helm chart, so it can be installed without access to the Helm repository.
+package helmchart
+
+import "embed"
+
+//go:embed Chart.yaml values.yaml all:templates
+var FS embed.FS
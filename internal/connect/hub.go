@@ -8,7 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"os"
+	"strings"
 	"time"
 
 	"github.com/kubeshark/kubeshark/config"
@@ -113,7 +113,7 @@ func (connector *Connector) PostLicense(license string) {
 				}
 				log.Warn().Err(err).Msg("Failed sending the license to Hub. Retrying...")
 			} else {
-				log.Debug().Str("license", license).Msg("Reported license to Hub:")
+				log.Debug().Msg("Reported license to Hub.")
 				return
 			}
 			time.Sleep(DefaultSleep)
@@ -122,8 +122,9 @@ func (connector *Connector) PostLicense(license string) {
 }
 
 type postScriptRequest struct {
-	Title string `json:"title"`
-	Code  string `json:"code"`
+	Title string                 `json:"title"`
+	Code  string                 `json:"code"`
+	Env   map[string]interface{} `json:"env,omitempty"`
 }
 
 func (connector *Connector) PostScript(script *misc.Script) (index int64, err error) {
@@ -132,6 +133,7 @@ func (connector *Connector) PostScript(script *misc.Script) (index int64, err er
 	payload := postScriptRequest{
 		Title: script.Title,
 		Code:  script.Code,
+		Env:   script.Env,
 	}
 
 	var scriptMarshalled []byte
@@ -255,39 +257,143 @@ func (connector *Connector) DeleteScript(index int64) (err error) {
 	return
 }
 
-func (connector *Connector) PostPcapsMerge(out *os.File) {
-	postEnvUrl := fmt.Sprintf("%s/pcaps/merge", connector.url)
+type getScriptsResponse struct {
+	Scripts map[int64]*misc.Script `json:"scripts"`
+}
 
-	if envMarshalled, err := json.Marshal(map[string]string{"query": ""}); err != nil {
-		log.Error().Err(err).Msg("Failed to marshal the env:")
-	} else {
-		ok := false
-		for !ok {
-			var resp *http.Response
-			if resp, err = utils.Post(postEnvUrl, "application/json", bytes.NewBuffer(envMarshalled), connector.client, config.Config.License); err != nil || resp.StatusCode != http.StatusOK {
-				if _, ok := err.(*url.Error); ok {
-					break
-				}
-				log.Warn().Err(err).Msg("Failed exported PCAP download. Retrying...")
-			} else {
-				defer resp.Body.Close()
+// ListScripts returns the scripts that are currently on the Hub, keyed by their indexes.
+func (connector *Connector) ListScripts() (scripts map[int64]*misc.Script, err error) {
+	getScriptsUrl := fmt.Sprintf("%s/scripts", connector.url)
 
-				// Check server response
-				if resp.StatusCode != http.StatusOK {
-					log.Error().Str("status", resp.Status).Err(err).Msg("Failed exported PCAP download.")
-					return
-				}
+	var req *http.Request
+	req, err = http.NewRequest(http.MethodGet, getScriptsUrl, nil)
+	if err != nil {
+		return
+	}
+	utils.AddIgnoreCaptureHeader(req)
+	req.Header.Set("License-Key", config.Config.License)
 
-				// Writer the body to file
-				_, err = io.Copy(out, resp.Body)
-				if err != nil {
-					log.Error().Err(err).Msg("Failed writing PCAP export:")
-					return
-				}
-				log.Info().Str("path", out.Name()).Msg("Downloaded exported PCAP:")
-				return
-			}
-			time.Sleep(DefaultSleep)
-		}
+	var resp *http.Response
+	resp, err = utils.Do(req, connector.client)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	var response getScriptsResponse
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return
+	}
+
+	scripts = response.Scripts
+	if scripts == nil {
+		scripts = make(map[int64]*misc.Script)
+	}
+
+	return
+}
+
+type PostPcapsMergeRequest struct {
+	Query     string   `json:"query"`
+	StartTime int64    `json:"startTime,omitempty"`
+	EndTime   int64    `json:"endTime,omitempty"`
+	Nodes     []string `json:"nodes,omitempty"`
+	Pods      []string `json:"pods,omitempty"`
+}
+
+// PostPcapsMerge requests the merged PCAP export. When offset is greater than zero, the download
+// is resumed using a range request and resumed tells whether the Hub honored it. Otherwise,
+// the returned body starts from the beginning. Caller should close body when done reading from it.
+func (connector *Connector) PostPcapsMerge(payload *PostPcapsMergeRequest, offset int64) (body io.ReadCloser, resumed bool, err error) {
+	postPcapsMergeUrl := fmt.Sprintf("%s/pcaps/merge", connector.url)
+
+	var payloadMarshalled []byte
+	if payloadMarshalled, err = json.Marshal(payload); err != nil {
+		log.Error().Err(err).Msg("Failed to marshal the payload:")
+		return
+	}
+
+	var req *http.Request
+	req, err = http.NewRequest(http.MethodPost, postPcapsMergeUrl, bytes.NewBuffer(payloadMarshalled))
+	if err != nil {
+		return
+	}
+	utils.AddIgnoreCaptureHeader(req)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("License-Key", config.Config.License)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	var resp *http.Response
+	resp, err = connector.client.Do(req)
+	if err != nil {
+		return
 	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusPartialContent:
+		resumed = true
+	default:
+		errorMsg, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		err = fmt.Errorf("got response with status code: %d, body: %s", resp.StatusCode, strings.ReplaceAll(string(errorMsg), "\n", ";"))
+		return
+	}
+
+	body = resp.Body
+	return
+}
+
+// GetEntry returns the full JSON of a dissected entry, including its request and response.
+func (connector *Connector) GetEntry(id string) (entry []byte, err error) {
+	getEntryUrl := fmt.Sprintf("%s/entries/%s", connector.url, url.PathEscape(id))
+
+	var req *http.Request
+	req, err = http.NewRequest(http.MethodGet, getEntryUrl, nil)
+	if err != nil {
+		return
+	}
+	utils.AddIgnoreCaptureHeader(req)
+	req.Header.Set("License-Key", config.Config.License)
+
+	var resp *http.Response
+	resp, err = utils.Do(req, connector.client)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+// WorkerStatus is the state of the capture on a Worker, as the Hub sees it.
+type WorkerStatus struct {
+	Pod       string `json:"pod"`
+	Node      string `json:"node"`
+	Capturing bool   `json:"capturing"`
+}
+
+// ListWorkers returns the capture states of the Workers that are connected to the Hub.
+func (connector *Connector) ListWorkers() (workers []*WorkerStatus, err error) {
+	getWorkersUrl := fmt.Sprintf("%s/workers", connector.url)
+
+	var req *http.Request
+	req, err = http.NewRequest(http.MethodGet, getWorkersUrl, nil)
+	if err != nil {
+		return
+	}
+	utils.AddIgnoreCaptureHeader(req)
+	req.Header.Set("License-Key", config.Config.License)
+
+	var resp *http.Response
+	resp, err = utils.Do(req, connector.client)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(resp.Body).Decode(&workers)
+	return
 }
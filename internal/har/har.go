@@ -0,0 +1,312 @@
+package har
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kubeshark/kubeshark/misc"
+)
+
+// The types below follow the HAR 1.2 specification: http://www.softwareishard.com/blog/har-12-spec/
+
+const Version = "1.2"
+
+// StartedDateTimeLayout formats the start times of the entries with a fixed width, in UTC, so that these sort as strings.
+const StartedDateTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+var fileNameRegex = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+type HAR struct {
+	Log *Log `json:"log"`
+}
+
+type Log struct {
+	Version string   `json:"version"`
+	Creator *Creator `json:"creator"`
+	Entries []*Entry `json:"entries"`
+}
+
+type Creator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type Entry struct {
+	StartedDateTime string    `json:"startedDateTime"`
+	Time            float64   `json:"time"`
+	Request         *Request  `json:"request"`
+	Response        *Response `json:"response"`
+	Cache           struct{}  `json:"cache"`
+	Timings         *Timings  `json:"timings"`
+	ServerIPAddress string    `json:"serverIPAddress,omitempty"`
+	Connection      string    `json:"connection,omitempty"`
+	Comment         string    `json:"comment,omitempty"`
+}
+
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type PostData struct {
+	MimeType string      `json:"mimeType"`
+	Params   []NameValue `json:"params,omitempty"`
+	Text     string      `json:"text"`
+}
+
+type Request struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []NameValue `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	QueryString []NameValue `json:"queryString"`
+	PostData    *PostData   `json:"postData,omitempty"`
+	HeadersSize int64       `json:"headersSize"`
+	BodySize    int64       `json:"bodySize"`
+}
+
+type Content struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type Response struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []NameValue `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	Content     *Content    `json:"content"`
+	RedirectURL string      `json:"redirectURL"`
+	HeadersSize int64       `json:"headersSize"`
+	BodySize    int64       `json:"bodySize"`
+}
+
+type Timings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+func NewHAR() *HAR {
+	return &HAR{
+		Log: &Log{
+			Version: Version,
+			Creator: &Creator{
+				Name:    misc.Software,
+				Version: misc.Ver,
+			},
+			Entries: []*Entry{},
+		},
+	}
+}
+
+// Services groups the HAR entries by the services that served them, a HAR per service.
+type Services map[string]*HAR
+
+// Add adds the entry to the HAR of the service.
+func (services Services) Add(service string, entry *Entry) {
+	h, ok := services[service]
+	if !ok {
+		h = NewHAR()
+		services[service] = h
+	}
+
+	h.Log.Entries = append(h.Log.Entries, entry)
+}
+
+// Sort sorts the entries of each of the HARs by their start times.
+func (services Services) Sort() {
+	for _, h := range services {
+		sort.SliceStable(h.Log.Entries, func(i, j int) bool {
+			return h.Log.Entries[i].StartedDateTime < h.Log.Entries[j].StartedDateTime
+		})
+	}
+}
+
+// FileNames returns a file name for each of the services. The service names are sanitized, so the ones that end up
+// the same are told apart by a numeric suffix, in the order of the service names.
+func (services Services) FileNames() map[string]string {
+	names := make([]string, 0, len(services))
+	for service := range services {
+		names = append(names, service)
+	}
+	sort.Strings(names)
+
+	fileNames := make(map[string]string, len(names))
+	used := make(map[string]bool, len(names))
+	for _, service := range names {
+		base := fileNameRegex.ReplaceAllString(service, "_")
+		fileName := fmt.Sprintf("%s.har", base)
+		for i := 2; used[fileName]; i++ {
+			fileName = fmt.Sprintf("%s_%d.har", base, i)
+		}
+
+		used[fileName] = true
+		fileNames[service] = fileName
+	}
+
+	return fileNames
+}
+
+type endpoint struct {
+	IP        string `json:"ip"`
+	Port      string `json:"port"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// entry is the subset of a dissected entry of the Hub that is needed to build a HAR entry.
+// The request and the response of the HTTP dissector are already shaped after HAR.
+type entry struct {
+	Id       string `json:"id"`
+	Protocol struct {
+		Name string `json:"name"`
+	} `json:"proto"`
+	Src         endpoint  `json:"src"`
+	Dst         endpoint  `json:"dst"`
+	Timestamp   int64     `json:"timestamp"`
+	ElapsedTime int64     `json:"elapsedTime"`
+	Request     *Request  `json:"request"`
+	Response    *Response `json:"response"`
+}
+
+// ParseEntry converts a dissected HTTP entry of the Hub into a HAR entry. It also returns the name of
+// the service that served the request, which falls back to the address when the name is not resolved.
+// The entry might be wrapped into the `data` field as it's returned by the entries endpoint.
+func ParseEntry(data []byte) (harEntry *Entry, service string, err error) {
+	var wrapped struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err = json.Unmarshal(data, &wrapped); err != nil {
+		return
+	}
+	if len(wrapped.Data) > 0 {
+		data = wrapped.Data
+	}
+
+	var e entry
+	if err = json.Unmarshal(data, &e); err != nil {
+		return
+	}
+
+	if e.Protocol.Name != "" && e.Protocol.Name != "http" {
+		err = fmt.Errorf("entry %s is not an HTTP entry: %s", e.Id, e.Protocol.Name)
+		return
+	}
+
+	if e.Request == nil || e.Response == nil {
+		err = errors.New("entry has no request or response")
+		return
+	}
+
+	service = e.Dst.Name
+	if service == "" {
+		service = fmt.Sprintf("%s_%s", e.Dst.IP, e.Dst.Port)
+	}
+
+	request := e.Request
+	request.URL = absoluteURL(request, e.Dst)
+	fillRequestDefaults(request)
+
+	response := e.Response
+	fillResponseDefaults(response)
+
+	harEntry = &Entry{
+		StartedDateTime: time.UnixMilli(e.Timestamp).UTC().Format(StartedDateTimeLayout),
+		Time:            float64(e.ElapsedTime),
+		Request:         request,
+		Response:        response,
+		Timings: &Timings{
+			Send:    0,
+			Wait:    float64(e.ElapsedTime),
+			Receive: 0,
+		},
+		ServerIPAddress: e.Dst.IP,
+	}
+
+	return
+}
+
+// absoluteURL builds the absolute URL that HAR requires from the path that the dissector might have recorded.
+func absoluteURL(request *Request, dst endpoint) string {
+	u, err := url.Parse(request.URL)
+	if err == nil && u.IsAbs() {
+		return request.URL
+	}
+
+	host := ""
+	for _, header := range request.Headers {
+		if strings.EqualFold(header.Name, "host") {
+			host = header.Value
+			break
+		}
+	}
+	if host == "" {
+		host = dst.Name
+	}
+	if host == "" {
+		host = fmt.Sprintf("%s:%s", dst.IP, dst.Port)
+	}
+
+	path := request.URL
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	return fmt.Sprintf("http://%s%s", host, path)
+}
+
+func fillRequestDefaults(request *Request) {
+	if request.HTTPVersion == "" {
+		request.HTTPVersion = "HTTP/1.1"
+	}
+	if request.Cookies == nil {
+		request.Cookies = []NameValue{}
+	}
+	if request.Headers == nil {
+		request.Headers = []NameValue{}
+	}
+	if request.QueryString == nil {
+		request.QueryString = []NameValue{}
+	}
+	if request.HeadersSize == 0 {
+		request.HeadersSize = -1
+	}
+	if request.BodySize == 0 && request.PostData == nil {
+		request.BodySize = -1
+	}
+}
+
+func fillResponseDefaults(response *Response) {
+	if response.HTTPVersion == "" {
+		response.HTTPVersion = "HTTP/1.1"
+	}
+	if response.Cookies == nil {
+		response.Cookies = []NameValue{}
+	}
+	if response.Headers == nil {
+		response.Headers = []NameValue{}
+	}
+	if response.Content == nil {
+		response.Content = &Content{}
+	}
+	if response.Content.MimeType == "" {
+		response.Content.MimeType = "application/octet-stream"
+	}
+	if response.HeadersSize == 0 {
+		response.HeadersSize = -1
+	}
+	if response.BodySize == 0 && response.Content.Size == 0 {
+		response.BodySize = -1
+	}
+}
@@ -0,0 +1,170 @@
+package har
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEntry(t *testing.T) {
+	tests := []struct {
+		Name            string
+		Data            string
+		Service         string
+		URL             string
+		StartedDateTime string
+		Err             bool
+	}{
+		{
+			Name:            "absolute URL",
+			Data:            `{"proto":{"name":"http"},"dst":{"name":"checkout","ip":"10.0.0.1","port":"80"},"timestamp":1700000000123,"request":{"method":"GET","url":"http://checkout/cart"},"response":{"status":200}}`,
+			Service:         "checkout",
+			URL:             "http://checkout/cart",
+			StartedDateTime: "2023-11-14T22:13:20.123Z",
+		},
+		{
+			Name:            "wrapped entry",
+			Data:            `{"data":{"proto":{"name":"http"},"dst":{"name":"checkout"},"timestamp":1700000000000,"request":{"url":"/cart"},"response":{}}}`,
+			Service:         "checkout",
+			URL:             "http://checkout/cart",
+			StartedDateTime: "2023-11-14T22:13:20.000Z",
+		},
+		{
+			Name:            "host header",
+			Data:            `{"dst":{"name":"checkout"},"timestamp":1700000000000,"request":{"url":"cart","headers":[{"name":"Host","value":"shop.example.com"}]},"response":{}}`,
+			Service:         "checkout",
+			URL:             "http://shop.example.com/cart",
+			StartedDateTime: "2023-11-14T22:13:20.000Z",
+		},
+		{
+			Name:            "unresolved service",
+			Data:            `{"dst":{"ip":"10.0.0.1","port":"8080"},"timestamp":1700000000000,"request":{"url":"/cart"},"response":{}}`,
+			Service:         "10.0.0.1_8080",
+			URL:             "http://10.0.0.1:8080/cart",
+			StartedDateTime: "2023-11-14T22:13:20.000Z",
+		},
+		{
+			Name: "not an HTTP entry",
+			Data: `{"proto":{"name":"redis"},"request":{},"response":{}}`,
+			Err:  true,
+		},
+		{
+			Name: "no response",
+			Data: `{"proto":{"name":"http"},"request":{"url":"/cart"}}`,
+			Err:  true,
+		},
+		{
+			Name: "invalid JSON",
+			Data: `{`,
+			Err:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			entry, service, err := ParseEntry([]byte(test.Data))
+
+			if test.Err {
+				if err == nil {
+					t.Errorf("unexpected result - expected: an error, actual: nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error - %v", err)
+			}
+
+			if service != test.Service {
+				t.Errorf("unexpected service - expected: %v, actual: %v", test.Service, service)
+			}
+
+			if entry.Request.URL != test.URL {
+				t.Errorf("unexpected URL - expected: %v, actual: %v", test.URL, entry.Request.URL)
+			}
+
+			if entry.StartedDateTime != test.StartedDateTime {
+				t.Errorf("unexpected start time - expected: %v, actual: %v", test.StartedDateTime, entry.StartedDateTime)
+			}
+
+			if entry.Request.HTTPVersion == "" || entry.Request.Headers == nil || entry.Response.Content == nil || entry.Response.Content.MimeType == "" {
+				t.Errorf("unexpected entry - the HAR defaults are not filled: %+v", entry)
+			}
+		})
+	}
+}
+
+func TestServices(t *testing.T) {
+	entries := []struct {
+		Service         string
+		StartedDateTime string
+	}{
+		{Service: "checkout", StartedDateTime: "2023-11-14T22:13:20.500Z"},
+		{Service: "payments", StartedDateTime: "2023-11-14T22:13:21.000Z"},
+		{Service: "checkout", StartedDateTime: "2023-11-14T22:13:20.050Z"},
+		{Service: "checkout", StartedDateTime: "2023-11-14T22:13:20.100Z"},
+	}
+
+	services := make(Services)
+	for _, entry := range entries {
+		services.Add(entry.Service, &Entry{StartedDateTime: entry.StartedDateTime})
+	}
+	services.Sort()
+
+	expected := map[string][]string{
+		"checkout": {"2023-11-14T22:13:20.050Z", "2023-11-14T22:13:20.100Z", "2023-11-14T22:13:20.500Z"},
+		"payments": {"2023-11-14T22:13:21.000Z"},
+	}
+
+	actual := make(map[string][]string)
+	for service, h := range services {
+		for _, entry := range h.Log.Entries {
+			actual[service] = append(actual[service], entry.StartedDateTime)
+		}
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected entries - expected: %v, actual: %v", expected, actual)
+	}
+}
+
+func TestServicesFileNames(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Services []string
+		Expected map[string]string
+	}{
+		{
+			Name:     "distinct names",
+			Services: []string{"checkout", "payments.prod"},
+			Expected: map[string]string{"checkout": "checkout.har", "payments.prod": "payments.prod.har"},
+		},
+		{
+			Name:     "sanitized names",
+			Services: []string{"10.0.0.1:8080", ""},
+			Expected: map[string]string{"10.0.0.1:8080": "10.0.0.1_8080.har", "": ".har"},
+		},
+		{
+			Name:     "colliding names",
+			Services: []string{"a:b", "a b", "a_b"},
+			Expected: map[string]string{"a b": "a_b.har", "a:b": "a_b_2.har", "a_b": "a_b_3.har"},
+		},
+		{
+			Name:     "colliding with a suffix",
+			Services: []string{"a b", "a_b", "a_b_2"},
+			Expected: map[string]string{"a b": "a_b.har", "a_b": "a_b_2.har", "a_b_2": "a_b_2_2.har"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			services := make(Services)
+			for _, service := range test.Services {
+				services.Add(service, &Entry{})
+			}
+
+			if actual := services.FileNames(); !reflect.DeepEqual(actual, test.Expected) {
+				t.Errorf("unexpected file names - expected: %v, actual: %v", test.Expected, actual)
+			}
+		})
+	}
+}
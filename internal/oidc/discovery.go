@@ -0,0 +1,96 @@
+package oidc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kubeshark/kubeshark/config/configStructs"
+	"github.com/kubeshark/kubeshark/utils"
+)
+
+const (
+	DiscoveryPath    = "/.well-known/openid-configuration"
+	DiscoveryTimeout = 10 * time.Second
+)
+
+// DiscoveryDocument is the part of the OpenID Provider Metadata that the Hub relies on.
+// https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+type DiscoveryDocument struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JwksUri               string   `json:"jwks_uri"`
+	ScopesSupported       []string `json:"scopes_supported"`
+	ClaimsSupported       []string `json:"claims_supported"`
+}
+
+// Discover fetches the discovery document of the issuer.
+func Discover(issuer string) (*DiscoveryDocument, error) {
+	discoveryUrl := strings.TrimSuffix(issuer, "/") + DiscoveryPath
+
+	client := &http.Client{Timeout: DiscoveryTimeout}
+	response, err := client.Get(discoveryUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the discovery document %s responded with status %d", discoveryUrl, response.StatusCode)
+	}
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var document DiscoveryDocument
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("the discovery document %s is invalid, %w", discoveryUrl, err)
+	}
+
+	return &document, nil
+}
+
+// Check checks the OIDC config against the discovery document. It returns the warnings about
+// what the provider may still support without declaring it, e.g. a custom claim of the roles.
+func (document *DiscoveryDocument) Check(config *configStructs.OidcConfig) (warnings []string, err error) {
+	var errs []error
+
+	if strings.TrimSuffix(document.Issuer, "/") != strings.TrimSuffix(config.Issuer, "/") {
+		errs = append(errs, fmt.Errorf("the issuer of the discovery document is %q, expected %q", document.Issuer, config.Issuer))
+	}
+
+	for _, endpoint := range []struct {
+		name string
+		url  string
+	}{
+		{name: "authorization_endpoint", url: document.AuthorizationEndpoint},
+		{name: "token_endpoint", url: document.TokenEndpoint},
+		{name: "jwks_uri", url: document.JwksUri},
+	} {
+		if endpoint.url == "" {
+			errs = append(errs, fmt.Errorf("the discovery document has no %s", endpoint.name))
+		}
+	}
+
+	if len(document.ScopesSupported) > 0 {
+		for _, scope := range config.Scopes {
+			if !utils.Contains(document.ScopesSupported, scope) {
+				errs = append(errs, fmt.Errorf("the scope %q is not supported by the provider, expected one of %s", scope, strings.Join(document.ScopesSupported, ",")))
+			}
+		}
+	}
+
+	if len(document.ClaimsSupported) > 0 && !utils.Contains(document.ClaimsSupported, config.RolesClaim) {
+		warnings = append(warnings, fmt.Sprintf("the claim %q is not declared by the provider, make sure that it's mapped onto the ID token", config.RolesClaim))
+	}
+
+	err = errors.Join(errs...)
+	return
+}
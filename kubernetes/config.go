@@ -7,22 +7,34 @@ import (
 	"github.com/rs/zerolog/log"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
 )
 
 const (
-	SUFFIX_SECRET                     = "secret"
-	SUFFIX_CONFIG_MAP                 = "config-map"
-	SECRET_LICENSE                    = "LICENSE"
-	CONFIG_POD_REGEX                  = "POD_REGEX"
-	CONFIG_NAMESPACES                 = "NAMESPACES"
-	CONFIG_EXCLUDED_NAMESPACES        = "EXCLUDED_NAMESPACES"
-	CONFIG_SCRIPTING_ENV              = "SCRIPTING_ENV"
-	CONFIG_INGRESS_ENABLED            = "INGRESS_ENABLED"
-	CONFIG_INGRESS_HOST               = "INGRESS_HOST"
-	CONFIG_PROXY_FRONT_PORT           = "PROXY_FRONT_PORT"
-	CONFIG_AUTH_ENABLED               = "AUTH_ENABLED"
-	CONFIG_AUTH_TYPE                  = "AUTH_TYPE"
-	CONFIG_AUTH_SAML_IDP_METADATA_URL = "AUTH_SAML_IDP_METADATA_URL"
+	SUFFIX_SECRET                          = "secret"
+	SUFFIX_CONFIG_MAP                      = "config-map"
+	SECRET_LICENSE                         = "LICENSE"
+	CONFIG_POD_REGEX                       = "POD_REGEX"
+	CONFIG_NAMESPACES                      = "NAMESPACES"
+	CONFIG_EXCLUDED_NAMESPACES             = "EXCLUDED_NAMESPACES"
+	CONFIG_LABEL_SELECTOR                  = "LABEL_SELECTOR"
+	CONFIG_WORKLOADS                       = "WORKLOADS"
+	CONFIG_NAMESPACE_SELECTOR              = "NAMESPACE_SELECTOR"
+	CONFIG_STOPPED                         = "STOPPED"
+	CONFIG_PAUSED_UNTIL                    = "PAUSED_UNTIL"
+	CONFIG_STOP_TRAFFIC_CAPTURING_DISABLED = "STOP_TRAFFIC_CAPTURING_DISABLED"
+	CONFIG_SCRIPTING_ENV                   = "SCRIPTING_ENV"
+	CONFIG_INGRESS_ENABLED                 = "INGRESS_ENABLED"
+	CONFIG_INGRESS_HOST                    = "INGRESS_HOST"
+	CONFIG_PROXY_FRONT_PORT                = "PROXY_FRONT_PORT"
+	CONFIG_AUTH_ENABLED                    = "AUTH_ENABLED"
+	CONFIG_AUTH_TYPE                       = "AUTH_TYPE"
+	CONFIG_AUTH_SAML_IDP_METADATA_URL      = "AUTH_SAML_IDP_METADATA_URL"
+	CONFIG_AUTH_OIDC_ISSUER                = "AUTH_OIDC_ISSUER"
+	CONFIG_AUTH_OIDC_CLIENT_ID             = "AUTH_OIDC_CLIENT_ID"
+	CONFIG_AUTH_OIDC_SCOPES                = "AUTH_OIDC_SCOPES"
+	CONFIG_AUTH_OIDC_ROLES_CLAIM           = "AUTH_OIDC_ROLES_CLAIM"
+	CONFIG_AUTH_OIDC_ROLES                 = "AUTH_OIDC_ROLES"
 )
 
 func SetSecret(provider *Provider, key string, value string) (updated bool, err error) {
@@ -40,7 +52,7 @@ func SetSecret(provider *Provider, key string, value string) (updated bool, err
 	_, err = provider.clientSet.CoreV1().Secrets(config.Config.Tap.Release.Namespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
 	if err == nil {
 		if updated {
-			log.Info().Str("secret", key).Str("value", value).Msg("Updated:")
+			log.Info().Str("secret", key).Msg("Updated:")
 		}
 	} else {
 		log.Error().Str("secret", key).Err(err).Send()
@@ -70,3 +82,25 @@ func SetConfig(provider *Provider, key string, value string) (updated bool, err
 	}
 	return
 }
+
+// UpdateConfig applies the update to the data of the config map. The update is applied again to the new data if
+// the config map is changed meanwhile by someone else, so it must not have side effects other than on the data.
+func UpdateConfig(provider *Provider, update func(data map[string]string) error) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap, err := provider.clientSet.CoreV1().ConfigMaps(config.Config.Tap.Release.Namespace).Get(context.TODO(), SELF_RESOURCES_PREFIX+SUFFIX_CONFIG_MAP, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		if configMap.Data == nil {
+			configMap.Data = make(map[string]string)
+		}
+
+		if err := update(configMap.Data); err != nil {
+			return err
+		}
+
+		_, err = provider.clientSet.CoreV1().ConfigMaps(config.Config.Tap.Release.Namespace).Update(context.TODO(), configMap, metav1.UpdateOptions{})
+		return err
+	})
+}
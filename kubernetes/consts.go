@@ -6,6 +6,11 @@ const (
 	FrontServiceName           = FrontPodName
 	HubPodName                 = SELF_RESOURCES_PREFIX + "hub"
 	HubServiceName             = HubPodName
+	WorkerDaemonSetName        = SELF_RESOURCES_PREFIX + "worker-daemon-set"
+	WorkerPodName              = WorkerDaemonSetName
+	WorkerContainerName        = "sniffer"
+	AppLabelKey                = "app.kubeshark.co/app"
+	WorkerDataPath             = "/app/data"
 	K8sAllNamespaces           = ""
 	MinKubernetesServerVersion = "1.16.0"
 )
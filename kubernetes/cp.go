@@ -18,7 +18,6 @@ import (
 )
 
 func CopyFromPod(ctx context.Context, provider *Provider, pod v1.Pod, srcPath string, dstPath string) error {
-	const containerName = "sniffer"
 	cmdArr := []string{"tar", "cf", "-", srcPath}
 	req := provider.clientSet.CoreV1().RESTClient().
 		Post().
@@ -27,9 +26,9 @@ func CopyFromPod(ctx context.Context, provider *Provider, pod v1.Pod, srcPath st
 		Name(pod.Name).
 		SubResource("exec").
 		VersionedParams(&v1.PodExecOptions{
-			Container: containerName,
+			Container: WorkerContainerName,
 			Command:   cmdArr,
-			Stdin:     true,
+			Stdin:     false,
 			Stdout:    true,
 			Stderr:    true,
 			TTY:       false,
@@ -43,10 +42,12 @@ func CopyFromPod(ctx context.Context, provider *Provider, pod v1.Pod, srcPath st
 	reader, outStream := io.Pipe()
 	errReader, errStream := io.Pipe()
 	go logErrors(errReader, pod)
+	execErrChan := make(chan error, 1)
 	go func() {
 		defer outStream.Close()
-		err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{
-			Stdin:  os.Stdin,
+		defer errStream.Close()
+		// The copies from several pods can run in parallel, so stdin is not shared with them.
+		err := exec.StreamWithContext(ctx, remotecommand.StreamOptions{
 			Stdout: outStream,
 			Stderr: errStream,
 			Tty:    false,
@@ -54,15 +55,20 @@ func CopyFromPod(ctx context.Context, provider *Provider, pod v1.Pod, srcPath st
 		if err != nil {
 			log.Error().Err(err).Str("pod", pod.Name).Msg("SPDYExecutor:")
 		}
+		execErrChan <- err
 	}()
 
 	prefix := getPrefix(srcPath)
 	prefix = path.Clean(prefix)
 	prefix = stripPathShortcuts(prefix)
 	dstPath = path.Join(dstPath, path.Base(prefix))
-	err = untarAll(reader, dstPath, prefix)
+	if err = untarAll(reader, dstPath, prefix); err != nil {
+		// Unblock the executor that might still be writing into the pipe.
+		reader.CloseWithError(err)
+		return err
+	}
 	// fo(reader)
-	return err
+	return <-execErrChan
 }
 
 // func fo(fi io.Reader) {
@@ -94,7 +100,9 @@ func logErrors(reader io.Reader, pod v1.Pod) {
 	r := bufio.NewReader(reader)
 	for {
 		msg, _, err := r.ReadLine()
-		log.Warn().Str("pod", pod.Name).Str("msg", string(msg)).Msg("SPDYExecutor:")
+		if len(msg) > 0 {
+			log.Warn().Str("pod", pod.Name).Str("msg", string(msg)).Msg("SPDYExecutor:")
+		}
 		if err != nil {
 			if err != io.EOF {
 				log.Error().Err(err).Send()
@@ -160,6 +168,9 @@ func untarAll(reader io.Reader, destDir, prefix string) error {
 			if err := outFile.Close(); err != nil {
 				return err
 			}
+			if err := os.Chtimes(destFileName, header.ModTime, header.ModTime); err != nil {
+				return err
+			}
 		}
 	}
 
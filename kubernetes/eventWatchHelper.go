@@ -9,6 +9,8 @@ import (
 	"k8s.io/apimachinery/pkg/watch"
 )
 
+// EventWatchHelper filters the events by the name of the object they regard and by its kind.
+// An empty kind matches the events of any kind.
 type EventWatchHelper struct {
 	kubernetesProvider *Provider
 	NameRegexFilter    *regexp.Regexp
@@ -32,7 +34,7 @@ func (wh *EventWatchHelper) Filter(wEvent *WatchEvent) (bool, error) {
 	if !wh.NameRegexFilter.MatchString(event.Name) {
 		return false, nil
 	}
-	if !strings.EqualFold(event.Regarding.Kind, wh.Kind) {
+	if wh.Kind != "" && !strings.EqualFold(event.Regarding.Kind, wh.Kind) {
 		return false, nil
 	}
 
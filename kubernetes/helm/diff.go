@@ -0,0 +1,100 @@
+package helm
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/kubeshark/kubeshark/config"
+)
+
+type ValueChange struct {
+	Path string
+	Old  interface{}
+	New  interface{}
+}
+
+func (change *ValueChange) IsAdded() bool {
+	return change.Old == nil
+}
+
+func (change *ValueChange) IsRemoved() bool {
+	return change.New == nil
+}
+
+// IsSensitive tells whether the value is a secret, which is not shown in a diff, only that it has changed.
+func (change *ValueChange) IsSensitive() bool {
+	for _, path := range config.SecretPaths() {
+		if change.Path == path {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (change *ValueChange) String() string {
+	switch {
+	case change.IsSensitive():
+		return fmt.Sprintf("%s: (sensitive value)", change.Path)
+	case change.IsAdded():
+		return fmt.Sprintf("%s: %v", change.Path, change.New)
+	case change.IsRemoved():
+		return fmt.Sprintf("%s: %v", change.Path, change.Old)
+	default:
+		return fmt.Sprintf("%s: %v -> %v", change.Path, change.Old, change.New)
+	}
+}
+
+// DiffValues compares the values of two releases leaf by leaf. The lists are compared as a whole.
+func DiffValues(oldValues map[string]interface{}, newValues map[string]interface{}) []*ValueChange {
+	oldLeaves := make(map[string]interface{})
+	flattenValues("", oldValues, oldLeaves)
+	newLeaves := make(map[string]interface{})
+	flattenValues("", newValues, newLeaves)
+
+	var changes []*ValueChange
+	for path, oldValue := range oldLeaves {
+		newValue, ok := newLeaves[path]
+		if !ok {
+			changes = append(changes, &ValueChange{Path: path, Old: oldValue})
+			continue
+		}
+
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, &ValueChange{Path: path, Old: oldValue, New: newValue})
+		}
+	}
+
+	for path, newValue := range newLeaves {
+		if _, ok := oldLeaves[path]; !ok {
+			changes = append(changes, &ValueChange{Path: path, New: newValue})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes
+}
+
+func flattenValues(prefix string, values map[string]interface{}, leaves map[string]interface{}) {
+	for key, value := range values {
+		path := key
+		if prefix != "" {
+			path = fmt.Sprintf("%s.%s", prefix, key)
+		}
+
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			flattenValues(path, nested, leaves)
+			continue
+		}
+
+		if value == nil {
+			continue
+		}
+
+		leaves[path] = value
+	}
+}
@@ -3,12 +3,16 @@ package helm
 import (
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/kubeshark/kubeshark/config"
+	"github.com/kubeshark/kubeshark/config/configStructs"
+	helmchart "github.com/kubeshark/kubeshark/helm-chart"
 	"github.com/kubeshark/kubeshark/misc"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
@@ -21,7 +25,9 @@ import (
 	"helm.sh/helm/v3/pkg/kube"
 	"helm.sh/helm/v3/pkg/registry"
 	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
 	"helm.sh/helm/v3/pkg/repo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const ENV_HELM_DRIVER = "HELM_DRIVER"
@@ -54,87 +60,212 @@ func parseOCIRef(chartRef string) (string, string, error) {
 	return chartRef, tag, nil
 }
 
-func (h *Helm) Install() (rel *release.Release, err error) {
+func (h *Helm) newActionConfig(logf action.DebugLog) (*action.Configuration, error) {
 	kubeConfigPath := config.Config.KubeConfigPath()
 	actionConfig := new(action.Configuration)
-	if err = actionConfig.Init(kube.GetConfig(kubeConfigPath, "", h.releaseNamespace), h.releaseNamespace, os.Getenv(ENV_HELM_DRIVER), func(format string, v ...interface{}) {
-		log.Info().Msgf(format, v...)
-	}); err != nil {
+	if err := actionConfig.Init(kube.GetConfig(kubeConfigPath, config.Config.Kube.Context, h.releaseNamespace), h.releaseNamespace, os.Getenv(ENV_HELM_DRIVER), logf); err != nil {
+		return nil, err
+	}
+
+	if err := setRegistryClient(actionConfig); err != nil {
+		return nil, err
+	}
+
+	return actionConfig, nil
+}
+
+// setRegistryClient sets the client of the OCI registry, if the chart is pulled from one.
+func setRegistryClient(actionConfig *action.Configuration) error {
+	if config.Config.Tap.Release.Source != configStructs.ReleaseSourceOci {
+		return nil
+	}
+
+	registryClient, err := registry.NewClient(registry.ClientOptCredentialsFile(settings.RegistryConfig))
+	if err != nil {
+		return err
+	}
+	actionConfig.RegistryClient = registryClient
+
+	return nil
+}
+
+func logInfo(format string, v ...interface{}) {
+	log.Info().Msgf(format, v...)
+}
+
+func logDebug(format string, v ...interface{}) {
+	log.Debug().Msgf(format, v...)
+}
+
+// loadChart loads the chart from the release.source. A path in the KUBESHARK_HELM_CHART_PATH environment
+// variable takes precedence over it. The chart must match the release.version if it's pinned.
+func (h *Helm) loadChart(chartPathOptions *action.ChartPathOptions) (loaded *chart.Chart, err error) {
+	releaseConfig := config.Config.Tap.Release
+
+	if chartPath := os.Getenv(fmt.Sprintf("%s_HELM_CHART_PATH", strings.ToUpper(misc.Program))); chartPath != "" {
+		loaded, err = loader.Load(chartPath)
+	} else {
+		switch releaseConfig.Source {
+		case configStructs.ReleaseSourceEmbedded:
+			loaded, err = loadEmbeddedChart()
+		case configStructs.ReleaseSourcePath:
+			loaded, err = loader.Load(releaseConfig.Path)
+		case configStructs.ReleaseSourceOci:
+			var chartPath string
+			chartPathOptions.Version = releaseConfig.Version
+			chartPath, err = chartPathOptions.LocateChart(h.repo, settings)
+			if err != nil {
+				return
+			}
+			loaded, err = loader.Load(chartPath)
+		default:
+			loaded, err = h.downloadChart(chartPathOptions, releaseConfig.Version)
+		}
+	}
+	if err != nil {
 		return
 	}
 
-	client := action.NewInstall(actionConfig)
-	client.Namespace = h.releaseNamespace
-	client.ReleaseName = h.releaseName
+	if releaseConfig.Version != "" && loaded.Metadata.Version != releaseConfig.Version {
+		err = fmt.Errorf("the chart version %s does not match the pinned release.version %s", loaded.Metadata.Version, releaseConfig.Version)
+		return
+	}
 
-	chartPath := os.Getenv(fmt.Sprintf("%s_HELM_CHART_PATH", strings.ToUpper(misc.Program)))
-	if chartPath == "" {
-		var chartURL string
-		chartURL, err = repo.FindChartInRepoURL(h.repo, h.releaseName, "", "", "", "", getter.All(&cli.EnvSettings{}))
-		if err != nil {
-			return
+	return
+}
+
+// loadEmbeddedChart loads the chart that's embedded in the binary, which is the chart of the same version as the CLI.
+func loadEmbeddedChart() (*chart.Chart, error) {
+	var files []*loader.BufferedFile
+	err := fs.WalkDir(helmchart.FS, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
 		}
 
-		var cp string
-		cp, err = client.ChartPathOptions.LocateChart(chartURL, settings)
+		data, err := helmchart.FS.ReadFile(path)
 		if err != nil {
-			return
+			return err
 		}
 
-		m := &downloader.Manager{
-			Out:              os.Stdout,
-			ChartPath:        cp,
-			Keyring:          client.ChartPathOptions.Keyring,
-			SkipUpdate:       false,
-			Getters:          getter.All(settings),
-			RepositoryConfig: settings.RepositoryConfig,
-			RepositoryCache:  settings.RepositoryCache,
-			Debug:            settings.Debug,
-		}
+		files = append(files, &loader.BufferedFile{Name: path, Data: data})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		dl := downloader.ChartDownloader{
-			Out:              m.Out,
-			Verify:           m.Verify,
-			Keyring:          m.Keyring,
-			RepositoryConfig: m.RepositoryConfig,
-			RepositoryCache:  m.RepositoryCache,
-			RegistryClient:   m.RegistryClient,
-			Getters:          m.Getters,
-			Options: []getter.Option{
-				getter.WithInsecureSkipVerifyTLS(false),
-			},
-		}
+	return loader.LoadFiles(files)
+}
+
+// downloadChart downloads the chart from the Helm repository, the latest version unless it's pinned.
+func (h *Helm) downloadChart(chartPathOptions *action.ChartPathOptions, version string) (loaded *chart.Chart, err error) {
+	var chartURL string
+	chartURL, err = repo.FindChartInRepoURL(h.repo, h.releaseName, version, "", "", "", getter.All(&cli.EnvSettings{}))
+	if err != nil {
+		return
+	}
+
+	var cp string
+	cp, err = chartPathOptions.LocateChart(chartURL, settings)
+	if err != nil {
+		return
+	}
+
+	m := &downloader.Manager{
+		Out:              os.Stdout,
+		ChartPath:        cp,
+		Keyring:          chartPathOptions.Keyring,
+		SkipUpdate:       false,
+		Getters:          getter.All(settings),
+		RepositoryConfig: settings.RepositoryConfig,
+		RepositoryCache:  settings.RepositoryCache,
+		Debug:            settings.Debug,
+	}
+
+	dl := downloader.ChartDownloader{
+		Out:              m.Out,
+		Verify:           m.Verify,
+		Keyring:          m.Keyring,
+		RepositoryConfig: m.RepositoryConfig,
+		RepositoryCache:  m.RepositoryCache,
+		RegistryClient:   m.RegistryClient,
+		Getters:          m.Getters,
+		Options: []getter.Option{
+			getter.WithInsecureSkipVerifyTLS(false),
+		},
+	}
+
+	repoPath := filepath.Dir(m.ChartPath)
+	err = os.MkdirAll(repoPath, os.ModePerm)
+	if err != nil {
+		return
+	}
 
-		repoPath := filepath.Dir(m.ChartPath)
-		err = os.MkdirAll(repoPath, os.ModePerm)
+	tag := ""
+	if registry.IsOCI(chartURL) {
+		chartURL, tag, err = parseOCIRef(chartURL)
 		if err != nil {
 			return
 		}
+		dl.Options = append(dl.Options,
+			getter.WithRegistryClient(m.RegistryClient),
+			getter.WithTagName(tag))
+	}
 
-		version := ""
-		if registry.IsOCI(chartURL) {
-			chartURL, version, err = parseOCIRef(chartURL)
-			if err != nil {
-				return
-			}
-			dl.Options = append(dl.Options,
-				getter.WithRegistryClient(m.RegistryClient),
-				getter.WithTagName(version))
-		}
+	log.Info().
+		Str("url", chartURL).
+		Str("repo-path", repoPath).
+		Msg("Downloading Helm chart:")
 
-		log.Info().
-			Str("url", chartURL).
-			Str("repo-path", repoPath).
-			Msg("Downloading Helm chart:")
+	if _, _, err = dl.DownloadTo(chartURL, tag, repoPath); err != nil {
+		return
+	}
 
-		if _, _, err = dl.DownloadTo(chartURL, version, repoPath); err != nil {
-			return
-		}
+	return loader.Load(m.ChartPath)
+}
 
-		chartPath = m.ChartPath
+// ConfigValues returns the config as the values of the chart, with the images rewritten to the release.imageRegistry.
+func ConfigValues() (values map[string]interface{}, err error) {
+	chartConfig := config.Config
+	if imageRegistry := chartConfig.Tap.Release.ImageRegistry; imageRegistry != "" {
+		chartConfig.Tap.Docker.Registry = imageRegistry
+		chartConfig.Tap.KernelModule.Image = RewriteImageRegistry(chartConfig.Tap.KernelModule.Image, imageRegistry)
 	}
+
+	var configMarshalled []byte
+	configMarshalled, err = json.Marshal(chartConfig)
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(configMarshalled, &values)
+	return
+}
+
+// RewriteImageRegistry replaces the registry and the repository path of the image with the registry,
+// e.g. kubeshark/pf-ring-module:all becomes mirror.local/kubeshark/pf-ring-module:all for mirror.local/kubeshark.
+func RewriteImageRegistry(image string, registry string) string {
+	name := image
+	if i := strings.LastIndex(image, "/"); i >= 0 {
+		name = image[i+1:]
+	}
+
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(registry, "/"), name)
+}
+
+func (h *Helm) Install() (rel *release.Release, err error) {
+	var actionConfig *action.Configuration
+	actionConfig, err = h.newActionConfig(logInfo)
+	if err != nil {
+		return
+	}
+
+	client := action.NewInstall(actionConfig)
+	client.Namespace = h.releaseNamespace
+	client.ReleaseName = h.releaseName
+
 	var chart *chart.Chart
-	chart, err = loader.Load(chartPath)
+	chart, err = h.loadChart(&client.ChartPathOptions)
 	if err != nil {
 		return
 	}
@@ -146,36 +277,144 @@ func (h *Helm) Install() (rel *release.Release, err error) {
 		Str("kube-version", chart.Metadata.KubeVersion).
 		Msg("Installing using Helm:")
 
-	var configMarshalled []byte
-	configMarshalled, err = json.Marshal(config.Config)
+	var values map[string]interface{}
+	values, err = ConfigValues()
 	if err != nil {
 		return
 	}
 
-	var configUnmarshalled map[string]interface{}
-	err = json.Unmarshal(configMarshalled, &configUnmarshalled)
+	rel, err = client.Run(chart, values)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// Template renders the chart with the config like `helm template`, without any access to the cluster.
+// The manifests are returned in the order of the installation, the hooks first.
+func (h *Helm) Template() (manifests []releaseutil.Manifest, err error) {
+	actionConfig := &action.Configuration{Log: logDebug}
+	if err = setRegistryClient(actionConfig); err != nil {
+		return
+	}
+
+	client := action.NewInstall(actionConfig)
+	client.Namespace = h.releaseNamespace
+	client.ReleaseName = h.releaseName
+	client.DryRun = true
+	client.ClientOnly = true
+	client.Replace = true
+	client.IncludeCRDs = true
+
+	var chart *chart.Chart
+	chart, err = h.loadChart(&client.ChartPathOptions)
 	if err != nil {
 		return
 	}
 
-	rel, err = client.Run(chart, configUnmarshalled)
+	var values map[string]interface{}
+	values, err = ConfigValues()
 	if err != nil {
 		return
 	}
 
+	var rel *release.Release
+	rel, err = client.Run(chart, values)
+	if err != nil {
+		return
+	}
+
+	for _, hook := range rel.Hooks {
+		manifests = append(manifests, releaseutil.Manifest{Name: hook.Path, Content: hook.Manifest})
+	}
+
+	var sorted []releaseutil.Manifest
+	_, sorted, err = releaseutil.SortManifests(releaseutil.SplitManifests(rel.Manifest), actionConfig.Capabilities.APIVersions, releaseutil.InstallOrder)
+	if err != nil {
+		return
+	}
+	manifests = append(manifests, sorted...)
+
 	return
 }
 
+// Upgrade upgrades the release with the full config as its values, so nothing of the previous values is reused.
+func (h *Helm) Upgrade() (rel *release.Release, err error) {
+	var actionConfig *action.Configuration
+	actionConfig, err = h.newActionConfig(logInfo)
+	if err != nil {
+		return
+	}
+
+	client := action.NewUpgrade(actionConfig)
+	client.Namespace = h.releaseNamespace
+	client.ResetValues = true
+
+	var chart *chart.Chart
+	chart, err = h.loadChart(&client.ChartPathOptions)
+	if err != nil {
+		return
+	}
+
+	log.Info().
+		Str("release", chart.Metadata.Name).
+		Str("version", chart.Metadata.Version).
+		Strs("source", chart.Metadata.Sources).
+		Str("kube-version", chart.Metadata.KubeVersion).
+		Msg("Upgrading using Helm:")
+
+	var values map[string]interface{}
+	values, err = ConfigValues()
+	if err != nil {
+		return
+	}
+
+	rel, err = client.Run(h.releaseName, chart, values)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// Rollback rolls the release back to the revision, or to the previous one if the revision is zero.
+func (h *Helm) Rollback(revision int) (err error) {
+	var actionConfig *action.Configuration
+	actionConfig, err = h.newActionConfig(logInfo)
+	if err != nil {
+		return
+	}
+
+	client := action.NewRollback(actionConfig)
+	client.Version = revision
+
+	return client.Run(h.releaseName)
+}
+
 func (h *Helm) Uninstall() (resp *release.UninstallReleaseResponse, err error) {
-	kubeConfigPath := config.Config.KubeConfigPath()
-	actionConfig := new(action.Configuration)
-	if err = actionConfig.Init(kube.GetConfig(kubeConfigPath, "", h.releaseNamespace), h.releaseNamespace, os.Getenv(ENV_HELM_DRIVER), func(format string, v ...interface{}) {
-		log.Info().Msgf(format, v...)
-	}); err != nil {
+	return h.uninstall(false, 0)
+}
+
+// UninstallAndWait uninstalls the release like Uninstall, but the dependents of the resources, e.g. the pods of the
+// DaemonSet, are deleted before the resources, and it waits up to the timeout until all of these are deleted.
+func (h *Helm) UninstallAndWait(timeout time.Duration) (resp *release.UninstallReleaseResponse, err error) {
+	return h.uninstall(true, timeout)
+}
+
+func (h *Helm) uninstall(wait bool, timeout time.Duration) (resp *release.UninstallReleaseResponse, err error) {
+	var actionConfig *action.Configuration
+	actionConfig, err = h.newActionConfig(logInfo)
+	if err != nil {
 		return
 	}
 
 	client := action.NewUninstall(actionConfig)
+	if wait {
+		client.Wait = true
+		client.Timeout = timeout
+		client.DeletionPropagation = string(metav1.DeletePropagationForeground)
+	}
 
 	resp, err = client.Run(h.releaseName)
 	if err != nil {
@@ -184,3 +423,21 @@ func (h *Helm) Uninstall() (resp *release.UninstallReleaseResponse, err error) {
 
 	return
 }
+
+// Status returns the last revision of the release. The error wraps driver.ErrReleaseNotFound if there is no release.
+func (h *Helm) Status() (rel *release.Release, err error) {
+	var actionConfig *action.Configuration
+	actionConfig, err = h.newActionConfig(logDebug)
+	if err != nil {
+		return
+	}
+
+	client := action.NewStatus(actionConfig)
+
+	rel, err = client.Run(h.releaseName)
+	if err != nil {
+		return
+	}
+
+	return
+}
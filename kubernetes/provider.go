@@ -14,9 +14,9 @@ import (
 	"github.com/kubeshark/kubeshark/config"
 	"github.com/kubeshark/kubeshark/misc"
 	"github.com/kubeshark/kubeshark/semver"
-	"github.com/kubeshark/kubeshark/utils"
 	"github.com/rs/zerolog/log"
 	"github.com/tanqiangyes/grep-go/reader"
+	apps "k8s.io/api/apps/v1"
 	core "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -28,7 +28,7 @@ import (
 )
 
 type Provider struct {
-	clientSet        *kubernetes.Clientset
+	clientSet        kubernetes.Interface
 	kubernetesConfig clientcmd.ClientConfig
 	clientConfig     rest.Config
 	managedBy        string
@@ -115,21 +115,6 @@ func (provider *Provider) ListAllPodsMatchingRegex(ctx context.Context, regex *r
 	return provider.listPodsImpl(ctx, regex, namespaces, metav1.ListOptions{})
 }
 
-func (provider *Provider) ListAllRunningPodsMatchingRegex(ctx context.Context, regex *regexp.Regexp, namespaces []string) ([]core.Pod, error) {
-	pods, err := provider.ListAllPodsMatchingRegex(ctx, regex, namespaces)
-	if err != nil {
-		return nil, err
-	}
-
-	matchingPods := make([]core.Pod, 0)
-	for _, pod := range pods {
-		if IsPodRunning(&pod) {
-			matchingPods = append(matchingPods, pod)
-		}
-	}
-	return matchingPods, nil
-}
-
 func (provider *Provider) ListPodsByAppLabel(ctx context.Context, namespaces string, labels map[string]string) ([]core.Pod, error) {
 	pods, err := provider.clientSet.CoreV1().Pods(namespaces).List(ctx, metav1.ListOptions{
 		LabelSelector: metav1.FormatLabelSelector(
@@ -188,6 +173,63 @@ func (provider *Provider) GetNamespaceEvents(ctx context.Context, namespace stri
 	return eventList.String(), nil
 }
 
+// ListWarningEvents returns the events of the namespace that are of the warning type.
+func (provider *Provider) ListWarningEvents(ctx context.Context, namespace string) ([]core.Event, error) {
+	eventList, err := provider.clientSet.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("type=%s", core.EventTypeWarning),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting events on ns: %s, %w", namespace, err)
+	}
+
+	return eventList.Items, nil
+}
+
+func (provider *Provider) GetConfigMap(ctx context.Context, namespace string, name string) (*core.ConfigMap, error) {
+	return provider.clientSet.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+func (provider *Provider) GetSecretValue(ctx context.Context, namespace string, name string, key string) (string, error) {
+	secret, err := provider.clientSet.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := secret.Data[key]
+	if !ok {
+		return "", fmt.Errorf("the secret %s/%s has no key %s", namespace, name, key)
+	}
+
+	return string(value), nil
+}
+
+func (provider *Provider) GetDaemonSet(ctx context.Context, namespace string, name string) (*apps.DaemonSet, error) {
+	return provider.clientSet.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+// DeletePersistentVolumeClaims deletes the PersistentVolumeClaims that match the label selector, e.g. the ones of
+// a release that are left behind by its uninstall. It returns the names of the deleted claims.
+func (provider *Provider) DeletePersistentVolumeClaims(ctx context.Context, namespace string, labelSelector string) ([]string, error) {
+	claims, err := provider.clientSet.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []string
+	for _, claim := range claims.Items {
+		err := provider.clientSet.CoreV1().PersistentVolumeClaims(namespace).Delete(ctx, claim.Name, metav1.DeleteOptions{})
+		if k8serrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return deleted, fmt.Errorf("failed to delete the persistent volume claim %s in ns: [%s], %w", claim.Name, namespace, err)
+		}
+
+		deleted = append(deleted, claim.Name)
+	}
+
+	return deleted, nil
+}
+
 // ValidateNotProxy We added this after a customer tried to run kubeshark from lens, which used len's kube config, which have cluster server configuration, which points to len's local proxy.
 // The workaround was to use the user's local default kube config.
 // For now - we are blocking the option to run kubeshark through a proxy to k8s server
@@ -217,7 +259,7 @@ func (provider *Provider) ValidateNotProxy() error {
 }
 
 func (provider *Provider) GetKubernetesVersion() (*semver.SemVersion, error) {
-	serverVersion, err := provider.clientSet.ServerVersion()
+	serverVersion, err := provider.clientSet.Discovery().ServerVersion()
 	if err != nil {
 		log.Debug().Err(err).Msg("While getting Kubernetes server version!")
 		return nil, err
@@ -227,23 +269,13 @@ func (provider *Provider) GetKubernetesVersion() (*semver.SemVersion, error) {
 	return &serverVersionSemVer, nil
 }
 
+// GetNamespaces returns the targeted namespaces of the config.
 func (provider *Provider) GetNamespaces() (namespaces []string) {
-	if len(config.Config.Tap.Namespaces) > 0 {
-		namespaces = utils.Unique(config.Config.Tap.Namespaces)
-	} else {
-		namespaceList, err := provider.clientSet.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
-		if err != nil {
-			log.Error().Err(err).Send()
-			return
-		}
-
-		for _, ns := range namespaceList.Items {
-			namespaces = append(namespaces, ns.Name)
-		}
+	namespaces, err := provider.ListTargetedNamespaces(context.TODO(), config.Config.Tap.Namespaces, config.Config.Tap.ExcludedNamespaces, config.Config.Tap.NamespaceSelector)
+	if err != nil {
+		log.Error().Err(err).Send()
 	}
 
-	namespaces = utils.Diff(namespaces, config.Config.Tap.ExcludedNamespaces)
-
 	return
 }
 
@@ -285,3 +317,13 @@ func loadKubernetesConfiguration(kubeConfigPath string, context string) clientcm
 func IsPodRunning(pod *core.Pod) bool {
 	return pod.Status.Phase == core.PodRunning
 }
+
+func IsPodReady(pod *core.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == core.PodReady {
+			return condition.Status == core.ConditionTrue
+		}
+	}
+
+	return false
+}
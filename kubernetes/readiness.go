@@ -0,0 +1,208 @@
+package kubernetes
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"time"
+
+	core "k8s.io/api/core/v1"
+)
+
+// ComponentSpec describes a component, a pod that is identified by its name, that the ReadinessTracker waits for.
+type ComponentSpec struct {
+	Name      string
+	NameRegex *regexp.Regexp
+	Namespace string
+	// Condition is the pod condition that marks the component as ready. A running pod is ready when it is empty.
+	Condition core.PodConditionType
+	// Timeout bounds how long the component might stay not ready, initially and after its pod is recreated.
+	// There is no bound when it is zero.
+	Timeout time.Duration
+}
+
+func (spec *ComponentSpec) isPodReady(pod *core.Pod) bool {
+	if spec.Condition == "" {
+		return IsPodRunning(pod)
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == spec.Condition {
+			return condition.Status == core.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+type ComponentEventType string
+
+const (
+	ComponentAdded    ComponentEventType = "added"
+	ComponentReady    ComponentEventType = "ready"
+	ComponentNotReady ComponentEventType = "not-ready"
+	ComponentRemoved  ComponentEventType = "removed"
+	ComponentTimedOut ComponentEventType = "timed-out"
+	ComponentFailed   ComponentEventType = "failed"
+)
+
+type ComponentEvent struct {
+	Component string
+	Pod       string
+	Type      ComponentEventType
+	Err       error
+}
+
+// ReadinessTracker watches the pods of a set of components and reports their readiness. A component
+// is ready when any of its pods is ready, so a pod that is recreated is tracked the same way as the original.
+type ReadinessTracker struct {
+	kubernetesProvider *Provider
+	specs              []*ComponentSpec
+	ready              map[string]bool
+	events             chan *ComponentEvent
+	allReady           chan struct{}
+	sync.Mutex
+}
+
+func NewReadinessTracker(kubernetesProvider *Provider, specs ...*ComponentSpec) *ReadinessTracker {
+	return &ReadinessTracker{
+		kubernetesProvider: kubernetesProvider,
+		specs:              specs,
+		ready:              make(map[string]bool),
+		events:             make(chan *ComponentEvent, len(specs)*4),
+		allReady:           make(chan struct{}, 1),
+	}
+}
+
+// Events returns the channel of the changes of the components.
+func (tracker *ReadinessTracker) Events() <-chan *ComponentEvent {
+	return tracker.events
+}
+
+// AllReady returns a channel that receives every time all the components become ready, i.e. initially
+// and then again after any of them is recreated.
+func (tracker *ReadinessTracker) AllReady() <-chan struct{} {
+	return tracker.allReady
+}
+
+func (tracker *ReadinessTracker) IsReady(component string) bool {
+	tracker.Lock()
+	defer tracker.Unlock()
+
+	return tracker.ready[component]
+}
+
+// Start watches the components until the context is done.
+func (tracker *ReadinessTracker) Start(ctx context.Context) {
+	for _, spec := range tracker.specs {
+		go tracker.track(ctx, spec)
+	}
+}
+
+func (tracker *ReadinessTracker) track(ctx context.Context, spec *ComponentSpec) {
+	podWatchHelper := NewPodWatchHelper(tracker.kubernetesProvider, spec.NameRegex)
+	eventChan, errorChan := FilteredWatch(ctx, podWatchHelper, []string{spec.Namespace}, podWatchHelper)
+
+	pods := make(map[string]bool)
+	deadline := spec.deadline()
+	for {
+		select {
+		case wEvent, ok := <-eventChan:
+			if !ok {
+				eventChan = nil
+				continue
+			}
+
+			pod, err := wEvent.ToPod()
+			if err != nil {
+				tracker.emit(ctx, &ComponentEvent{Component: spec.Name, Type: ComponentFailed, Err: err})
+				continue
+			}
+
+			switch wEvent.Type {
+			case EventAdded, EventModified:
+				if _, ok := pods[pod.Name]; !ok {
+					tracker.emit(ctx, &ComponentEvent{Component: spec.Name, Pod: pod.Name, Type: ComponentAdded})
+				}
+				pods[pod.Name] = spec.isPodReady(pod)
+			case EventDeleted:
+				delete(pods, pod.Name)
+				tracker.emit(ctx, &ComponentEvent{Component: spec.Name, Pod: pod.Name, Type: ComponentRemoved})
+			default:
+				continue
+			}
+
+			isReady := false
+			for _, podReady := range pods {
+				isReady = isReady || podReady
+			}
+
+			if tracker.setReady(ctx, spec, pod.Name, isReady) {
+				if isReady {
+					deadline = nil
+				} else {
+					deadline = spec.deadline()
+				}
+			}
+		case err, ok := <-errorChan:
+			if !ok {
+				errorChan = nil
+				continue
+			}
+
+			tracker.emit(ctx, &ComponentEvent{Component: spec.Name, Type: ComponentFailed, Err: err})
+		case <-deadline:
+			deadline = nil
+			tracker.emit(ctx, &ComponentEvent{Component: spec.Name, Type: ComponentTimedOut})
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (spec *ComponentSpec) deadline() <-chan time.Time {
+	if spec.Timeout <= 0 {
+		return nil
+	}
+
+	return time.After(spec.Timeout)
+}
+
+// setReady records the readiness of a component and reports whether it has changed.
+func (tracker *ReadinessTracker) setReady(ctx context.Context, spec *ComponentSpec, podName string, isReady bool) bool {
+	tracker.Lock()
+	changed := tracker.ready[spec.Name] != isReady
+	tracker.ready[spec.Name] = isReady
+
+	allReady := true
+	for _, s := range tracker.specs {
+		allReady = allReady && tracker.ready[s.Name]
+	}
+	tracker.Unlock()
+
+	if !changed {
+		return false
+	}
+
+	if isReady {
+		tracker.emit(ctx, &ComponentEvent{Component: spec.Name, Pod: podName, Type: ComponentReady})
+	} else {
+		tracker.emit(ctx, &ComponentEvent{Component: spec.Name, Pod: podName, Type: ComponentNotReady})
+	}
+
+	if isReady && allReady {
+		select {
+		case tracker.allReady <- struct{}{}:
+		default:
+		}
+	}
+
+	return true
+}
+
+func (tracker *ReadinessTracker) emit(ctx context.Context, event *ComponentEvent) {
+	select {
+	case tracker.events <- event:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,258 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/kubeshark/kubeshark/config/configStructs"
+	"github.com/kubeshark/kubeshark/utils"
+	core "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PodTargets are the rules that select the targeted pods in the targeted namespaces. A pod is targeted if its name
+// matches the regex, its labels match the label selector and, if any workloads are given, it belongs to one of them.
+type PodTargets struct {
+	Regex         *regexp.Regexp
+	LabelSelector string
+	Workloads     []*configStructs.Workload
+}
+
+// TargetedPod is a pod along with the rules that it matched, e.g. deploy/checkout or -l app=checkout.
+type TargetedPod struct {
+	core.Pod
+	Rules []string
+}
+
+// ListTargetedPods lists the running pods that the targets select in the namespaces.
+func (provider *Provider) ListTargetedPods(ctx context.Context, targets *PodTargets, namespaces []string) ([]*TargetedPod, error) {
+	targetedPods := make([]*TargetedPod, 0)
+	for _, namespace := range namespaces {
+		pods, err := provider.clientSet.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: targets.LabelSelector})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pods in ns: [%s], %w", namespace, err)
+		}
+
+		var podWorkloads map[string][]string
+		if len(targets.Workloads) > 0 {
+			podWorkloads, err = provider.resolveWorkloadPods(ctx, namespace, targets.Workloads, pods.Items)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, pod := range pods.Items {
+			if !IsPodRunning(&pod) || !targets.Regex.MatchString(pod.Name) {
+				continue
+			}
+
+			var rules []string
+			if len(targets.Workloads) > 0 {
+				rules = podWorkloads[pod.Name]
+				if len(rules) == 0 {
+					continue
+				}
+			}
+
+			if targets.LabelSelector != "" {
+				rules = append(rules, fmt.Sprintf("-l %s", targets.LabelSelector))
+			}
+
+			if len(rules) == 0 || targets.Regex.String() != ".*" {
+				rules = append(rules, fmt.Sprintf("regex %s", targets.Regex.String()))
+			}
+
+			targetedPods = append(targetedPods, &TargetedPod{Pod: pod, Rules: rules})
+		}
+	}
+
+	return targetedPods, nil
+}
+
+// resolveWorkloadPods maps the names of the pods to the workloads that they belong to, through the owner references
+// of the pods, or the endpoints of the services.
+func (provider *Provider) resolveWorkloadPods(ctx context.Context, namespace string, workloads []*configStructs.Workload, pods []core.Pod) (map[string][]string, error) {
+	podWorkloads := make(map[string][]string)
+
+	var replicaSetOwners map[string]string
+	for _, workload := range workloads {
+		switch workload.Kind {
+		case configStructs.WorkloadKindService:
+			endpoints, err := provider.clientSet.CoreV1().Endpoints(namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+			if k8serrors.IsNotFound(err) {
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to get the endpoints of %s in ns: [%s], %w", workload, namespace, err)
+			}
+
+			for _, subset := range endpoints.Subsets {
+				for _, address := range append(subset.Addresses, subset.NotReadyAddresses...) {
+					if address.TargetRef != nil && address.TargetRef.Kind == "Pod" && !utils.Contains(podWorkloads[address.TargetRef.Name], workload.Ref) {
+						podWorkloads[address.TargetRef.Name] = append(podWorkloads[address.TargetRef.Name], workload.Ref)
+					}
+				}
+			}
+		case configStructs.WorkloadKindDeployment:
+			if replicaSetOwners == nil {
+				var err error
+				if replicaSetOwners, err = provider.listReplicaSetOwners(ctx, namespace); err != nil {
+					return nil, err
+				}
+			}
+
+			for _, pod := range pods {
+				if owner := metav1.GetControllerOf(&pod); owner != nil && owner.Kind == configStructs.WorkloadKindReplicaSet && replicaSetOwners[owner.Name] == workload.Name {
+					podWorkloads[pod.Name] = append(podWorkloads[pod.Name], workload.Ref)
+				}
+			}
+		default:
+			for _, pod := range pods {
+				if owner := metav1.GetControllerOf(&pod); owner != nil && owner.Kind == workload.Kind && owner.Name == workload.Name {
+					podWorkloads[pod.Name] = append(podWorkloads[pod.Name], workload.Ref)
+				}
+			}
+		}
+	}
+
+	return podWorkloads, nil
+}
+
+// listReplicaSetOwners maps the names of the ReplicaSets to the names of the Deployments that own them.
+func (provider *Provider) listReplicaSetOwners(ctx context.Context, namespace string) (map[string]string, error) {
+	replicaSets, err := provider.clientSet.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get replica sets in ns: [%s], %w", namespace, err)
+	}
+
+	owners := make(map[string]string)
+	for _, replicaSet := range replicaSets.Items {
+		if owner := metav1.GetControllerOf(&replicaSet); owner != nil && owner.Kind == configStructs.WorkloadKindDeployment {
+			owners[replicaSet.Name] = owner.Name
+		}
+	}
+
+	return owners, nil
+}
+
+// Targeting is the targeting of a running Kubeshark, as it's kept in its config map for the Hub.
+type Targeting struct {
+	Regex              string   `json:"regex"`
+	Namespaces         []string `json:"namespaces"`
+	ExcludedNamespaces []string `json:"excludedNamespaces"`
+	LabelSelector      string   `json:"labelSelector"`
+	Workloads          []string `json:"workloads"`
+	NamespaceSelector  string   `json:"namespaceSelector"`
+}
+
+func NewTargeting(tapConfig *configStructs.TapConfig) *Targeting {
+	return &Targeting{
+		Regex:              tapConfig.PodRegexStr,
+		Namespaces:         tapConfig.Namespaces,
+		ExcludedNamespaces: tapConfig.ExcludedNamespaces,
+		LabelSelector:      tapConfig.LabelSelector,
+		Workloads:          tapConfig.Workloads,
+		NamespaceSelector:  tapConfig.NamespaceSelector,
+	}
+}
+
+// Validate reports the invalid rules, like TapConfig.Validate does.
+func (targeting *Targeting) Validate() error {
+	tapConfig := configStructs.TapConfig{
+		PodRegexStr:       targeting.Regex,
+		LabelSelector:     targeting.LabelSelector,
+		Workloads:         targeting.Workloads,
+		NamespaceSelector: targeting.NamespaceSelector,
+	}
+
+	return tapConfig.ValidateTargeting()
+}
+
+// PodTargets returns the rules of the targeted pods.
+func (targeting *Targeting) PodTargets() (*PodTargets, error) {
+	regex, err := regexp.Compile(targeting.Regex)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid regex %w", targeting.Regex, err)
+	}
+
+	return &PodTargets{
+		Regex:         regex,
+		LabelSelector: targeting.LabelSelector,
+		Workloads:     configStructs.ParseWorkloads(targeting.Workloads),
+	}, nil
+}
+
+// GetTargeting reads the targeting from the config map, along with the resource version of the config map.
+func (provider *Provider) GetTargeting(ctx context.Context, releaseNamespace string) (targeting *Targeting, resourceVersion string, err error) {
+	var configMap *core.ConfigMap
+	configMap, err = provider.GetConfigMap(ctx, releaseNamespace, SELF_RESOURCES_PREFIX+SUFFIX_CONFIG_MAP)
+	if err != nil {
+		return
+	}
+
+	targeting = &Targeting{
+		Regex:              configMap.Data[CONFIG_POD_REGEX],
+		Namespaces:         splitConfigList(configMap.Data[CONFIG_NAMESPACES]),
+		ExcludedNamespaces: splitConfigList(configMap.Data[CONFIG_EXCLUDED_NAMESPACES]),
+		LabelSelector:      configMap.Data[CONFIG_LABEL_SELECTOR],
+		Workloads:          splitConfigList(configMap.Data[CONFIG_WORKLOADS]),
+		NamespaceSelector:  configMap.Data[CONFIG_NAMESPACE_SELECTOR],
+	}
+	resourceVersion = configMap.ResourceVersion
+	return
+}
+
+// UpdateTargeting writes the targeting into the config map, only if the config map is still at the resource version.
+// Otherwise, i.e. if the config map was changed meanwhile, a conflict error is returned, see k8serrors.IsConflict.
+func (provider *Provider) UpdateTargeting(ctx context.Context, releaseNamespace string, targeting *Targeting, resourceVersion string) error {
+	configMap, err := provider.GetConfigMap(ctx, releaseNamespace, SELF_RESOURCES_PREFIX+SUFFIX_CONFIG_MAP)
+	if err != nil {
+		return err
+	}
+
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	configMap.Data[CONFIG_POD_REGEX] = targeting.Regex
+	configMap.Data[CONFIG_NAMESPACES] = strings.Join(targeting.Namespaces, ",")
+	configMap.Data[CONFIG_EXCLUDED_NAMESPACES] = strings.Join(targeting.ExcludedNamespaces, ",")
+	configMap.Data[CONFIG_LABEL_SELECTOR] = targeting.LabelSelector
+	configMap.Data[CONFIG_WORKLOADS] = strings.Join(targeting.Workloads, ",")
+	configMap.Data[CONFIG_NAMESPACE_SELECTOR] = targeting.NamespaceSelector
+	configMap.ResourceVersion = resourceVersion
+
+	_, err = provider.clientSet.CoreV1().ConfigMaps(releaseNamespace).Update(ctx, configMap, metav1.UpdateOptions{})
+	return err
+}
+
+func splitConfigList(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	return strings.Split(value, ",")
+}
+
+// ListTargetedNamespaces returns the given namespaces, or all of these, that match the namespace selector, except the excluded ones.
+func (provider *Provider) ListTargetedNamespaces(ctx context.Context, namespaces []string, excludedNamespaces []string, namespaceSelector string) ([]string, error) {
+	var targetedNamespaces []string
+	if len(namespaces) > 0 && namespaceSelector == "" {
+		targetedNamespaces = utils.Unique(namespaces)
+	} else {
+		namespaceList, err := provider.clientSet.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: namespaceSelector})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ns := range namespaceList.Items {
+			if len(namespaces) > 0 && !utils.Contains(namespaces, ns.Name) {
+				continue
+			}
+			targetedNamespaces = append(targetedNamespaces, ns.Name)
+		}
+	}
+
+	return utils.Diff(targetedNamespaces, excludedNamespaces), nil
+}
@@ -0,0 +1,201 @@
+package kubernetes
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/kubeshark/kubeshark/config/configStructs"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func ownerMock(kind string, name string) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}}
+}
+
+func podMock(namespace string, name string, app string, phase core.PodPhase, owners []metav1.OwnerReference) *core.Pod {
+	return &core.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       namespace,
+			Name:            name,
+			Labels:          map[string]string{"app": app},
+			OwnerReferences: owners,
+		},
+		Status: core.PodStatus{Phase: phase},
+	}
+}
+
+func targetsProviderMock() *Provider {
+	objects := []runtime.Object{
+		&apps.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "checkout-abc", OwnerReferences: ownerMock(configStructs.WorkloadKindDeployment, "checkout")}},
+		&apps.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "cache-x"}},
+		podMock("shop", "checkout-abc-1", "checkout", core.PodRunning, ownerMock(configStructs.WorkloadKindReplicaSet, "checkout-abc")),
+		podMock("shop", "checkout-abc-2", "checkout", core.PodPending, ownerMock(configStructs.WorkloadKindReplicaSet, "checkout-abc")),
+		podMock("shop", "payments-0", "payments", core.PodRunning, ownerMock(configStructs.WorkloadKindStatefulSet, "payments")),
+		podMock("shop", "cache-1", "cache", core.PodRunning, ownerMock(configStructs.WorkloadKindReplicaSet, "cache-x")),
+		podMock("other", "checkout-zzz", "checkout", core.PodRunning, nil),
+		&core.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "payments-svc"},
+			Subsets: []core.EndpointSubset{{
+				Addresses:         []core.EndpointAddress{{TargetRef: &core.ObjectReference{Kind: "Pod", Name: "payments-0"}}},
+				NotReadyAddresses: []core.EndpointAddress{{TargetRef: &core.ObjectReference{Kind: "Pod", Name: "cache-1"}}},
+			}},
+		},
+	}
+
+	return &Provider{clientSet: fake.NewSimpleClientset(objects...)}
+}
+
+func TestListTargetedPods(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Regex         string
+		LabelSelector string
+		Workloads     []string
+		Namespaces    []string
+		Expected      map[string][]string
+	}{
+		{
+			Name:       "all the running pods",
+			Regex:      ".*",
+			Namespaces: []string{"shop"},
+			Expected: map[string][]string{
+				"checkout-abc-1": {"regex .*"},
+				"payments-0":     {"regex .*"},
+				"cache-1":        {"regex .*"},
+			},
+		},
+		{
+			Name:          "label selector",
+			Regex:         ".*",
+			LabelSelector: "app=checkout",
+			Namespaces:    []string{"shop", "other"},
+			Expected: map[string][]string{
+				"checkout-abc-1": {"-l app=checkout"},
+				"checkout-zzz":   {"-l app=checkout"},
+			},
+		},
+		{
+			Name:       "deployment",
+			Regex:      ".*",
+			Workloads:  []string{"deploy/checkout"},
+			Namespaces: []string{"shop", "other"},
+			Expected: map[string][]string{
+				"checkout-abc-1": {"deploy/checkout"},
+			},
+		},
+		{
+			Name:       "service and stateful set",
+			Regex:      ".*",
+			Workloads:  []string{"svc/payments-svc", "sts/payments"},
+			Namespaces: []string{"shop"},
+			Expected: map[string][]string{
+				"payments-0": {"svc/payments-svc", "sts/payments"},
+				"cache-1":    {"svc/payments-svc"},
+			},
+		},
+		{
+			Name:       "missing service",
+			Regex:      ".*",
+			Workloads:  []string{"svc/missing"},
+			Namespaces: []string{"shop"},
+			Expected:   map[string][]string{},
+		},
+		{
+			Name:          "all the rules",
+			Regex:         "abc",
+			LabelSelector: "app=checkout",
+			Workloads:     []string{"deploy/checkout", "rs/cache-x"},
+			Namespaces:    []string{"shop"},
+			Expected: map[string][]string{
+				"checkout-abc-1": {"deploy/checkout", "-l app=checkout", "regex abc"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			targets := &PodTargets{
+				Regex:         regexp.MustCompile(test.Regex),
+				LabelSelector: test.LabelSelector,
+				Workloads:     configStructs.ParseWorkloads(test.Workloads),
+			}
+
+			targetedPods, err := targetsProviderMock().ListTargetedPods(context.Background(), targets, test.Namespaces)
+			if err != nil {
+				t.Fatalf("unexpected error - %v", err)
+			}
+
+			actual := make(map[string][]string)
+			for _, targetedPod := range targetedPods {
+				actual[targetedPod.Name] = targetedPod.Rules
+			}
+
+			if !reflect.DeepEqual(actual, test.Expected) {
+				t.Errorf("unexpected targeted pods - expected: %v, actual: %v", test.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestResolveWorkloadPods(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Workloads []string
+		Expected  map[string][]string
+	}{
+		{
+			Name:      "deployment through its replica set",
+			Workloads: []string{"deploy/checkout"},
+			Expected: map[string][]string{
+				"checkout-abc-1": {"deploy/checkout"},
+				"checkout-abc-2": {"deploy/checkout"},
+			},
+		},
+		{
+			Name:      "replica set without a deployment",
+			Workloads: []string{"rs/cache-x", "deploy/cache"},
+			Expected: map[string][]string{
+				"cache-1": {"rs/cache-x"},
+			},
+		},
+		{
+			Name:      "ready and not ready endpoints",
+			Workloads: []string{"svc/payments-svc"},
+			Expected: map[string][]string{
+				"payments-0": {"svc/payments-svc"},
+				"cache-1":    {"svc/payments-svc"},
+			},
+		},
+		{
+			Name:      "no owner",
+			Workloads: []string{"ds/checkout-zzz", "sts/checkout"},
+			Expected:  map[string][]string{},
+		},
+	}
+
+	provider := targetsProviderMock()
+	pods, err := provider.clientSet.CoreV1().Pods("shop").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			actual, err := provider.resolveWorkloadPods(context.Background(), "shop", configStructs.ParseWorkloads(test.Workloads), pods.Items)
+			if err != nil {
+				t.Fatalf("unexpected error - %v", err)
+			}
+
+			if !reflect.DeepEqual(actual, test.Expected) {
+				t.Errorf("unexpected workload pods - expected: %v, actual: %v", test.Expected, actual)
+			}
+		})
+	}
+}
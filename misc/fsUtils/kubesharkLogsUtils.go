@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
 
 	"github.com/kubeshark/kubeshark/config"
@@ -73,12 +74,33 @@ func DumpLogs(ctx context.Context, provider *kubernetes.Provider, filePath strin
 		log.Debug().Str("namespace", config.Config.Tap.Release.Namespace).Msg("Successfully added events.")
 	}
 
-	if err := AddFileToZip(zipWriter, config.ConfigFilePath); err != nil {
-		log.Error().Err(err).Msg("Failed write file!")
-	} else {
-		log.Debug().Str("file-path", config.ConfigFilePath).Msg("Successfully added file.")
+	// Every layer of the config is added, prefixed by its order, as the later files override the earlier ones.
+	for i, filePath := range config.LoadedConfigFiles {
+		if err := addConfigFileToZip(zipWriter, filePath, fmt.Sprintf("%d_%s", i+1, filepath.Base(filePath))); err != nil {
+			log.Error().Err(err).Str("file-path", filePath).Msg("Failed write file!")
+		} else {
+			log.Debug().Str("file-path", filePath).Msg("Successfully added file.")
+		}
 	}
 
 	log.Info().Str("path", filePath).Msg("You can find the ZIP file with all logs at:")
 	return nil
 }
+
+// addConfigFileToZip adds the config file with its secrets redacted.
+func addConfigFileToZip(zipWriter *zip.Writer, filePath string, fileName string) error {
+	if _, err := os.Stat(filePath); err != nil {
+		return err
+	}
+
+	configFile, err := config.ReadConfigFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	if err := configFile.RedactSecrets(); err != nil {
+		return err
+	}
+
+	return AddStrToZip(zipWriter, string(configFile.Bytes()), fileName)
+}
@@ -0,0 +1,57 @@
+package fsUtils
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+func AddFileToTar(tarWriter *tar.Writer, filename string, name string) error {
+	fileToTar, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s, %w", filename, err)
+	}
+	defer fileToTar.Close()
+
+	info, err := fileToTar.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to get file information %s, %w", filename, err)
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+
+	// Using FileInfoHeader() above only uses the basename of the file.
+	// Overwrite it to preserve the folder structure inside the archive.
+	header.Name = name
+
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write header in tar for %s, %w", filename, err)
+	}
+
+	_, err = io.Copy(tarWriter, fileToTar)
+	return err
+}
+
+func AddBytesToTar(tarWriter *tar.Writer, data []byte, name string) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write header in tar for %s, %w", name, err)
+	}
+
+	if _, err := tarWriter.Write(data); err != nil {
+		return fmt.Errorf("couldn't write data to tar file: %s, %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package misc
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// ScriptBundle declares a set of scripts in a single YAML file.
+type ScriptBundle struct {
+	Scripts []BundleScript `yaml:"scripts"`
+}
+
+// BundleScript is either a script file, whose path is relative to the bundle, or an inline script.
+type BundleScript struct {
+	Title   string                 `yaml:"title"`
+	Path    string                 `yaml:"path"`
+	Code    string                 `yaml:"code"`
+	Enabled *bool                  `yaml:"enabled"`
+	Env     map[string]interface{} `yaml:"env"`
+}
+
+func (script *BundleScript) IsEnabled() bool {
+	return script.Enabled == nil || *script.Enabled
+}
+
+func IsScriptBundle(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// ReadScriptBundle returns the enabled scripts of a bundle.
+func ReadScriptBundle(path string) (scripts []*Script, err error) {
+	var body []byte
+	body, err = os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	var bundle ScriptBundle
+	if err = yaml.UnmarshalWithOptions(body, &bundle, yaml.Strict()); err != nil {
+		err = fmt.Errorf("invalid script bundle %s, %w", path, err)
+		return
+	}
+
+	titles := make(map[string]bool)
+	for i, bundleScript := range bundle.Scripts {
+		if !bundleScript.IsEnabled() {
+			continue
+		}
+
+		var script *Script
+		if bundleScript.Path != "" {
+			scriptPath := bundleScript.Path
+			if !filepath.IsAbs(scriptPath) {
+				scriptPath = filepath.Join(filepath.Dir(path), scriptPath)
+			}
+
+			script, err = ReadScriptFile(scriptPath)
+			if err != nil {
+				return
+			}
+		} else if bundleScript.Code != "" {
+			script = &Script{
+				Path: fmt.Sprintf("%s#%d", path, i),
+				Code: bundleScript.Code,
+			}
+		} else {
+			err = fmt.Errorf("script #%d of bundle %s has neither a path nor a code", i, path)
+			return
+		}
+
+		if bundleScript.Title != "" {
+			script.Title = bundleScript.Title
+		}
+		script.Env = bundleScript.Env
+
+		if titles[script.Title] {
+			err = fmt.Errorf("duplicate script title %q in bundle %s", script.Title, path)
+			return
+		}
+		titles[script.Title] = true
+
+		scripts = append(scripts, script)
+	}
+
+	return
+}
+
+// ReadScriptTree returns the scripts in a directory and in its subdirectories.
+func ReadScriptTree(dir string) (scripts []*Script, err error) {
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || filepath.Ext(path) != ".js" {
+			return nil
+		}
+
+		script, err := ReadScriptFile(path)
+		if err != nil {
+			return err
+		}
+
+		scripts = append(scripts, script)
+		return nil
+	})
+
+	return
+}
+
+// ReadScripts reads the scripts of a source, which is either a YAML bundle or a directory tree.
+func ReadScripts(source string) (scripts []*Script, err error) {
+	if IsScriptBundle(source) {
+		return ReadScriptBundle(source)
+	}
+
+	var info os.FileInfo
+	info, err = os.Stat(source)
+	if err != nil {
+		return
+	}
+
+	if !info.IsDir() {
+		var script *Script
+		script, err = ReadScriptFile(source)
+		if err != nil {
+			return
+		}
+		scripts = append(scripts, script)
+		return
+	}
+
+	return ReadScriptTree(source)
+}
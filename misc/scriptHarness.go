@@ -0,0 +1,344 @@
+package misc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/robertkrimen/otto"
+)
+
+// ScriptHooks are the functions that the Hub calls on a script.
+var ScriptHooks = []string{
+	"onItemCaptured",
+	"onPacketCaptured",
+	"onJobPassed",
+	"onJobFailed",
+}
+
+// scriptHelpers are the helper functions that the Hub provides to the scripts. The stubs record their calls
+// and return undefined, unless a return value is given below.
+var scriptHelpers = []string{
+	"console.log",
+	"console.error",
+	"test.pass",
+	"test.fail",
+	"vendor.slack",
+	"vendor.slackBot",
+	"vendor.webhook",
+	"vendor.influxdb",
+	"vendor.elastic",
+	"vendor.s3.put",
+	"vendor.s3.clear",
+	"jobs.schedule",
+	"jobs.remove",
+	"jobs.removeAll",
+	"jobs.list",
+	"jobs.run",
+	"jobs.runAll",
+	"jobs.scheduler.isRunning",
+	"jobs.scheduler.start",
+	"jobs.scheduler.stop",
+	"pcap.nodeName",
+	"pcap.snapshot",
+	"pcap.path",
+	"file.write",
+	"file.append",
+	"file.move",
+	"file.copy",
+	"file.delete",
+	"file.mkdir",
+	"file.mkdirTemp",
+	"file.path",
+	"file.read",
+	"file.tar",
+	"kfl.match",
+	"kfl.validate",
+	"chart.save",
+}
+
+// scriptHelperResults are the return values of the stubs, given as JavaScript expressions.
+// The filter helpers match every entry, so the filtered paths of the hooks are exercised.
+var scriptHelperResults = map[string]string{
+	"test.pass":                "arguments[0]",
+	"test.fail":                "arguments[0]",
+	"jobs.list":                "[]",
+	"jobs.scheduler.isRunning": "false",
+	"pcap.nodeName":            "'local'",
+	"pcap.snapshot":            "'snapshot.pcap'",
+	"pcap.path":                "'/app/data/' + arguments[0]",
+	"file.mkdirTemp":           "'/tmp/' + (arguments[0] || 'tmp')",
+	"file.path":                "'/app/data/' + arguments[0]",
+	"file.read":                "''",
+	"file.tar":                 "'/tmp/archive.tar.gz'",
+	"kfl.match":                "true",
+	"kfl.validate":             "true",
+}
+
+// ScriptHookTimeout bounds a single run of the script or one of its hooks.
+const ScriptHookTimeout = 5 * time.Second
+
+var errScriptHookTimeout = errors.New("timed out")
+
+// ScriptCall is a call to a helper function, as it is recorded by its stub.
+type ScriptCall struct {
+	Helper    string   `json:"helper"`
+	Arguments []string `json:"arguments"`
+}
+
+func (call *ScriptCall) String() string {
+	return fmt.Sprintf("%s(%s)", call.Helper, strings.Join(call.Arguments, ", "))
+}
+
+// ScriptHookResult is the outcome of a single run of a hook.
+type ScriptHookResult struct {
+	Hook   string       `json:"hook"`
+	Calls  []ScriptCall `json:"calls"`
+	Return string       `json:"return,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// ScriptHarness runs a script locally in an otto VM, with the helper functions of the Hub stubbed out.
+type ScriptHarness struct {
+	vm    *otto.Otto
+	hooks []string
+	calls []ScriptCall
+}
+
+// NewScriptHarness evaluates the script with the given env and returns the harness to run its hooks on.
+func NewScriptHarness(script *Script, env map[string]interface{}) (harness *ScriptHarness, err error) {
+	harness = &ScriptHarness{
+		vm: otto.New(),
+	}
+
+	if err = harness.vm.Set("env", env); err != nil {
+		return
+	}
+
+	for _, helper := range scriptHelpers {
+		if err = harness.stub(helper); err != nil {
+			return
+		}
+	}
+
+	if err = harness.run(func() error {
+		_, err := harness.vm.Run(script.Code)
+		return err
+	}); err != nil {
+		err = fmt.Errorf("failed evaluating the script %s, %w", script.Path, err)
+		return
+	}
+
+	for _, hook := range ScriptHooks {
+		value, _ := harness.vm.Get(hook)
+		if value.IsFunction() {
+			harness.hooks = append(harness.hooks, hook)
+		}
+	}
+
+	if len(harness.hooks) == 0 {
+		err = fmt.Errorf("the script %s defines none of the hooks: %s", script.Path, strings.Join(ScriptHooks, ", "))
+		return
+	}
+
+	return
+}
+
+// Hooks returns the hooks that the script defines.
+func (harness *ScriptHarness) Hooks() []string {
+	return harness.hooks
+}
+
+// HasHook reports whether the script defines the hook.
+func (harness *ScriptHarness) HasHook(hook string) bool {
+	for _, h := range harness.hooks {
+		if h == hook {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Calls returns the helper calls that are made while the script is evaluated.
+func (harness *ScriptHarness) Calls() []ScriptCall {
+	return harness.calls
+}
+
+// RunHook calls the hook with a JSON argument, e.g. a recorded entry.
+func (harness *ScriptHarness) RunHook(hook string, data []byte) (result *ScriptHookResult) {
+	result = &ScriptHookResult{
+		Hook: hook,
+	}
+	harness.calls = nil
+
+	err := harness.run(func() error {
+		argument, err := harness.vm.Call("JSON.parse", nil, string(data))
+		if err != nil {
+			return err
+		}
+
+		value, err := harness.vm.Call(hook, nil, argument)
+		if err != nil {
+			return err
+		}
+
+		if !value.IsUndefined() {
+			result.Return = harness.stringify(value)
+		}
+
+		return nil
+	})
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	result.Calls = harness.calls
+	return
+}
+
+// run interrupts the VM when the function does not return in time, e.g. on an endless loop.
+func (harness *ScriptHarness) run(f func() error) (err error) {
+	harness.vm.Interrupt = make(chan func(), 1)
+	timer := time.AfterFunc(ScriptHookTimeout, func() {
+		harness.vm.Interrupt <- func() {
+			panic(errScriptHookTimeout)
+		}
+	})
+	defer timer.Stop()
+
+	defer func() {
+		if caught := recover(); caught != nil {
+			if caught != errScriptHookTimeout {
+				panic(caught)
+			}
+			err = errScriptHookTimeout
+		}
+	}()
+
+	return f()
+}
+
+// stub defines a helper function that records its calls, creating the objects of its path as needed.
+func (harness *ScriptHarness) stub(helper string) error {
+	path := strings.Split(helper, ".")
+
+	value, err := harness.vm.Get(path[0])
+	if err != nil {
+		return err
+	}
+	if !value.IsObject() {
+		object, err := harness.vm.Object("({})")
+		if err != nil {
+			return err
+		}
+		if err := harness.vm.Set(path[0], object); err != nil {
+			return err
+		}
+		value = object.Value()
+	}
+
+	object := value.Object()
+	for _, name := range path[1 : len(path)-1] {
+		child, err := object.Get(name)
+		if err != nil {
+			return err
+		}
+		if !child.IsObject() {
+			childObject, err := harness.vm.Object("({})")
+			if err != nil {
+				return err
+			}
+			if err := object.Set(name, childObject); err != nil {
+				return err
+			}
+			child = childObject.Value()
+		}
+		object = child.Object()
+	}
+
+	var result otto.Value
+	if expression, ok := scriptHelperResults[helper]; ok {
+		result, err = harness.vm.Run(fmt.Sprintf("(function() { return %s; })", expression))
+		if err != nil {
+			return err
+		}
+	}
+
+	return object.Set(path[len(path)-1], func(call otto.FunctionCall) otto.Value {
+		recorded := ScriptCall{
+			Helper:    helper,
+			Arguments: []string{},
+		}
+		var arguments []interface{}
+		for _, argument := range call.ArgumentList {
+			recorded.Arguments = append(recorded.Arguments, harness.stringify(argument))
+			arguments = append(arguments, argument)
+		}
+		harness.calls = append(harness.calls, recorded)
+
+		if !result.IsFunction() {
+			return otto.UndefinedValue()
+		}
+
+		value, err := result.Call(otto.UndefinedValue(), arguments...)
+		if err != nil {
+			return otto.UndefinedValue()
+		}
+
+		return value
+	})
+}
+
+func (harness *ScriptHarness) stringify(value otto.Value) string {
+	if value.IsFunction() {
+		return "function"
+	}
+
+	if value.IsString() {
+		return value.String()
+	}
+
+	s, err := harness.vm.Call("JSON.stringify", nil, value)
+	if err != nil || s.IsUndefined() {
+		return value.String()
+	}
+
+	return s.String()
+}
+
+// ReadScriptFixture reads the recorded entries of a fixture file. It accepts either a JSON array,
+// a single JSON object or JSON lines, e.g. the output of the `query` command.
+func ReadScriptFixture(path string) (entries []json.RawMessage, err error) {
+	var body []byte
+	body, err = os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return
+	}
+
+	if body[0] == '[' {
+		err = json.Unmarshal(body, &entries)
+		return
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	for decoder.More() {
+		var entry json.RawMessage
+		if err = decoder.Decode(&entry); err != nil {
+			err = fmt.Errorf("invalid fixture %s, %w", path, err)
+			return
+		}
+		entries = append(entries, entry)
+	}
+
+	return
+}
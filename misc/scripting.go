@@ -10,9 +10,10 @@ import (
 )
 
 type Script struct {
-	Path  string `json:"path"`
-	Title string `json:"title"`
-	Code  string `json:"code"`
+	Path  string                 `json:"path"`
+	Title string                 `json:"title"`
+	Code  string                 `json:"code"`
+	Env   map[string]interface{} `json:"env,omitempty"`
 }
 
 func ReadScriptFile(path string) (script *Script, err error) {
@@ -45,6 +46,10 @@ func ReadScriptFile(path string) (script *Script, err error) {
 		titleIsSet = true
 	}
 
+	if !titleIsSet {
+		title = filename
+	}
+
 	script = &Script{
 		Path:  path,
 		Title: title,
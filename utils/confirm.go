@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// AskForConfirmation prints the question and returns true only if the user answers with "yes".
+func AskForConfirmation(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s Only 'yes' will be accepted to approve: ", question)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	return strings.TrimSpace(answer) == "yes"
+}
@@ -2,8 +2,10 @@ package utils
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 
 	"github.com/rs/zerolog/log"
@@ -24,3 +26,34 @@ func WaitForTermination(ctx context.Context, cancel context.CancelFunc) {
 		cancel()
 	}
 }
+
+// HoldTermination keeps the termination signals from interrupting the process until release is called, so that e.g.
+// a cleanup is not left half-done by a repeated Ctrl+C. The held signals are reported by the message, and release
+// returns whether any signal was held.
+func HoldTermination(message string) (release func() (held bool)) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+
+	var signaled atomic.Bool
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-sigChan:
+				signaled.Store(true)
+				log.Warn().Msg(fmt.Sprintf(Yellow, message))
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() bool {
+		signal.Stop(sigChan)
+		close(done)
+		<-stopped
+		return signaled.Load()
+	}
+}